@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+
+	"github.com/sherine-k/catalog-filter/pkg/filter"
+	mirror "github.com/sherine-k/catalog-filter/pkg/filter/mirror-config/v1alpha1"
+)
+
+const (
+	formatDOT     = "dot"
+	formatMermaid = "mermaid"
+)
+
+type graphOptions struct {
+	configPaths pathList
+	strict      bool
+	catalogDir  string
+	pkg         string
+	channel     string
+	format      string
+	dest        string
+	after       bool
+	full        bool
+	heads       string
+	logLevel    string
+}
+
+// runGraph filters the catalog and renders the upgrade graph of one of its channels, highlighting the
+// entries that were kept, dropped, or kept outside of the version range to preserve a single head.
+func runGraph(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	opts, err := parseGraphFlags(args, stderr)
+	if err != nil {
+		return err
+	}
+
+	log, err := newLogger(opts.logLevel, stderr)
+	if err != nil {
+		return err
+	}
+	config, err := loadConfig(opts.configPaths, opts.strict)
+	if err != nil {
+		return err
+	}
+	root, err := catalogFS(opts.catalogDir)
+	if err != nil {
+		return err
+	}
+	fbc, err := declcfg.LoadFS(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	report := &mirror.FilterReport{}
+	f := mirror.NewMirrorFilter(*config, mirror.InFull(opts.full), mirror.WithLogger(logrus.NewEntry(log)), mirror.WithReport(report),
+		mirror.WithMultipleHeads(filter.MultipleHeadsPolicy(opts.heads)))
+	filtered, err := f.FilterCatalog(ctx, fbc)
+	if err != nil {
+		return fmt.Errorf("unable to filter catalog %q: %v", opts.catalogDir, err)
+	}
+	source := fbc
+	if opts.after {
+		source = filtered
+	}
+	g, renderOpts, err := mirror.ChannelGraph(source, filtered, report, opts.pkg, opts.channel)
+	if err != nil {
+		return err
+	}
+
+	write := g.WriteDOT
+	if opts.format == formatMermaid {
+		write = g.WriteMermaid
+	}
+	if opts.dest == "" {
+		return write(stdout, renderOpts)
+	}
+	file, err := os.Create(opts.dest)
+	if err != nil {
+		return err
+	}
+	if err := write(file, renderOpts); err != nil {
+		file.Close()
+		return fmt.Errorf("write file %q: %v", opts.dest, err)
+	}
+	return file.Close()
+}
+
+func parseGraphFlags(args []string, stderr io.Writer) (graphOptions, error) {
+	opts := graphOptions{}
+	flags := flag.NewFlagSet("catalog-filter graph", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: catalog-filter graph [flags] <catalog-dir>\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	flags.Var(&opts.configPaths, "config", "path to a FilterConfiguration file, repeat it to merge several files (required)")
+	flags.BoolVar(&opts.strict, "strict", false, "validate the configuration files against the JSON Schema of their API, reporting unknown fields with their line and column")
+	flags.StringVar(&opts.pkg, "package", "", "package of the channel to render (required)")
+	flags.StringVar(&opts.channel, "channel", "", "channel to render (required)")
+	flags.StringVar(&opts.format, "format", formatDOT, "graph format: dot or mermaid")
+	flags.StringVar(&opts.dest, "dest", "", "file to write the graph to (defaults to stdout)")
+	flags.BoolVar(&opts.after, "after", false, "render the channel after filtering instead of before")
+	flags.BoolVar(&opts.full, "full", false, "keep all bundles of the filtered channels instead of only the channel heads")
+	flags.StringVar(&opts.heads, "multiple-heads", "", "how channels with several heads are filtered: error (default), keepAll or keepHighest")
+	flags.StringVar(&opts.logLevel, "log-level", "warning", "log level: panic, fatal, error, warning, info, debug or trace")
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+
+	var errs []error
+	if len(opts.configPaths) == 0 {
+		errs = append(errs, errors.New("--config must be specified"))
+	}
+	if opts.pkg == "" || opts.channel == "" {
+		errs = append(errs, errors.New("--package and --channel must be specified"))
+	}
+	switch flags.NArg() {
+	case 0:
+		errs = append(errs, errors.New("a catalog directory must be specified"))
+	case 1:
+		opts.catalogDir = flags.Arg(0)
+	default:
+		errs = append(errs, fmt.Errorf("expected exactly one catalog directory, got %d", flags.NArg()))
+	}
+	switch opts.format {
+	case formatDOT, formatMermaid:
+	default:
+		errs = append(errs, fmt.Errorf("unsupported graph format %q", opts.format))
+	}
+	if err := filter.MultipleHeadsPolicy(opts.heads).Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid --multiple-heads: %v", err))
+	}
+	return opts, errors.Join(errs...)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunGraph(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      func(t *testing.T) []string
+		assertion func(*testing.T, *bytes.Buffer, error)
+	}{
+		{
+			name: "WHEN no package or channel THEN Returns error",
+			args: func(t *testing.T) []string {
+				return []string{"graph", "--config", "testdata/config.yaml", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, "--package and --channel must be specified")
+			},
+		},
+		{
+			name: "WHEN unknown graph format THEN Returns error",
+			args: func(t *testing.T) []string {
+				return []string{"graph", "--config", "testdata/config.yaml", "--package", "foo", "--channel", "stable", "--format", "svg", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, `unsupported graph format "svg"`)
+			},
+		},
+		{
+			name: "WHEN unknown channel THEN Returns error",
+			args: func(t *testing.T) []string {
+				return []string{"graph", "--config", "testdata/config.yaml", "--package", "foo", "--channel", "fast", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, `package "foo" channel "fast" not found`)
+			},
+		},
+		{
+			name: "WHEN format is dot THEN Writes the channel before filtering",
+			args: func(t *testing.T) []string {
+				return []string{"graph", "--config", "testdata/config.yaml", "--package", "foo", "--channel", "stable", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, stdout *bytes.Buffer, err error) {
+				require.NoError(t, err)
+				assert.Contains(t, stdout.String(), `digraph "foo/stable" {`)
+				assert.Contains(t, stdout.String(), `"foo.v0.3.0" [label="foo.v0.3.0\n0.3.0", style=filled, fillcolor="palegreen"];`)
+				assert.Contains(t, stdout.String(), `"foo.v0.1.0" [label="foo.v0.1.0\n0.1.0", style="filled,dashed", fillcolor="lightgrey", fontcolor="grey40"];`)
+				assert.Contains(t, stdout.String(), `"foo.v0.2.0" -> "foo.v0.1.0" [label="replaces"];`)
+			},
+		},
+		{
+			name: "WHEN format is mermaid and after THEN Writes the channel after filtering",
+			args: func(t *testing.T) []string {
+				return []string{"graph", "--config", "testdata/config.yaml", "--package", "foo", "--channel", "stable", "--format", "mermaid", "--after", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, stdout *bytes.Buffer, err error) {
+				require.NoError(t, err)
+				assert.Contains(t, stdout.String(), "flowchart TD\n")
+				assert.Contains(t, stdout.String(), "\tclass n0,n1 kept\n")
+				assert.NotContains(t, stdout.String(), "dropped")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			err := run(context.Background(), tt.args(t), stdout, &bytes.Buffer{})
+			tt.assertion(t, stdout, err)
+		})
+	}
+}
+
+func TestRunGraph_Dest(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "stable.dot")
+	stdout := &bytes.Buffer{}
+	err := run(context.Background(), []string{"graph", "--config", "testdata/config.yaml", "--package", "foo", "--channel", "stable", "--dest", dest, "testdata/catalog"}, stdout, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Empty(t, stdout.String())
+	data, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `digraph "foo/stable" {`)
+}
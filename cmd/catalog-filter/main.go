@@ -0,0 +1,346 @@
+// Command catalog-filter renders a file based catalog (FBC) directory, filters it according to a
+// mirror FilterConfiguration and writes the filtered catalog back out.
+//
+// Usage:
+//
+//	catalog-filter --config filter.yaml [--config team.yaml] [--strict] [--full] [--log-level info] [--output json|yaml|dir|oci] [--dest path] [--canonical] <catalog-dir>
+//
+// The catalog can also be read from the catalog image of an OCI image layout, given as oci:<layout-dir>[:<reference>],
+// and written back as an OCI image layout with --output oci, on top of the image it was read from.
+//
+// The images of the filtered catalog can be listed alongside it, as text, JSON or an `oc image mirror` mapping file:
+//
+//	catalog-filter --config filter.yaml --images images.txt [--images-format text|json|mapping] [--mirror-to registry/namespace] <catalog-dir>
+//
+// The images of the filtered catalog can be pinned to the digests listed in a JSON file mapping tag references to
+// digests, and their references rewritten to point at a mirror registry:
+//
+//	catalog-filter --config filter.yaml [--digests digests.json] [--rewrite-registry registry.redhat.io=mirror.example.com/redhat] <catalog-dir>
+//
+// The graph subcommand renders the upgrade graph of a channel before or after filtering, as DOT or Mermaid:
+//
+//	catalog-filter graph --config filter.yaml --package foo --channel stable [--format dot|mermaid] [--after] <catalog-dir>
+//
+// The schema subcommand writes the JSON Schema of a FilterConfiguration API version:
+//
+//	catalog-filter schema [--api-version olm.operatorframework.io/filter/mirror/v1alpha1] [--dest path]
+package main
+
+//go:generate go run . schema --api-version olm.operatorframework.io/filter/mirror/v1alpha1 --dest ../../schemas/mirror-filter-configuration.v1alpha1.json
+//go:generate go run . schema --api-version olm.operatorframework.io/v1alpha1 --dest ../../schemas/filter-configuration.v1alpha1.json
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+
+	"github.com/sherine-k/catalog-filter/pkg/filter"
+	mirror "github.com/sherine-k/catalog-filter/pkg/filter/mirror-config/v1alpha1"
+	"github.com/sherine-k/catalog-filter/pkg/images"
+	"github.com/sherine-k/catalog-filter/pkg/ocilayout"
+)
+
+const (
+	outputJSON = "json"
+	outputYAML = "yaml"
+	outputDir  = "dir"
+	outputOCI  = "oci"
+
+	ociPrefix = "oci:"
+
+	imagesText    = "text"
+	imagesJSON    = "json"
+	imagesMapping = "mapping"
+)
+
+type options struct {
+	configPaths  pathList
+	strict       bool
+	catalogDir   string
+	output       string
+	dest         string
+	full         bool
+	deps         bool
+	logLevel     string
+	reportPath   string
+	heads        string
+	imagesPath   string
+	imagesFormat string
+	mirrorTo     string
+	rewrites     rewriteList
+	digestsPath  string
+	canonical    bool
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if len(args) > 0 && args[0] == "graph" {
+		return runGraph(ctx, args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "schema" {
+		return runSchema(args[1:], stdout, stderr)
+	}
+	opts, err := parseFlags(args, stderr)
+	if err != nil {
+		return err
+	}
+
+	log, err := newLogger(opts.logLevel, stderr)
+	if err != nil {
+		return err
+	}
+	config, err := loadConfig(opts.configPaths, opts.strict)
+	if err != nil {
+		return err
+	}
+
+	report := &mirror.FilterReport{}
+	f := mirror.NewMirrorFilter(*config, mirror.InFull(opts.full), mirror.WithLogger(logrus.NewEntry(log)), mirror.WithReport(report), mirror.WithDependencies(opts.deps),
+		mirror.WithMultipleHeads(filter.MultipleHeadsPolicy(opts.heads)))
+	root, err := catalogFS(opts.catalogDir)
+	if err != nil {
+		return err
+	}
+	filtered, err := filterCatalog(ctx, root, f, len(config.Packages) > 0)
+	if opts.reportPath != "" {
+		// the report is written even when filtering fails, it explains how far the filtering went
+		if reportErr := writeReport(*report, opts.reportPath); reportErr != nil {
+			return errors.Join(err, reportErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to filter catalog %q: %v", opts.catalogDir, err)
+	}
+
+	if opts.digestsPath != "" {
+		if filtered, err = pinImages(ctx, filtered, opts.digestsPath, log); err != nil {
+			return fmt.Errorf("unable to pin images: %v", err)
+		}
+	}
+	// the images are listed before they are rewritten, the mapping pulls them from where the catalog refers to them
+	if opts.imagesPath != "" {
+		if err := writeImages(*filtered, opts.imagesFormat, opts.mirrorTo, opts.imagesPath); err != nil {
+			return fmt.Errorf("unable to write images: %v", err)
+		}
+	}
+	if len(opts.rewrites) > 0 {
+		if filtered, err = rewriteImages(ctx, filtered, images.Rewrites(opts.rewrites), log); err != nil {
+			return fmt.Errorf("unable to rewrite images: %v", err)
+		}
+	}
+	if opts.canonical {
+		if filtered, err = filter.Canonicalize(filtered); err != nil {
+			return fmt.Errorf("unable to canonicalize catalog: %v", err)
+		}
+	}
+	return writeCatalog(*filtered, opts.output, opts.dest, opts.catalogDir, stdout)
+}
+
+func newLogger(logLevel string, stderr io.Writer) (*logrus.Logger, error) {
+	log := logrus.New()
+	log.SetOutput(stderr)
+	level, err := logrus.ParseLevel(logLevel)
+	if err != nil {
+		return nil, err
+	}
+	log.SetLevel(level)
+	return log, nil
+}
+
+// loadConfig merges the FilterConfiguration documents of all the files at paths, validating each of them against
+// the JSON Schema of its API when strict is set.
+func loadConfig(paths []string, strict bool) (*mirror.FilterConfiguration, error) {
+	load := mirror.LoadFilterConfigurationFiles
+	if strict {
+		load = mirror.LoadFilterConfigurationFilesStrict
+	}
+	config, err := load(paths...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter configuration: %v", err)
+	}
+	return config, nil
+}
+
+// pathList is a flag that can be repeated, each occurrence adding a path.
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathList) Set(path string) error {
+	*p = append(*p, path)
+	return nil
+}
+
+// pinImages pins the images of fbc to the digests of the JSON file at digestsPath, and warns about the ones that
+// were left unpinned.
+func pinImages(ctx context.Context, fbc *declcfg.DeclarativeConfig, digestsPath string, log *logrus.Logger) (*declcfg.DeclarativeConfig, error) {
+	digests, err := images.LoadDigestMapFile(digestsPath)
+	if err != nil {
+		return nil, err
+	}
+	report := &images.PinReport{}
+	pinned, err := images.NewPinner(digests, images.WithPinReport(report)).FilterCatalog(ctx, fbc)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range report.Bundles {
+		for _, ref := range b.Unresolved {
+			log.Warnf("package %q, bundle %q: image %q at %s not pinned: %s", b.Package, b.Bundle, ref.Reference, ref.Field, ref.Reason)
+		}
+	}
+	return pinned, nil
+}
+
+// rewriteImages rewrites the image references of fbc, and warns about the ones that were left unchanged.
+func rewriteImages(ctx context.Context, fbc *declcfg.DeclarativeConfig, rules images.Rewrites, log *logrus.Logger) (*declcfg.DeclarativeConfig, error) {
+	report := &images.RewriteReport{}
+	rewritten, err := images.NewRewriter(rules, images.WithRewriteReport(report)).FilterCatalog(ctx, fbc)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range report.Unrewritten {
+		log.Warnf("package %q, bundle %q: image %q at %s not rewritten: %s", ref.Package, ref.Bundle, ref.Reference, ref.Field, ref.Reason)
+	}
+	return rewritten, nil
+}
+
+// rewriteList is a flag that can be repeated, each occurrence adding a from=to rewrite rule.
+type rewriteList []images.Rewrite
+
+func (r *rewriteList) String() string {
+	rules := make([]string, 0, len(*r))
+	for _, rule := range *r {
+		rules = append(rules, rule.From+"="+rule.To)
+	}
+	return strings.Join(rules, ",")
+}
+
+func (r *rewriteList) Set(rule string) error {
+	from, to, ok := strings.Cut(rule, "=")
+	if !ok || from == "" || to == "" {
+		return fmt.Errorf("expected from=to, got %q", rule)
+	}
+	*r = append(*r, images.Rewrite{From: from, To: to})
+	return nil
+}
+
+// catalogFS returns the catalog directory, or the catalog of the image of an oci:<layout-dir>[:<reference>] OCI
+// image layout.
+func catalogFS(catalog string) (fs.FS, error) {
+	dir, reference, ok := ociLayout(catalog)
+	if !ok {
+		return os.DirFS(catalog), nil
+	}
+	var opts []ocilayout.Option
+	if reference != "" {
+		opts = append(opts, ocilayout.WithReference(reference))
+	}
+	root, err := ocilayout.ConfigsFS(dir, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read catalog %q: %v", catalog, err)
+	}
+	return root, nil
+}
+
+// ociLayout splits an oci:<layout-dir>[:<reference>] catalog into its layout directory and image reference name.
+func ociLayout(catalog string) (string, string, bool) {
+	layout, ok := strings.CutPrefix(catalog, ociPrefix)
+	if !ok {
+		return "", "", false
+	}
+	dir, reference, _ := strings.Cut(layout, ":")
+	return dir, reference, true
+}
+
+// filterCatalog streams the catalog through the filter's KeepMeta when the configuration selects packages.
+// An empty configuration keeps every package, which KeepMeta cannot express, so the whole catalog is loaded.
+func filterCatalog(ctx context.Context, root fs.FS, f filter.CatalogFilter, selectsPackages bool) (*declcfg.DeclarativeConfig, error) {
+	if selectsPackages {
+		return filter.FilterFS(ctx, root, f)
+	}
+	fbc, err := declcfg.LoadFS(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	return f.FilterCatalog(ctx, fbc)
+}
+
+func parseFlags(args []string, stderr io.Writer) (options, error) {
+	opts := options{}
+	flags := flag.NewFlagSet("catalog-filter", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: catalog-filter [flags] <catalog-dir>\n       catalog-filter graph [flags] <catalog-dir>\n       catalog-filter schema [flags]\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	flags.Var(&opts.configPaths, "config", "path to a FilterConfiguration file, repeat it to merge several files (required)")
+	flags.BoolVar(&opts.strict, "strict", false, "validate the configuration files against the JSON Schema of their API, reporting unknown fields with their line and column")
+	flags.StringVar(&opts.output, "output", outputJSON, "output format: json, yaml, dir or oci")
+	flags.StringVar(&opts.dest, "dest", "", "destination of the filtered catalog: a file for json and yaml (defaults to stdout), a directory for dir and oci")
+	flags.BoolVar(&opts.full, "full", false, "keep all bundles of the filtered channels instead of only the channel heads")
+	flags.BoolVar(&opts.deps, "dependencies", false, "keep the bundles providing the packages and GVKs required by the kept bundles")
+	flags.StringVar(&opts.heads, "multiple-heads", "", "how channels with several heads are filtered: error (default), keepAll or keepHighest")
+	flags.StringVar(&opts.reportPath, "report", "", "path of a JSON file explaining why each package, channel and bundle was kept or removed")
+	flags.StringVar(&opts.imagesPath, "images", "", "path of a file listing the bundle and related images of the filtered catalog")
+	flags.StringVar(&opts.imagesFormat, "images-format", imagesText, "format of the images file: text, json or mapping")
+	flags.StringVar(&opts.mirrorTo, "mirror-to", "", "registry, optionally followed by a namespace, the images are mirrored to (required for the mapping format)")
+	flags.StringVar(&opts.digestsPath, "digests", "", "path of a JSON file mapping tag references to digests, the images of the filtered catalog are pinned to")
+	flags.Var(&opts.rewrites, "rewrite-registry", "from=to rule rewriting the image references of the filtered catalog whose registry, or repository prefix, is from, repeat it to add rules")
+	flags.BoolVar(&opts.canonical, "canonical", false, "write the filtered catalog in canonical order, for catalogs with the same content to be byte-identical")
+	flags.StringVar(&opts.logLevel, "log-level", "warning", "log level: panic, fatal, error, warning, info, debug or trace")
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+
+	var errs []error
+	if len(opts.configPaths) == 0 {
+		errs = append(errs, errors.New("--config must be specified"))
+	}
+	switch flags.NArg() {
+	case 0:
+		errs = append(errs, errors.New("a catalog directory must be specified"))
+	case 1:
+		opts.catalogDir = flags.Arg(0)
+	default:
+		errs = append(errs, fmt.Errorf("expected exactly one catalog directory, got %d", flags.NArg()))
+	}
+	switch opts.output {
+	case outputJSON, outputYAML:
+	case outputDir, outputOCI:
+		if opts.dest == "" {
+			errs = append(errs, fmt.Errorf("--dest must be specified when --output is %s", opts.output))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unsupported output format %q", opts.output))
+	}
+	switch opts.imagesFormat {
+	case imagesText, imagesJSON:
+	case imagesMapping:
+		if opts.mirrorTo == "" {
+			errs = append(errs, errors.New("--mirror-to must be specified when --images-format is mapping"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unsupported images format %q", opts.imagesFormat))
+	}
+	if err := filter.MultipleHeadsPolicy(opts.heads).Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid --multiple-heads: %v", err))
+	}
+	return opts, errors.Join(errs...)
+}
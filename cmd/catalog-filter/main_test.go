@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+
+	mirror "github.com/sherine-k/catalog-filter/pkg/filter/mirror-config/v1alpha1"
+	"github.com/sherine-k/catalog-filter/pkg/ocilayout"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      func(t *testing.T) []string
+		assertion func(*testing.T, *bytes.Buffer, error)
+	}{
+		{
+			name: "WHEN no config THEN Returns error",
+			args: func(t *testing.T) []string { return []string{"testdata/catalog"} },
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, "--config must be specified")
+			},
+		},
+		{
+			name: "WHEN no catalog directory THEN Returns error",
+			args: func(t *testing.T) []string { return []string{"--config", "testdata/config.yaml"} },
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, "a catalog directory must be specified")
+			},
+		},
+		{
+			name: "WHEN output is dir without dest THEN Returns error",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--output", "dir", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, "--dest must be specified when --output is dir")
+			},
+		},
+		{
+			name: "WHEN unknown output format THEN Returns error",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--output", "xml", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, `unsupported output format "xml"`)
+			},
+		},
+		{
+			name: "WHEN unknown multiple heads policy THEN Returns error",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--multiple-heads", "keepOne", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, `invalid --multiple-heads: unknown multiple heads policy "keepOne"`)
+			},
+		},
+		{
+			name: "WHEN images format is mapping without mirror-to THEN Returns error",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--images", "images.txt", "--images-format", "mapping", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, "--mirror-to must be specified when --images-format is mapping")
+			},
+		},
+		{
+			name: "WHEN unknown images format THEN Returns error",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--images", "images.txt", "--images-format", "csv", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, `unsupported images format "csv"`)
+			},
+		},
+		{
+			name: "WHEN rewrite rule has no target THEN Returns error",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--rewrite-registry", "quay.io", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, `invalid value "quay.io" for flag -rewrite-registry: expected from=to, got "quay.io"`)
+			},
+		},
+		{
+			name: "WHEN rewrite rules THEN Writes catalog pointing at the mirror registry",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--rewrite-registry", "quay.io/example=mirror.example.com/ns", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, stdout *bytes.Buffer, err error) {
+				require.NoError(t, err)
+				fbc, err := declcfg.LoadReader(stdout)
+				require.NoError(t, err)
+				assertFilteredCatalog(t, fbc)
+				for _, b := range fbc.Bundles {
+					assert.Regexp(t, `^mirror\.example\.com/ns/foo-bundle:v0\.[23]\.0$`, b.Image)
+				}
+			},
+		},
+		{
+			name: "WHEN digests THEN Writes catalog pinned to the digests",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--digests", "testdata/digests.json", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, stdout *bytes.Buffer, err error) {
+				require.NoError(t, err)
+				fbc, err := declcfg.LoadReader(stdout)
+				require.NoError(t, err)
+				assertFilteredCatalog(t, fbc)
+				images := map[string]string{}
+				for _, b := range fbc.Bundles {
+					images[b.Name] = b.Image
+				}
+				assert.Equal(t, map[string]string{
+					"foo.v0.2.0": "quay.io/example/foo-bundle:v0.2.0",
+					"foo.v0.3.0": "quay.io/example/foo-bundle@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+				}, images)
+			},
+		},
+		{
+			name: "WHEN output is json THEN Writes filtered catalog to stdout",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, stdout *bytes.Buffer, err error) {
+				require.NoError(t, err)
+				fbc, err := declcfg.LoadReader(stdout)
+				require.NoError(t, err)
+				assertFilteredCatalog(t, fbc)
+			},
+		},
+		{
+			name: "WHEN several configs THEN Filters with the merged configuration",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--config", "testdata/config.yaml", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, stdout *bytes.Buffer, err error) {
+				require.NoError(t, err)
+				fbc, err := declcfg.LoadReader(stdout)
+				require.NoError(t, err)
+				assertFilteredCatalog(t, fbc)
+			},
+		},
+		{
+			name: "WHEN strict THEN Filters with the validated configuration",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--strict", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, stdout *bytes.Buffer, err error) {
+				require.NoError(t, err)
+				fbc, err := declcfg.LoadReader(stdout)
+				require.NoError(t, err)
+				assertFilteredCatalog(t, fbc)
+			},
+		},
+		{
+			name: "WHEN strict and a config has an unknown field THEN Returns error with its line and column",
+			args: func(t *testing.T) []string {
+				config := filepath.Join(t.TempDir(), "config.yaml")
+				require.NoError(t, os.WriteFile(config, []byte("apiVersion: olm.operatorframework.io/filter/mirror/v1alpha1\nkind: FilterConfiguration\npackages:\n  - name: foo\n    versionRnage: '>=0.2.0'\n"), 0o600))
+				return []string{"--config", config, "--strict", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, `document [0] is invalid: invalid filter configuration: line 5, column 5: packages[0]: unknown field "versionRnage"`)
+			},
+		},
+		{
+			name: "WHEN a config is missing THEN Returns error",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--config", "testdata/missing.yaml", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, "invalid filter configuration: open testdata/missing.yaml: no such file or directory")
+			},
+		},
+		{
+			name: "WHEN output is yaml with dest THEN Writes filtered catalog to file",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--output", "yaml", "--dest", filepath.Join(t.TempDir(), "catalog.yaml"), "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, stdout *bytes.Buffer, err error) {
+				require.NoError(t, err)
+				assert.Empty(t, stdout.String())
+			},
+		},
+		{
+			name: "WHEN full THEN Returns error when mixed with versionRange",
+			args: func(t *testing.T) []string {
+				return []string{"--config", "testdata/config.yaml", "--full", "testdata/catalog"}
+			},
+			assertion: func(t *testing.T, _ *bytes.Buffer, err error) {
+				assert.ErrorContains(t, err, "Full: true cannot be mixed with versionRange")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			err := run(context.Background(), tt.args(t), stdout, &bytes.Buffer{})
+			tt.assertion(t, stdout, err)
+		})
+	}
+}
+
+func TestRun_OutputDir(t *testing.T) {
+	dest := t.TempDir()
+	err := run(context.Background(), []string{"--config", "testdata/config.yaml", "--output", "dir", "--dest", dest, "testdata/catalog"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dest, "foo", "catalog.json"))
+	fbc, err := declcfg.LoadFS(context.Background(), os.DirFS(dest))
+	require.NoError(t, err)
+	assertFilteredCatalog(t, fbc)
+}
+
+func TestRun_Report(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	err := run(context.Background(), []string{"--config", "testdata/config.yaml", "--report", reportPath, "testdata/catalog"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	data, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+	report := mirror.FilterReport{}
+	require.NoError(t, json.Unmarshal(data, &report))
+	// bar is dropped while the catalog is streamed, before it reaches FilterCatalog
+	bar, ok := report.Package("bar")
+	require.True(t, ok)
+	assert.Equal(t, mirror.Removed, bar.Decision)
+	assert.Equal(t, mirror.ReasonPackageNotSelected, bar.Reason)
+	foo, ok := report.Package("foo")
+	require.True(t, ok)
+	assert.Equal(t, mirror.Kept, foo.Decision)
+	stable, ok := foo.Channel("stable")
+	require.True(t, ok)
+	assert.Len(t, stable.Entries, 3)
+}
+
+func TestRun_Images(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "WHEN images format is text THEN Lists the images of the filtered catalog",
+			expected: `quay.io/example/foo-bundle:v0.2.0
+quay.io/example/foo-bundle:v0.3.0
+`,
+		},
+		{
+			name: "WHEN images format is mapping THEN Maps the images to the mirror registry",
+			args: []string{"--images-format", "mapping", "--mirror-to", "mirror.example.com/ns"},
+			expected: `quay.io/example/foo-bundle:v0.2.0=mirror.example.com/ns/example/foo-bundle:v0.2.0
+quay.io/example/foo-bundle:v0.3.0=mirror.example.com/ns/example/foo-bundle:v0.3.0
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imagesPath := filepath.Join(t.TempDir(), "images.txt")
+			args := append([]string{"--config", "testdata/config.yaml", "--images", imagesPath}, tt.args...)
+			err := run(context.Background(), append(args, "testdata/catalog"), &bytes.Buffer{}, &bytes.Buffer{})
+			require.NoError(t, err)
+			data, err := os.ReadFile(imagesPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestRun_ImagesMappingPinned(t *testing.T) {
+	dir := t.TempDir()
+	digestsPath := filepath.Join(dir, "digests.json")
+	require.NoError(t, os.WriteFile(digestsPath, []byte(`{
+    "quay.io/example/foo-bundle:v0.2.0": "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210",
+    "quay.io/example/foo-bundle:v0.3.0": "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+}`), 0644))
+	imagesPath := filepath.Join(dir, "mapping.txt")
+
+	args := []string{"--config", "testdata/config.yaml", "--digests", digestsPath, "--images", imagesPath, "--images-format", "mapping", "--mirror-to", "mirror.example.com/ns", "testdata/catalog"}
+	require.NoError(t, run(context.Background(), args, &bytes.Buffer{}, &bytes.Buffer{}))
+	data, err := os.ReadFile(imagesPath)
+	require.NoError(t, err)
+	assert.Equal(t, `quay.io/example/foo-bundle@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef=mirror.example.com/ns/example/foo-bundle:sha256-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+quay.io/example/foo-bundle@sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210=mirror.example.com/ns/example/foo-bundle:sha256-fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210
+`, string(data))
+}
+
+func TestRun_OCILayout(t *testing.T) {
+	catalog, err := declcfg.LoadFS(context.Background(), os.DirFS("testdata/catalog"))
+	require.NoError(t, err)
+	source := filepath.Join(t.TempDir(), "catalog")
+	require.NoError(t, ocilayout.Write(source, *catalog, ocilayout.WithReferenceName("v1")))
+
+	dest := filepath.Join(t.TempDir(), "filtered")
+	err = run(context.Background(), []string{"--config", "testdata/config.yaml", "--output", "oci", "--dest", dest, "oci:" + source + ":v1"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	fbc, err := ocilayout.Load(context.Background(), dest, ocilayout.WithReference("v1"))
+	require.NoError(t, err)
+	assertFilteredCatalog(t, fbc)
+
+	err = run(context.Background(), []string{"--config", "testdata/config.yaml", "oci:" + source + ":v2"}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, `unable to read catalog "oci:`+source+`:v2": no image named "v2"`)
+}
+
+func TestRun_Canonical(t *testing.T) {
+	catalog, err := declcfg.LoadFS(context.Background(), os.DirFS("testdata/catalog"))
+	require.NoError(t, err)
+	slices.Reverse(catalog.Packages)
+	slices.Reverse(catalog.Bundles)
+	for i := range catalog.Channels {
+		slices.Reverse(catalog.Channels[i].Entries)
+	}
+	shuffled := t.TempDir()
+	require.NoError(t, writeCatalog(*catalog, outputJSON, filepath.Join(shuffled, "catalog.json"), "", &bytes.Buffer{}))
+
+	write := func(catalogDir string) string {
+		stdout := &bytes.Buffer{}
+		err := run(context.Background(), []string{"--config", "testdata/config.yaml", "--canonical", catalogDir}, stdout, &bytes.Buffer{})
+		require.NoError(t, err)
+		return stdout.String()
+	}
+	assert.Equal(t, write("testdata/catalog"), write(shuffled))
+}
+
+func assertFilteredCatalog(t *testing.T, fbc *declcfg.DeclarativeConfig) {
+	require.Len(t, fbc.Packages, 1)
+	assert.Equal(t, "foo", fbc.Packages[0].Name)
+	require.Len(t, fbc.Channels, 1)
+	assert.Len(t, fbc.Channels[0].Entries, 2)
+	assert.Len(t, fbc.Bundles, 2)
+}
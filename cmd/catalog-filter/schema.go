@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	configv1alpha1 "github.com/sherine-k/catalog-filter/pkg/filter/config/v1alpha1"
+	mirror "github.com/sherine-k/catalog-filter/pkg/filter/mirror-config/v1alpha1"
+	"github.com/sherine-k/catalog-filter/pkg/schema"
+)
+
+// schemas are the JSON Schemas of the FilterConfiguration API versions.
+var schemas = map[string]func() *schema.Schema{
+	mirror.FilterAPIVersion:         mirror.JSONSchema,
+	configv1alpha1.FilterAPIVersion: configv1alpha1.JSONSchema,
+}
+
+// runSchema writes the JSON Schema of a FilterConfiguration API version.
+func runSchema(args []string, stdout, stderr io.Writer) error {
+	var apiVersion, dest string
+	flags := flag.NewFlagSet("catalog-filter schema", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: catalog-filter schema [flags]\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&apiVersion, "api-version", mirror.FilterAPIVersion, fmt.Sprintf("API version of the FilterConfiguration: %s or %s", mirror.FilterAPIVersion, configv1alpha1.FilterAPIVersion))
+	flags.StringVar(&dest, "dest", "", "file to write the schema to (defaults to stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	jsonSchema, ok := schemas[apiVersion]
+	if !ok {
+		return fmt.Errorf("unsupported API version %q", apiVersion)
+	}
+
+	if dest == "" {
+		return jsonSchema().Write(stdout)
+	}
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if err := jsonSchema().Write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("write file %q: %v", dest, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		file       string
+	}{
+		{name: "mirror", apiVersion: "olm.operatorframework.io/filter/mirror/v1alpha1", file: "mirror-filter-configuration.v1alpha1.json"},
+		{name: "config", apiVersion: "olm.operatorframework.io/v1alpha1", file: "filter-configuration.v1alpha1.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			require.NoError(t, run(context.Background(), []string{"schema", "--api-version", tt.apiVersion}, stdout, &bytes.Buffer{}))
+			expected, err := os.ReadFile(filepath.Join("../../schemas", tt.file))
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), stdout.String(), "the schema files are out of date, run go generate ./cmd/catalog-filter")
+		})
+	}
+	t.Run("WHEN API version is unknown THEN Returns error", func(t *testing.T) {
+		err := run(context.Background(), []string{"schema", "--api-version", "v1"}, &bytes.Buffer{}, &bytes.Buffer{})
+		assert.EqualError(t, err, `unsupported API version "v1"`)
+	})
+}
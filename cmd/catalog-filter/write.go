@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+
+	mirror "github.com/sherine-k/catalog-filter/pkg/filter/mirror-config/v1alpha1"
+	"github.com/sherine-k/catalog-filter/pkg/images"
+	"github.com/sherine-k/catalog-filter/pkg/ocilayout"
+)
+
+// writeCatalog writes fbc in the output format. The oci format builds the image on top of the image of catalog,
+// when it was read from an OCI image layout.
+func writeCatalog(fbc declcfg.DeclarativeConfig, output, dest, catalog string, stdout io.Writer) error {
+	switch output {
+	case outputDir:
+		return writeDir(fbc, dest)
+	case outputOCI:
+		var opts []ocilayout.WriteOption
+		if dir, reference, ok := ociLayout(catalog); ok {
+			var baseOpts []ocilayout.Option
+			if reference != "" {
+				baseOpts = append(baseOpts, ocilayout.WithReference(reference))
+				opts = append(opts, ocilayout.WithReferenceName(reference))
+			}
+			opts = append(opts, ocilayout.WithBaseImage(dir, baseOpts...))
+		}
+		return ocilayout.Write(dest, fbc, opts...)
+	case outputYAML:
+		return writeStream(fbc, declcfg.WriteYAML, dest, stdout)
+	default:
+		return writeStream(fbc, declcfg.WriteJSON, dest, stdout)
+	}
+}
+
+func writeStream(fbc declcfg.DeclarativeConfig, writeFunc declcfg.WriteFunc, dest string, stdout io.Writer) error {
+	if dest == "" {
+		return writeFunc(fbc, stdout)
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if err := writeFunc(fbc, f); err != nil {
+		f.Close()
+		return fmt.Errorf("write file %q: %v", dest, err)
+	}
+	return f.Close()
+}
+
+// writeDir writes one catalog.json per package under dest, the layout `opm render` users expect.
+// Unlike declcfg.WriteFS, deprecations and other meta objects are kept alongside their package.
+// Meta objects that belong to no package are written to dest/catalog.json.
+func writeDir(fbc declcfg.DeclarativeConfig, dest string) error {
+	byPackage := map[string]*declcfg.DeclarativeConfig{}
+	pkgConfig := func(name string) *declcfg.DeclarativeConfig {
+		cfg, ok := byPackage[name]
+		if !ok {
+			cfg = &declcfg.DeclarativeConfig{}
+			byPackage[name] = cfg
+		}
+		return cfg
+	}
+	for _, p := range fbc.Packages {
+		pkgConfig(p.Name).Packages = append(pkgConfig(p.Name).Packages, p)
+	}
+	for _, c := range fbc.Channels {
+		pkgConfig(c.Package).Channels = append(pkgConfig(c.Package).Channels, c)
+	}
+	for _, b := range fbc.Bundles {
+		pkgConfig(b.Package).Bundles = append(pkgConfig(b.Package).Bundles, b)
+	}
+	for _, d := range fbc.Deprecations {
+		pkgConfig(d.Package).Deprecations = append(pkgConfig(d.Package).Deprecations, d)
+	}
+	for _, o := range fbc.Others {
+		pkgConfig(o.Package).Others = append(pkgConfig(o.Package).Others, o)
+	}
+
+	if err := os.MkdirAll(dest, 0777); err != nil {
+		return err
+	}
+	for pkg, cfg := range byPackage {
+		dir := filepath.Join(dest, pkg)
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
+		if err := writeStream(*cfg, declcfg.WriteJSON, filepath.Join(dir, "catalog.json"), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeReport(report mirror.FilterReport, dest string) error {
+	data, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, 0666)
+}
+
+// writeImages writes the images of the filtered catalog to dest, in the given format. The mapping format mirrors
+// them to mirrorTo.
+func writeImages(fbc declcfg.DeclarativeConfig, format, mirrorTo, dest string) error {
+	list := images.List(&fbc)
+	var writeFunc func(io.Writer) error
+	switch format {
+	case imagesJSON:
+		writeFunc = func(w io.Writer) error { return images.WriteJSON(w, list) }
+	case imagesMapping:
+		writeFunc = func(w io.Writer) error {
+			return images.WriteMapping(w, list, images.MappingOptions{Destination: mirrorTo})
+		}
+	default:
+		writeFunc = func(w io.Writer) error { return images.WriteText(w, list) }
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if err := writeFunc(f); err != nil {
+		f.Close()
+		return fmt.Errorf("write file %q: %v", dest, err)
+	}
+	return f.Close()
+}
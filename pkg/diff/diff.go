@@ -0,0 +1,252 @@
+// Package diff compares two file based catalogs, typically the outputs of two successive
+// FilterCatalog runs, and computes what was added, removed or modified between them.
+package diff
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+type ChangeType string
+
+const (
+	Added    ChangeType = "added"
+	Removed  ChangeType = "removed"
+	Modified ChangeType = "modified"
+)
+
+type Kind string
+
+const (
+	KindPackage          Kind = "package"
+	KindChannel          Kind = "channel"
+	KindChannelEntry     Kind = "channelEntry"
+	KindBundle           Kind = "bundle"
+	KindDeprecationEntry Kind = "deprecationEntry"
+)
+
+// Change is a single difference between two catalogs.
+// Channel is only set for channel entries, and Reference only for deprecation entries.
+type Change struct {
+	Type      ChangeType                      `json:"type"`
+	Kind      Kind                            `json:"kind"`
+	Package   string                          `json:"package"`
+	Channel   string                          `json:"channel,omitempty"`
+	Name      string                          `json:"name,omitempty"`
+	Reference *declcfg.PackageScopedReference `json:"reference,omitempty"`
+}
+
+// ChangeSet is the ordered list of changes between an old and a new catalog.
+type ChangeSet struct {
+	Changes []Change `json:"changes"`
+}
+
+// Empty returns true when both catalogs hold the same packages, channels, bundles and deprecations.
+func (c ChangeSet) Empty() bool {
+	return len(c.Changes) == 0
+}
+
+// Select returns the changes of the given kind and type.
+func (c ChangeSet) Select(kind Kind, changeType ChangeType) []Change {
+	var selected []Change
+	for _, change := range c.Changes {
+		if change.Kind == kind && change.Type == changeType {
+			selected = append(selected, change)
+		}
+	}
+	return selected
+}
+
+// catalogIndex keys a catalog the same way the mirror filter's operatorIndex does:
+// by package and bundle name for bundles, and by package, channel and entry name for channel entries.
+type catalogIndex struct {
+	Packages            map[string]declcfg.Package
+	ChannelEntries      map[string]map[string]map[string]declcfg.ChannelEntry
+	BundlesByPkgAndName map[string]map[string]declcfg.Bundle
+	DeprecationEntries  map[string]map[declcfg.PackageScopedReference]declcfg.DeprecationEntry
+}
+
+func newCatalogIndex(fbc *declcfg.DeclarativeConfig) catalogIndex {
+	index := catalogIndex{
+		Packages:            make(map[string]declcfg.Package),
+		ChannelEntries:      make(map[string]map[string]map[string]declcfg.ChannelEntry),
+		BundlesByPkgAndName: make(map[string]map[string]declcfg.Bundle),
+		DeprecationEntries:  make(map[string]map[declcfg.PackageScopedReference]declcfg.DeprecationEntry),
+	}
+	if fbc == nil {
+		return index
+	}
+	for _, p := range fbc.Packages {
+		index.Packages[p.Name] = p
+	}
+	for _, c := range fbc.Channels {
+		if _, ok := index.ChannelEntries[c.Package]; !ok {
+			index.ChannelEntries[c.Package] = make(map[string]map[string]declcfg.ChannelEntry)
+		}
+		if _, ok := index.ChannelEntries[c.Package][c.Name]; !ok {
+			index.ChannelEntries[c.Package][c.Name] = make(map[string]declcfg.ChannelEntry)
+		}
+		for _, e := range c.Entries {
+			index.ChannelEntries[c.Package][c.Name][e.Name] = e
+		}
+	}
+	for _, b := range fbc.Bundles {
+		if _, ok := index.BundlesByPkgAndName[b.Package]; !ok {
+			index.BundlesByPkgAndName[b.Package] = make(map[string]declcfg.Bundle)
+		}
+		index.BundlesByPkgAndName[b.Package][b.Name] = b
+	}
+	for _, d := range fbc.Deprecations {
+		if _, ok := index.DeprecationEntries[d.Package]; !ok {
+			index.DeprecationEntries[d.Package] = make(map[declcfg.PackageScopedReference]declcfg.DeprecationEntry)
+		}
+		for _, e := range d.Entries {
+			index.DeprecationEntries[d.Package][e.Reference] = e
+		}
+	}
+	return index
+}
+
+// Diff compares oldFBC to newFBC. Either of them may be nil, in which case it is considered empty.
+// Channel entries are compared by name, as well as their replaces and skips edges: an entry whose
+// edges changed is reported as modified. Bundles with the same name are reported as modified
+// when any of their fields differ.
+func Diff(oldFBC, newFBC *declcfg.DeclarativeConfig) ChangeSet {
+	oldIndex, newIndex := newCatalogIndex(oldFBC), newCatalogIndex(newFBC)
+	changes := []Change{}
+
+	changes = append(changes, diffMaps(oldIndex.Packages, newIndex.Packages, func(t ChangeType, name string) Change {
+		return Change{Type: t, Kind: KindPackage, Package: name, Name: name}
+	}, nil)...)
+
+	for _, pkg := range unionKeys(oldIndex.ChannelEntries, newIndex.ChannelEntries) {
+		oldChannels, newChannels := oldIndex.ChannelEntries[pkg], newIndex.ChannelEntries[pkg]
+		changes = append(changes, diffMaps(oldChannels, newChannels, func(t ChangeType, name string) Change {
+			return Change{Type: t, Kind: KindChannel, Package: pkg, Name: name}
+		}, func(map[string]declcfg.ChannelEntry, map[string]declcfg.ChannelEntry) bool { return true })...)
+		for _, ch := range unionKeys(oldChannels, newChannels) {
+			changes = append(changes, diffMaps(oldChannels[ch], newChannels[ch], func(t ChangeType, name string) Change {
+				return Change{Type: t, Kind: KindChannelEntry, Package: pkg, Channel: ch, Name: name}
+			}, nil)...)
+		}
+	}
+
+	for _, pkg := range unionKeys(oldIndex.BundlesByPkgAndName, newIndex.BundlesByPkgAndName) {
+		changes = append(changes, diffMaps(oldIndex.BundlesByPkgAndName[pkg], newIndex.BundlesByPkgAndName[pkg], func(t ChangeType, name string) Change {
+			return Change{Type: t, Kind: KindBundle, Package: pkg, Name: name}
+		}, nil)...)
+	}
+
+	for _, pkg := range unionKeys(oldIndex.DeprecationEntries, newIndex.DeprecationEntries) {
+		oldEntries, newEntries := oldIndex.DeprecationEntries[pkg], newIndex.DeprecationEntries[pkg]
+		for _, ref := range unionKeysFunc(oldEntries, newEntries, compareReferences) {
+			oldEntry, inOld := oldEntries[ref]
+			newEntry, inNew := newEntries[ref]
+			reference := ref
+			change := Change{Kind: KindDeprecationEntry, Package: pkg, Name: ref.Name, Reference: &reference}
+			switch {
+			case !inOld:
+				change.Type = Added
+			case !inNew:
+				change.Type = Removed
+			case !reflect.DeepEqual(oldEntry, newEntry):
+				change.Type = Modified
+			default:
+				continue
+			}
+			changes = append(changes, change)
+		}
+	}
+	return ChangeSet{Changes: changes}
+}
+
+// Delta returns a catalog holding only the bundles of newFBC that were added or modified since oldFBC, along
+// with their packages and the channels of newFBC they are entries of, so that an incremental mirror only copies
+// what changed. The channels only keep the entries of these bundles, with their replaces and skips unchanged.
+// The delta loads as a catalog, but it does not pass validation when unchanged bundles are needed to link its
+// entries into a single upgrade graph, or when the default channel of a package has no changed bundle.
+func Delta(oldFBC, newFBC *declcfg.DeclarativeConfig) *declcfg.DeclarativeConfig {
+	changes := Diff(oldFBC, newFBC)
+	newIndex := newCatalogIndex(newFBC)
+	delta := &declcfg.DeclarativeConfig{}
+	bundles := map[string]map[string]struct{}{}
+	for _, c := range changes.Changes {
+		if c.Kind != KindBundle || c.Type == Removed {
+			continue
+		}
+		delta.Bundles = append(delta.Bundles, newIndex.BundlesByPkgAndName[c.Package][c.Name])
+		if _, ok := bundles[c.Package]; !ok {
+			bundles[c.Package] = map[string]struct{}{}
+		}
+		bundles[c.Package][c.Name] = struct{}{}
+	}
+	for _, pkg := range sortedKeys(bundles) {
+		if p, ok := newIndex.Packages[pkg]; ok {
+			delta.Packages = append(delta.Packages, p)
+		}
+		channels := newIndex.ChannelEntries[pkg]
+		for _, name := range sortedKeys(channels) {
+			ch := declcfg.Channel{Schema: declcfg.SchemaChannel, Package: pkg, Name: name}
+			for _, entry := range sortedKeys(channels[name]) {
+				if _, ok := bundles[pkg][entry]; ok {
+					ch.Entries = append(ch.Entries, channels[name][entry])
+				}
+			}
+			if len(ch.Entries) > 0 {
+				delta.Channels = append(delta.Channels, ch)
+			}
+		}
+	}
+	return delta
+}
+
+// diffMaps reports the keys only present in oldMap as removed, the keys only present in newMap as added,
+// and the keys whose values differ as modified. When equal is nil, values are compared with reflect.DeepEqual.
+func diffMaps[V any](oldMap, newMap map[string]V, change func(ChangeType, string) Change, equal func(V, V) bool) []Change {
+	if equal == nil {
+		equal = func(a, b V) bool { return reflect.DeepEqual(a, b) }
+	}
+	var changes []Change
+	for _, key := range unionKeys(oldMap, newMap) {
+		oldValue, inOld := oldMap[key]
+		newValue, inNew := newMap[key]
+		switch {
+		case !inOld:
+			changes = append(changes, change(Added, key))
+		case !inNew:
+			changes = append(changes, change(Removed, key))
+		case !equal(oldValue, newValue):
+			changes = append(changes, change(Modified, key))
+		}
+	}
+	return changes
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	return unionKeysFunc(a, b, cmp.Compare[string])
+}
+
+func unionKeysFunc[K comparable, V any](a, b map[K]V, compare func(K, K) int) []K {
+	keys := make([]K, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, compare)
+	return keys
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	return unionKeys(m, nil)
+}
+
+func compareReferences(a, b declcfg.PackageScopedReference) int {
+	return cmp.Or(cmp.Compare(a.Schema, b.Schema), cmp.Compare(a.Name, b.Name))
+}
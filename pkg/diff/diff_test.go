@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+func oldCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}, {Name: "bar", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v1"}}},
+			{Name: "beta", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v1"}}},
+			{Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{{Name: "bar.v1"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			{Name: "foo.v1", Package: "foo", Image: "quay.io/foo/bundle:v1", Properties: []property.Property{property.MustBuildPackage("foo", "1.0.0")}},
+			{Name: "bar.v1", Package: "bar", Image: "quay.io/bar/bundle:v1", Properties: []property.Property{property.MustBuildPackage("bar", "1.0.0")}},
+		},
+		Deprecations: []declcfg.Deprecation{{Package: "foo", Entries: []declcfg.DeprecationEntry{
+			{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaChannel, Name: "beta"}, Message: "beta is deprecated"},
+		}}},
+	}
+}
+
+func newCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}, {Name: "baz", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v1"}, {Name: "foo.v2", Replaces: "foo.v1"}}},
+			{Name: "stable", Package: "baz", Entries: []declcfg.ChannelEntry{{Name: "baz.v1"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			{Name: "foo.v1", Package: "foo", Image: "quay.io/foo/bundle:v1", Properties: []property.Property{property.MustBuildPackage("foo", "1.0.0")}},
+			{Name: "foo.v2", Package: "foo", Image: "quay.io/foo/bundle:v2", Properties: []property.Property{property.MustBuildPackage("foo", "2.0.0")}},
+			{Name: "baz.v1", Package: "baz", Image: "quay.io/baz/bundle:v1", Properties: []property.Property{property.MustBuildPackage("baz", "1.0.0")}},
+		},
+		Deprecations: []declcfg.Deprecation{{Package: "foo", Entries: []declcfg.DeprecationEntry{
+			{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v1"}, Message: "foo.v1 is deprecated"},
+		}}},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		oldFBC    *declcfg.DeclarativeConfig
+		newFBC    *declcfg.DeclarativeConfig
+		assertion func(*testing.T, ChangeSet)
+	}{
+		{
+			name:   "WHEN both catalogs are equal THEN returns no changes",
+			oldFBC: oldCatalog(),
+			newFBC: oldCatalog(),
+			assertion: func(t *testing.T, changes ChangeSet) {
+				assert.True(t, changes.Empty())
+			},
+		},
+		{
+			name:   "WHEN old catalog is nil THEN everything is added",
+			oldFBC: nil,
+			newFBC: oldCatalog(),
+			assertion: func(t *testing.T, changes ChangeSet) {
+				assert.Len(t, changes.Select(KindPackage, Added), 2)
+				assert.Len(t, changes.Select(KindChannel, Added), 3)
+				assert.Len(t, changes.Select(KindChannelEntry, Added), 3)
+				assert.Len(t, changes.Select(KindBundle, Added), 2)
+				assert.Len(t, changes.Select(KindDeprecationEntry, Added), 1)
+			},
+		},
+		{
+			name:   "WHEN catalogs differ THEN returns ordered changes",
+			oldFBC: oldCatalog(),
+			newFBC: newCatalog(),
+			assertion: func(t *testing.T, changes ChangeSet) {
+				assert.Equal(t, []Change{
+					{Type: Removed, Kind: KindPackage, Package: "bar", Name: "bar"},
+					{Type: Added, Kind: KindPackage, Package: "baz", Name: "baz"},
+					{Type: Removed, Kind: KindChannel, Package: "bar", Name: "stable"},
+					{Type: Removed, Kind: KindChannelEntry, Package: "bar", Channel: "stable", Name: "bar.v1"},
+					{Type: Added, Kind: KindChannel, Package: "baz", Name: "stable"},
+					{Type: Added, Kind: KindChannelEntry, Package: "baz", Channel: "stable", Name: "baz.v1"},
+					{Type: Removed, Kind: KindChannel, Package: "foo", Name: "beta"},
+					{Type: Removed, Kind: KindChannelEntry, Package: "foo", Channel: "beta", Name: "foo.v1"},
+					{Type: Added, Kind: KindChannelEntry, Package: "foo", Channel: "stable", Name: "foo.v2"},
+					{Type: Removed, Kind: KindBundle, Package: "bar", Name: "bar.v1"},
+					{Type: Added, Kind: KindBundle, Package: "baz", Name: "baz.v1"},
+					{Type: Added, Kind: KindBundle, Package: "foo", Name: "foo.v2"},
+					{Type: Added, Kind: KindDeprecationEntry, Package: "foo", Name: "foo.v1", Reference: &declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v1"}},
+					{Type: Removed, Kind: KindDeprecationEntry, Package: "foo", Name: "beta", Reference: &declcfg.PackageScopedReference{Schema: declcfg.SchemaChannel, Name: "beta"}},
+				}, changes.Changes)
+			},
+		},
+		{
+			name:   "WHEN a bundle image changes THEN the bundle is modified",
+			oldFBC: oldCatalog(),
+			newFBC: func() *declcfg.DeclarativeConfig {
+				fbc := oldCatalog()
+				fbc.Bundles[0].Image = "quay.io/foo/bundle:v1-rebuilt"
+				return fbc
+			}(),
+			assertion: func(t *testing.T, changes ChangeSet) {
+				assert.Equal(t, []Change{{Type: Modified, Kind: KindBundle, Package: "foo", Name: "foo.v1"}}, changes.Changes)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.assertion(t, Diff(tt.oldFBC, tt.newFBC))
+		})
+	}
+}
+
+func TestDelta(t *testing.T) {
+	delta := Delta(oldCatalog(), newCatalog())
+	require.NotNil(t, delta)
+	assert.Equal(t, []declcfg.Package{{Name: "baz", DefaultChannel: "stable"}, {Name: "foo", DefaultChannel: "stable"}}, delta.Packages)
+	assert.Equal(t, []declcfg.Bundle{
+		{Name: "baz.v1", Package: "baz", Image: "quay.io/baz/bundle:v1", Properties: []property.Property{property.MustBuildPackage("baz", "1.0.0")}},
+		{Name: "foo.v2", Package: "foo", Image: "quay.io/foo/bundle:v2", Properties: []property.Property{property.MustBuildPackage("foo", "2.0.0")}},
+	}, delta.Bundles)
+	assert.Equal(t, []declcfg.Channel{
+		{Schema: declcfg.SchemaChannel, Name: "stable", Package: "baz", Entries: []declcfg.ChannelEntry{{Name: "baz.v1"}}},
+		{Schema: declcfg.SchemaChannel, Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v2", Replaces: "foo.v1"}}},
+	}, delta.Channels)
+	m, err := declcfg.ConvertToModel(*delta)
+	require.NoError(t, err)
+	assert.NoError(t, m.Validate())
+
+	assert.Equal(t, &declcfg.DeclarativeConfig{}, Delta(newCatalog(), newCatalog()))
+}
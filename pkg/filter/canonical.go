@@ -0,0 +1,168 @@
+package filter
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
+)
+
+// Canonical returns a CatalogFilter that puts catalogs in canonical order, see Canonicalize. It is typically
+// chained after another filter, for the catalogs it writes to be compared or committed.
+func Canonical() CatalogFilter {
+	return canonicalFilter{}
+}
+
+type canonicalFilter struct{}
+
+func (canonicalFilter) FilterCatalog(_ context.Context, fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	return Canonicalize(fbc)
+}
+
+// Canonicalize returns a copy of fbc in canonical order, so that catalogs holding the same packages, channels,
+// bundles, deprecations and other meta objects are written identically:
+//   - packages are sorted by name, channels, bundles and deprecations by package and name, and other meta
+//     objects by package, schema and name
+//   - channel entries are in topological upgrade order, heads first, and the skips of each entry sorted
+//   - properties are sorted by type and value, and related images by name and image
+//   - the values of properties and the other meta objects are written as compact JSON with sorted keys
+//
+// It fails when a channel has duplicate entries or a cycle, or when a value is not valid JSON.
+func Canonicalize(fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	if fbc == nil {
+		return nil, nil
+	}
+	out := cloneCatalog(fbc)
+
+	slices.SortFunc(out.Packages, func(a, b declcfg.Package) int { return strings.Compare(a.Name, b.Name) })
+	for i := range out.Packages {
+		p := &out.Packages[i]
+		if err := canonicalProperties(p.Properties); err != nil {
+			return nil, fmt.Errorf("package %q: %v", p.Name, err)
+		}
+	}
+
+	slices.SortFunc(out.Channels, func(a, b declcfg.Channel) int {
+		return cmp.Or(strings.Compare(a.Package, b.Package), strings.Compare(a.Name, b.Name))
+	})
+	for i := range out.Channels {
+		ch := &out.Channels[i]
+		if err := canonicalEntries(ch); err != nil {
+			return nil, fmt.Errorf("package %q, channel %q: %v", ch.Package, ch.Name, err)
+		}
+		ch.Properties = slices.Clone(ch.Properties)
+		if err := canonicalProperties(ch.Properties); err != nil {
+			return nil, fmt.Errorf("package %q, channel %q: %v", ch.Package, ch.Name, err)
+		}
+	}
+
+	slices.SortFunc(out.Bundles, func(a, b declcfg.Bundle) int {
+		return cmp.Or(strings.Compare(a.Package, b.Package), strings.Compare(a.Name, b.Name))
+	})
+	for i := range out.Bundles {
+		b := &out.Bundles[i]
+		if err := canonicalProperties(b.Properties); err != nil {
+			return nil, fmt.Errorf("package %q, bundle %q: %v", b.Package, b.Name, err)
+		}
+		slices.SortFunc(b.RelatedImages, func(a, b declcfg.RelatedImage) int {
+			return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.Image, b.Image))
+		})
+	}
+
+	slices.SortFunc(out.Deprecations, func(a, b declcfg.Deprecation) int { return strings.Compare(a.Package, b.Package) })
+	for i := range out.Deprecations {
+		slices.SortFunc(out.Deprecations[i].Entries, func(a, b declcfg.DeprecationEntry) int {
+			return cmp.Or(cmp.Compare(referenceRank(a.Reference.Schema), referenceRank(b.Reference.Schema)),
+				strings.Compare(a.Reference.Schema, b.Reference.Schema), strings.Compare(a.Reference.Name, b.Reference.Name))
+		})
+	}
+
+	for i := range out.Others {
+		o := &out.Others[i]
+		blob, err := canonicalJSON(o.Blob)
+		if err != nil {
+			return nil, fmt.Errorf("package %q, %s %q: %v", o.Package, o.Schema, o.Name, err)
+		}
+		o.Blob = blob
+	}
+	slices.SortFunc(out.Others, func(a, b declcfg.Meta) int {
+		return cmp.Or(strings.Compare(a.Package, b.Package), strings.Compare(a.Schema, b.Schema), strings.Compare(a.Name, b.Name),
+			bytes.Compare(a.Blob, b.Blob))
+	})
+	return out, nil
+}
+
+// canonicalEntries sorts the entries of ch in topological order, and their skips by name.
+func canonicalEntries(ch *declcfg.Channel) error {
+	if len(ch.Entries) == 0 {
+		return nil
+	}
+	g, err := graph.New(*ch, graph.WithMultipleHeads(true))
+	if err != nil {
+		return err
+	}
+	order := map[string]int{}
+	for i, name := range g.TopologicalOrder() {
+		order[name] = i
+	}
+	slices.SortFunc(ch.Entries, func(a, b declcfg.ChannelEntry) int { return cmp.Compare(order[a.Name], order[b.Name]) })
+	for i := range ch.Entries {
+		slices.Sort(ch.Entries[i].Skips)
+	}
+	return nil
+}
+
+// canonicalProperties writes the values of properties as canonical JSON, and sorts them by type and value.
+func canonicalProperties(properties []property.Property) error {
+	for i := range properties {
+		value, err := canonicalJSON(properties[i].Value)
+		if err != nil {
+			return fmt.Errorf("property %q at index [%d]: %v", properties[i].Type, i, err)
+		}
+		properties[i].Value = value
+	}
+	slices.SortFunc(properties, func(a, b property.Property) int {
+		return cmp.Or(strings.Compare(a.Type, b.Type), bytes.Compare(a.Value, b.Value))
+	})
+	return nil
+}
+
+// canonicalJSON returns data as compact JSON with sorted object keys, numbers being kept as they are written.
+func canonicalJSON(data json.RawMessage) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// referenceRank orders the deprecation entries of a package: the package first, then its channels and bundles.
+func referenceRank(schema string) int {
+	switch schema {
+	case declcfg.SchemaPackage:
+		return 0
+	case declcfg.SchemaChannel:
+		return 1
+	case declcfg.SchemaBundle:
+		return 2
+	}
+	return 3
+}
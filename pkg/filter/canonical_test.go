@@ -0,0 +1,184 @@
+package filter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+// shuffledCatalog returns a catalog whose objects are out of order. When reversed is set, the same objects are
+// listed in reverse order.
+func shuffledCatalog(reversed bool) *declcfg.DeclarativeConfig {
+	fbc := &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "stable", Properties: []property.Property{
+				{Type: "owner", Value: json.RawMessage(`{"team": "b", "name": "x"}`)},
+				{Type: "owner", Value: json.RawMessage(`{"name": "a"}`)},
+			}},
+			{Schema: declcfg.SchemaPackage, Name: "bar", DefaultChannel: "stable"},
+		},
+		Channels: []declcfg.Channel{
+			{Schema: declcfg.SchemaChannel, Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1"},
+				{Name: "foo.v3", Replaces: "foo.v2", Skips: []string{"foo.v1", "foo.v0"}},
+				{Name: "foo.v2", Replaces: "foo.v1"},
+			}},
+			{Schema: declcfg.SchemaChannel, Name: "fast", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v3"}}},
+			{Schema: declcfg.SchemaChannel, Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{{Name: "bar.v1"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			{Schema: declcfg.SchemaBundle, Name: "foo.v2", Package: "foo", Image: "quay.io/foo/bundle:v2", Properties: []property.Property{
+				property.MustBuildPackage("foo", "2.0.0"),
+				{Type: "olm.gvk", Value: json.RawMessage(`{"version":"v1","kind":"Foo","group":"foo.io"}`)},
+			}, RelatedImages: []declcfg.RelatedImage{
+				{Name: "operator", Image: "quay.io/foo/operator:v2"},
+				{Name: "", Image: "quay.io/foo/bundle:v2"},
+			}},
+			{Schema: declcfg.SchemaBundle, Name: "foo.v1", Package: "foo", Image: "quay.io/foo/bundle:v1"},
+			{Schema: declcfg.SchemaBundle, Name: "foo.v3", Package: "foo", Image: "quay.io/foo/bundle:v3"},
+			{Schema: declcfg.SchemaBundle, Name: "bar.v1", Package: "bar", Image: "quay.io/bar/bundle:v1"},
+		},
+		Deprecations: []declcfg.Deprecation{
+			{Schema: declcfg.SchemaDeprecation, Package: "foo", Entries: []declcfg.DeprecationEntry{
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v1"}, Message: "foo.v1 is deprecated"},
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaChannel, Name: "fast"}, Message: "fast is deprecated"},
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaPackage}, Message: "foo is deprecated"},
+			}},
+			{Schema: declcfg.SchemaDeprecation, Package: "bar", Entries: []declcfg.DeprecationEntry{
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaPackage}, Message: "bar is deprecated"},
+			}},
+		},
+		Others: []declcfg.Meta{
+			{Schema: "custom", Package: "foo", Name: "b", Blob: json.RawMessage(`{"schema": "custom", "package": "foo", "name": "b"}`)},
+			{Schema: "custom", Package: "foo", Name: "a", Blob: json.RawMessage(`{"name":"a","package":"foo","schema":"custom"}`)},
+		},
+	}
+	if reversed {
+		slices.Reverse(fbc.Packages)
+		slices.Reverse(fbc.Channels)
+		slices.Reverse(fbc.Bundles)
+		slices.Reverse(fbc.Deprecations)
+		slices.Reverse(fbc.Others)
+		slices.Reverse(fbc.Packages[1].Properties)
+		slices.Reverse(fbc.Channels[2].Entries)
+		slices.Reverse(fbc.Bundles[3].Properties)
+		slices.Reverse(fbc.Bundles[3].RelatedImages)
+		slices.Reverse(fbc.Deprecations[1].Entries)
+	}
+	return fbc
+}
+
+func TestCanonicalize(t *testing.T) {
+	out, err := Canonicalize(shuffledCatalog(false))
+	require.NoError(t, err)
+
+	var packages []string
+	for _, p := range out.Packages {
+		packages = append(packages, p.Name)
+	}
+	assert.Equal(t, []string{"bar", "foo"}, packages)
+	assert.Equal(t, []property.Property{
+		{Type: "owner", Value: json.RawMessage(`{"name":"a"}`)},
+		{Type: "owner", Value: json.RawMessage(`{"name":"x","team":"b"}`)},
+	}, out.Packages[1].Properties)
+
+	var channels []string
+	for _, ch := range out.Channels {
+		channels = append(channels, ch.Package+"/"+ch.Name)
+	}
+	assert.Equal(t, []string{"bar/stable", "foo/fast", "foo/stable"}, channels)
+	assert.Equal(t, []declcfg.ChannelEntry{
+		{Name: "foo.v3", Replaces: "foo.v2", Skips: []string{"foo.v0", "foo.v1"}},
+		{Name: "foo.v2", Replaces: "foo.v1"},
+		{Name: "foo.v1"},
+	}, out.Channels[2].Entries)
+
+	var bundles []string
+	for _, b := range out.Bundles {
+		bundles = append(bundles, b.Name)
+	}
+	assert.Equal(t, []string{"bar.v1", "foo.v1", "foo.v2", "foo.v3"}, bundles)
+	assert.Equal(t, []property.Property{
+		{Type: "olm.gvk", Value: json.RawMessage(`{"group":"foo.io","kind":"Foo","version":"v1"}`)},
+		{Type: property.TypePackage, Value: json.RawMessage(`{"packageName":"foo","version":"2.0.0"}`)},
+	}, out.Bundles[2].Properties)
+	assert.Equal(t, []declcfg.RelatedImage{
+		{Name: "", Image: "quay.io/foo/bundle:v2"},
+		{Name: "operator", Image: "quay.io/foo/operator:v2"},
+	}, out.Bundles[2].RelatedImages)
+
+	require.Len(t, out.Deprecations, 2)
+	assert.Equal(t, "bar", out.Deprecations[0].Package)
+	var deprecated []string
+	for _, e := range out.Deprecations[1].Entries {
+		deprecated = append(deprecated, e.Reference.Schema+"/"+e.Reference.Name)
+	}
+	assert.Equal(t, []string{"olm.package/", "olm.channel/fast", "olm.bundle/foo.v1"}, deprecated)
+
+	require.Len(t, out.Others, 2)
+	assert.Equal(t, "a", out.Others[0].Name)
+	assert.Equal(t, `{"name":"b","package":"foo","schema":"custom"}`, string(out.Others[1].Blob))
+}
+
+func TestCanonicalize_ByteIdentical(t *testing.T) {
+	write := func(fbc *declcfg.DeclarativeConfig) string {
+		out, err := Canonical().FilterCatalog(context.Background(), fbc)
+		require.NoError(t, err)
+		buf := &bytes.Buffer{}
+		require.NoError(t, declcfg.WriteJSON(*out, buf))
+		return buf.String()
+	}
+	assert.Equal(t, write(shuffledCatalog(false)), write(shuffledCatalog(true)))
+}
+
+func TestCanonicalize_DoesNotModifyInput(t *testing.T) {
+	fbc := shuffledCatalog(false)
+	_, err := Canonicalize(fbc)
+	require.NoError(t, err)
+	assert.Equal(t, shuffledCatalog(false), fbc)
+}
+
+func TestCanonicalize_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		fbc     *declcfg.DeclarativeConfig
+		wantErr string
+	}{
+		{
+			name: "WHEN a channel has a cycle THEN an error is returned",
+			fbc: &declcfg.DeclarativeConfig{Channels: []declcfg.Channel{{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1", Replaces: "foo.v2"},
+				{Name: "foo.v2", Replaces: "foo.v1"},
+			}}}},
+			wantErr: `package "foo", channel "stable": `,
+		},
+		{
+			name: "WHEN a property value is not valid JSON THEN an error is returned",
+			fbc: &declcfg.DeclarativeConfig{Bundles: []declcfg.Bundle{{Name: "foo.v1", Package: "foo", Properties: []property.Property{
+				{Type: "custom", Value: json.RawMessage(`{`)},
+			}}}},
+			wantErr: `package "foo", bundle "foo.v1": property "custom" at index [0]: `,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Canonicalize(tt.fbc)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestCanonicalize_Nil(t *testing.T) {
+	out, err := Canonicalize(nil)
+	require.NoError(t, err)
+	assert.Nil(t, out)
+}
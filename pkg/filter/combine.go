@@ -0,0 +1,482 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
+)
+
+// Chain returns a CatalogFilter that runs filters in order, each one on the output of the previous one.
+// Its KeepMeta keeps the meta objects that all the filters implementing MetaFilter keep.
+func Chain(filters ...CatalogFilter) CatalogFilter {
+	return &chainFilter{filters: filters}
+}
+
+// Union returns a CatalogFilter that runs each of filters on its own copy of the catalog, and keeps what
+// at least one of them keeps. The entries of a channel kept by several filters are merged: an entry upgrades
+// from all the entries it upgrades from in any of the outputs, a replaces that differs from the first one being
+// turned into a skips. The merged channels must have no cycle. A merged channel that has more heads than the
+// channels it merges, such as when a filter keeps the channel head and another one older entries, is rebuilt
+// from the channel of the catalog: the entries of its replaces chain that connect the kept entries are kept as
+// well, the way graph.Channel.FilterEntries keeps them, along with their bundles. The first filter that keeps
+// a package, a bundle or a meta object decides its content, such as the default channel of a package. Its KeepMeta keeps the meta objects that one of the filters keeps, a filter that does
+// not implement MetaFilter keeping all of them.
+func Union(filters ...CatalogFilter) CatalogFilter {
+	return &unionFilter{filters: filters}
+}
+
+// Intersect returns a CatalogFilter that runs each of filters on its own copy of the catalog, and keeps what
+// all of them keep. Channels are merged the way Union merges them, then the entries that some of the filters
+// dropped are removed, the entries that upgraded from them upgrading from their predecessors instead. A channel
+// left with more heads is rebuilt from the channel of the catalog like Union rebuilds it. Channels left
+// without entries are removed, along with the bundles left out of all channels. A package that all the
+// filters keep but that is left without channels fails the intersection. When the default channel of a
+// package is removed, the default channel chosen by another filter is used if it remains. Its KeepMeta keeps the meta objects that all the filters implementing
+// MetaFilter keep.
+func Intersect(filters ...CatalogFilter) CatalogFilter {
+	return &intersectFilter{filters: filters}
+}
+
+type chainFilter struct {
+	filters []CatalogFilter
+}
+
+func (f *chainFilter) FilterCatalog(ctx context.Context, fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	for i, cf := range f.filters {
+		if fbc == nil {
+			return nil, nil
+		}
+		var err error
+		if fbc, err = cf.FilterCatalog(ctx, fbc); err != nil {
+			return nil, fmt.Errorf("filter at index [%d]: %w", i, err)
+		}
+	}
+	return fbc, nil
+}
+
+func (f *chainFilter) KeepMeta(meta *declcfg.Meta) bool {
+	return allKeep(f.filters, meta)
+}
+
+type unionFilter struct {
+	filters []CatalogFilter
+}
+
+func (f *unionFilter) FilterCatalog(ctx context.Context, fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	if fbc == nil {
+		return nil, nil
+	}
+	outputs, err := filterCopies(ctx, f.filters, fbc)
+	if err != nil {
+		return nil, err
+	}
+	return union(outputs, fbc)
+}
+
+func (f *unionFilter) KeepMeta(meta *declcfg.Meta) bool {
+	return slices.ContainsFunc(f.filters, func(cf CatalogFilter) bool {
+		mf, ok := cf.(MetaFilter)
+		return !ok || mf.KeepMeta(meta)
+	})
+}
+
+type intersectFilter struct {
+	filters []CatalogFilter
+}
+
+func (f *intersectFilter) FilterCatalog(ctx context.Context, fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	if fbc == nil {
+		return nil, nil
+	}
+	if len(f.filters) == 0 {
+		return fbc, nil
+	}
+	outputs, err := filterCopies(ctx, f.filters, fbc)
+	if err != nil {
+		return nil, err
+	}
+	return intersect(outputs, fbc)
+}
+
+func (f *intersectFilter) KeepMeta(meta *declcfg.Meta) bool {
+	return allKeep(f.filters, meta)
+}
+
+func allKeep(filters []CatalogFilter, meta *declcfg.Meta) bool {
+	for _, cf := range filters {
+		if mf, ok := cf.(MetaFilter); ok && !mf.KeepMeta(meta) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterCopies runs each of filters on a copy of fbc, as filters are free to modify the catalog they filter.
+func filterCopies(ctx context.Context, filters []CatalogFilter, fbc *declcfg.DeclarativeConfig) ([]*declcfg.DeclarativeConfig, error) {
+	outputs := make([]*declcfg.DeclarativeConfig, 0, len(filters))
+	for i, cf := range filters {
+		out, err := cf.FilterCatalog(ctx, cloneCatalog(fbc))
+		if err != nil {
+			return nil, fmt.Errorf("filter at index [%d]: %w", i, err)
+		}
+		if out == nil {
+			out = &declcfg.DeclarativeConfig{}
+		}
+		outputs = append(outputs, out)
+	}
+	return outputs, nil
+}
+
+func cloneCatalog(fbc *declcfg.DeclarativeConfig) *declcfg.DeclarativeConfig {
+	out := &declcfg.DeclarativeConfig{
+		Packages:     slices.Clone(fbc.Packages),
+		Channels:     slices.Clone(fbc.Channels),
+		Bundles:      slices.Clone(fbc.Bundles),
+		Deprecations: slices.Clone(fbc.Deprecations),
+		Others:       slices.Clone(fbc.Others),
+	}
+	for i := range out.Packages {
+		out.Packages[i].Properties = slices.Clone(out.Packages[i].Properties)
+	}
+	for i := range out.Channels {
+		out.Channels[i].Entries = slices.Clone(out.Channels[i].Entries)
+		for j := range out.Channels[i].Entries {
+			out.Channels[i].Entries[j].Skips = slices.Clone(out.Channels[i].Entries[j].Skips)
+		}
+	}
+	for i := range out.Bundles {
+		out.Bundles[i].Properties = slices.Clone(out.Bundles[i].Properties)
+		out.Bundles[i].RelatedImages = slices.Clone(out.Bundles[i].RelatedImages)
+	}
+	for i := range out.Deprecations {
+		out.Deprecations[i].Entries = slices.Clone(out.Deprecations[i].Entries)
+	}
+	return out
+}
+
+type objectKey struct {
+	schema, pkg, name string
+}
+
+// merged collects the objects of several catalogs by key, in the order they are first found.
+type merged[T any] struct {
+	keys    []objectKey
+	objects map[objectKey][]T
+}
+
+func collect[T any](outputs []*declcfg.DeclarativeConfig, objects func(*declcfg.DeclarativeConfig) []T, key func(T) objectKey) merged[T] {
+	m := merged[T]{objects: map[objectKey][]T{}}
+	for _, out := range outputs {
+		for _, o := range objects(out) {
+			k := key(o)
+			if _, ok := m.objects[k]; !ok {
+				m.keys = append(m.keys, k)
+			}
+			m.objects[k] = append(m.objects[k], o)
+		}
+	}
+	return m
+}
+
+func collectCatalogs(outputs []*declcfg.DeclarativeConfig) (merged[declcfg.Package], merged[declcfg.Channel], merged[declcfg.Bundle], merged[declcfg.Deprecation], merged[declcfg.Meta]) {
+	return collect(outputs, func(c *declcfg.DeclarativeConfig) []declcfg.Package { return c.Packages },
+			func(p declcfg.Package) objectKey { return objectKey{pkg: p.Name} }),
+		collect(outputs, func(c *declcfg.DeclarativeConfig) []declcfg.Channel { return c.Channels },
+			func(ch declcfg.Channel) objectKey { return objectKey{pkg: ch.Package, name: ch.Name} }),
+		collect(outputs, func(c *declcfg.DeclarativeConfig) []declcfg.Bundle { return c.Bundles },
+			func(b declcfg.Bundle) objectKey { return objectKey{pkg: b.Package, name: b.Name} }),
+		collect(outputs, func(c *declcfg.DeclarativeConfig) []declcfg.Deprecation { return c.Deprecations },
+			func(d declcfg.Deprecation) objectKey { return objectKey{pkg: d.Package} }),
+		collect(outputs, func(c *declcfg.DeclarativeConfig) []declcfg.Meta { return c.Others },
+			func(m declcfg.Meta) objectKey { return objectKey{schema: m.Schema, pkg: m.Package, name: m.Name} })
+}
+
+func union(outputs []*declcfg.DeclarativeConfig, in *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	packages, channels, bundles, deprecations, others := collectCatalogs(outputs)
+	input := indexInput(in)
+	out := &declcfg.DeclarativeConfig{}
+	for _, k := range packages.keys {
+		out.Packages = append(out.Packages, packages.objects[k][0])
+	}
+	var linked []objectKey
+	for _, k := range channels.keys {
+		ch, added, err := linkMergedChannel(mergeChannels(channels.objects[k]), channels.objects[k], input.channels[k])
+		if err != nil {
+			return nil, err
+		}
+		out.Channels = append(out.Channels, ch)
+		for _, name := range sets.List(added) {
+			if key := (objectKey{pkg: k.pkg, name: name}); !slices.Contains(linked, key) {
+				linked = append(linked, key)
+			}
+		}
+	}
+	for _, k := range bundles.keys {
+		out.Bundles = append(out.Bundles, bundles.objects[k][0])
+	}
+	// the bundles of the entries added back to channels come from the catalog, unless a filter kept them
+	for _, k := range linked {
+		if _, ok := bundles.objects[k]; !ok {
+			out.Bundles = append(out.Bundles, input.bundles[k]...)
+		}
+	}
+	for _, k := range deprecations.keys {
+		d := deprecations.objects[k][0]
+		d.Entries = nil
+		seen := sets.New[declcfg.PackageScopedReference]()
+		for _, dep := range deprecations.objects[k] {
+			for _, e := range dep.Entries {
+				if !seen.Has(e.Reference) {
+					seen.Insert(e.Reference)
+					d.Entries = append(d.Entries, e)
+				}
+			}
+		}
+		out.Deprecations = append(out.Deprecations, d)
+	}
+	for _, k := range others.keys {
+		out.Others = append(out.Others, others.objects[k][0])
+	}
+	return out, nil
+}
+
+func intersect(outputs []*declcfg.DeclarativeConfig, in *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	packages, channels, bundles, deprecations, others := collectCatalogs(outputs)
+	input := indexInput(in)
+	inAll := func(n int) bool { return n == len(outputs) }
+
+	out := &declcfg.DeclarativeConfig{}
+	keptChannels := map[string]sets.Set[string]{}
+	keptEntries := map[string]sets.Set[string]{}
+	// the entries that connect the common ones, which only some of the filters keep
+	linkedEntries := map[string]sets.Set[string]{}
+	for _, k := range channels.keys {
+		group := channels.objects[k]
+		if !inAll(len(group)) || !inAll(len(packages.objects[objectKey{pkg: k.pkg}])) {
+			continue
+		}
+		common := sets.New[string]()
+		for _, e := range group[0].Entries {
+			common.Insert(e.Name)
+		}
+		for _, ch := range group[1:] {
+			names := sets.New[string]()
+			for _, e := range ch.Entries {
+				names.Insert(e.Name)
+			}
+			common = common.Intersection(names)
+		}
+		if common.Len() == 0 {
+			continue
+		}
+		ch := mergeChannels(group)
+		dropped := sets.New[string]()
+		for _, e := range ch.Entries {
+			if !common.Has(e.Name) {
+				dropped.Insert(e.Name)
+			}
+		}
+		ch.Entries = graph.RemoveEntries(ch.Entries, dropped)
+		ch, added, err := linkMergedChannel(ch, group, input.channels[k])
+		if err != nil {
+			return nil, err
+		}
+		out.Channels = append(out.Channels, ch)
+		if _, ok := keptChannels[ch.Package]; !ok {
+			keptChannels[ch.Package] = sets.New[string]()
+			keptEntries[ch.Package] = sets.New[string]()
+			linkedEntries[ch.Package] = sets.New[string]()
+		}
+		keptChannels[ch.Package].Insert(ch.Name)
+		keptEntries[ch.Package] = keptEntries[ch.Package].Union(common)
+		linkedEntries[ch.Package] = linkedEntries[ch.Package].Union(added)
+	}
+
+	for _, k := range packages.keys {
+		if _, ok := keptChannels[k.pkg]; !ok {
+			if inAll(len(packages.objects[k])) {
+				return nil, fmt.Errorf("package %q has no bundles left after intersecting, the filters keep different bundles", k.pkg)
+			}
+			continue
+		}
+		pkg := packages.objects[k][0]
+		if !keptChannels[k.pkg].Has(pkg.DefaultChannel) {
+			i := slices.IndexFunc(packages.objects[k], func(p declcfg.Package) bool { return keptChannels[k.pkg].Has(p.DefaultChannel) })
+			if i < 0 {
+				return nil, fmt.Errorf("package %q has no default channel left after intersecting, the filters keep different channels", pkg.Name)
+			}
+			pkg.DefaultChannel = packages.objects[k][i].DefaultChannel
+		}
+		out.Packages = append(out.Packages, pkg)
+	}
+	for _, k := range bundles.keys {
+		if inAll(len(bundles.objects[k])) && keptEntries[k.pkg].Has(k.name) {
+			out.Bundles = append(out.Bundles, bundles.objects[k][0])
+		}
+	}
+	for _, pkg := range sets.List(sets.KeySet(linkedEntries)) {
+		for _, name := range sets.List(linkedEntries[pkg].Difference(keptEntries[pkg])) {
+			out.Bundles = append(out.Bundles, input.bundles[objectKey{pkg: pkg, name: name}]...)
+		}
+		keptEntries[pkg] = keptEntries[pkg].Union(linkedEntries[pkg])
+	}
+	for _, k := range deprecations.keys {
+		if !inAll(len(deprecations.objects[k])) || keptChannels[k.pkg] == nil {
+			continue
+		}
+		counts := map[declcfg.PackageScopedReference]int{}
+		for _, dep := range deprecations.objects[k] {
+			for _, e := range dep.Entries {
+				counts[e.Reference]++
+			}
+		}
+		d := deprecations.objects[k][0]
+		d.Entries = slices.DeleteFunc(slices.Clone(d.Entries), func(e declcfg.DeprecationEntry) bool {
+			switch e.Reference.Schema {
+			case declcfg.SchemaChannel:
+				return !inAll(counts[e.Reference]) || !keptChannels[k.pkg].Has(e.Reference.Name)
+			case declcfg.SchemaBundle:
+				return !inAll(counts[e.Reference]) || !keptEntries[k.pkg].Has(e.Reference.Name)
+			}
+			return !inAll(counts[e.Reference])
+		})
+		if len(d.Entries) > 0 {
+			out.Deprecations = append(out.Deprecations, d)
+		}
+	}
+	for _, k := range others.keys {
+		if inAll(len(others.objects[k])) && (k.pkg == "" || keptChannels[k.pkg] != nil) {
+			out.Others = append(out.Others, others.objects[k][0])
+		}
+	}
+	return out, nil
+}
+
+// mergeChannels merges the entries of channels, which are versions of the same channel. Each entry upgrades
+// from all the entries it upgrades from in any of channels: it keeps the first replaces found, and skips
+// the entries that other channels replace or skip.
+func mergeChannels(channels []declcfg.Channel) declcfg.Channel {
+	ch := channels[0]
+	ch.Entries = nil
+	index := map[string]int{}
+	for _, c := range channels {
+		for _, e := range c.Entries {
+			i, ok := index[e.Name]
+			if !ok {
+				index[e.Name] = len(ch.Entries)
+				ch.Entries = append(ch.Entries, declcfg.ChannelEntry{Name: e.Name, Replaces: e.Replaces, Skips: slices.Clone(e.Skips)})
+				continue
+			}
+			merged := &ch.Entries[i]
+			if merged.Replaces == "" && e.Replaces != "" {
+				merged.Replaces = e.Replaces
+				merged.Skips = slices.DeleteFunc(merged.Skips, func(s string) bool { return s == e.Replaces })
+			}
+			upgradesFrom := e.Skips
+			if e.Replaces != "" {
+				upgradesFrom = append([]string{e.Replaces}, e.Skips...)
+			}
+			for _, from := range upgradesFrom {
+				if from != merged.Replaces && !slices.Contains(merged.Skips, from) {
+					merged.Skips = append(merged.Skips, from)
+				}
+			}
+		}
+	}
+	return ch
+}
+
+// inputIndex holds the channels and bundles of the catalog that the filters filtered.
+type inputIndex struct {
+	channels map[objectKey]*declcfg.Channel
+	bundles  map[objectKey][]declcfg.Bundle
+}
+
+func indexInput(fbc *declcfg.DeclarativeConfig) inputIndex {
+	index := inputIndex{channels: map[objectKey]*declcfg.Channel{}, bundles: map[objectKey][]declcfg.Bundle{}}
+	for i, ch := range fbc.Channels {
+		index.channels[objectKey{pkg: ch.Package, name: ch.Name}] = &fbc.Channels[i]
+	}
+	for _, b := range fbc.Bundles {
+		k := objectKey{pkg: b.Package, name: b.Name}
+		index.bundles[k] = append(index.bundles[k], b)
+	}
+	return index
+}
+
+// linkMergedChannel checks that ch, merged from channels, has no cycle and no more heads than the channels it
+// was merged from. When it has more heads, it is rebuilt from input, the channel of the catalog the channels
+// were filtered from, with relinkChannel. It returns the channel and the names of the entries added back.
+func linkMergedChannel(ch declcfg.Channel, channels []declcfg.Channel, input *declcfg.Channel) (declcfg.Channel, sets.Set[string], error) {
+	heads, err := extraHeads(ch, channels)
+	if err != nil || len(heads) == 0 {
+		return ch, nil, err
+	}
+	if input != nil {
+		if linked, added, ok := relinkChannel(ch, *input); ok {
+			if linkedHeads, err := extraHeads(linked, append(slices.Clone(channels), *input)); err == nil && len(linkedHeads) == 0 {
+				return linked, added, nil
+			}
+		}
+	}
+	return ch, nil, fmt.Errorf("package %q channel %q is invalid after merging: multiple channel heads found: %v", ch.Package, ch.Name, heads)
+}
+
+// relinkChannel returns ch with the entries of input: the entries of ch are kept along with the entries of the
+// replaces chains of input that connect them, the way graph.Channel.FilterEntries keeps them, and the other
+// entries of input are removed, the way graph.RemoveEntries removes them. It also returns the names of the
+// entries that connect the entries of ch, and false when ch has entries that are not entries of input.
+func relinkChannel(ch, input declcfg.Channel) (declcfg.Channel, sets.Set[string], bool) {
+	heads, err := graph.NewChannelHeads(input)
+	if err != nil {
+		return ch, nil, false
+	}
+	kept := sets.New[string]()
+	for _, e := range ch.Entries {
+		if !heads[0].Graph.Has(e.Name) {
+			return ch, nil, false
+		}
+		kept.Insert(e.Name)
+	}
+	linked := kept.Clone()
+	for _, c := range heads {
+		linked = linked.Union(c.FilterEntries(func(n *graph.Node) bool { return kept.Has(n.Name) }, func(*graph.Node) {}))
+	}
+	removed := sets.New[string]()
+	for _, e := range input.Entries {
+		if !linked.Has(e.Name) {
+			removed.Insert(e.Name)
+		}
+	}
+	ch.Entries = graph.RemoveEntries(input.Entries, removed)
+	return ch, linked.Difference(kept), true
+}
+
+// extraHeads returns the names of the heads of ch, merged from channels, when it has more heads than the
+// channels it was merged from. It fails when ch is not a valid upgrade graph, such as when it has a cycle.
+func extraHeads(ch declcfg.Channel, channels []declcfg.Channel) ([]string, error) {
+	g, err := graph.New(ch, graph.WithMultipleHeads(true))
+	if err != nil {
+		return nil, fmt.Errorf("package %q channel %q is invalid after merging: %v", ch.Package, ch.Name, err)
+	}
+	maxHeads := 1
+	for _, c := range channels {
+		if cg, err := graph.New(c, graph.WithMultipleHeads(true)); err == nil {
+			maxHeads = max(maxHeads, len(cg.Heads()))
+		}
+	}
+	heads := g.Heads()
+	if len(heads) <= maxHeads {
+		return nil, nil
+	}
+	names := make([]string, 0, len(heads))
+	for _, h := range heads {
+		names = append(names, h.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,388 @@
+package filter
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+type catalogFilterFunc func(fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error)
+
+func (f catalogFilterFunc) FilterCatalog(_ context.Context, fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	return f(fbc)
+}
+
+// keepChannel returns a filter that keeps the channel of pkg with the given entries, and their bundles.
+func keepChannel(pkg, channel string, entries ...declcfg.ChannelEntry) CatalogFilter {
+	return catalogFilterFunc(func(fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+		out := &declcfg.DeclarativeConfig{
+			Packages: []declcfg.Package{{Name: pkg, DefaultChannel: channel}},
+			Channels: []declcfg.Channel{{Name: channel, Package: pkg, Entries: entries}},
+		}
+		for _, b := range fbc.Bundles {
+			if b.Package == pkg && slices.ContainsFunc(entries, func(e declcfg.ChannelEntry) bool { return e.Name == b.Name }) {
+				out.Bundles = append(out.Bundles, b)
+			}
+		}
+		for _, d := range fbc.Deprecations {
+			if d.Package == pkg {
+				out.Deprecations = append(out.Deprecations, d)
+			}
+		}
+		return out, nil
+	})
+}
+
+func combineTestCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Name: "foo", DefaultChannel: "stable"},
+			{Name: "bar", DefaultChannel: "stable"},
+		},
+		Channels: []declcfg.Channel{
+			{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v3", Replaces: "foo.v2"},
+				{Name: "foo.v2", Replaces: "foo.v1"},
+				{Name: "foo.v1"},
+			}},
+			{Name: "fast", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v3"}}},
+			{Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{{Name: "bar.v1"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			{Name: "foo.v1", Package: "foo"},
+			{Name: "foo.v2", Package: "foo"},
+			{Name: "foo.v3", Package: "foo"},
+			{Name: "bar.v1", Package: "bar"},
+		},
+		Deprecations: []declcfg.Deprecation{
+			{Package: "foo", Entries: []declcfg.DeprecationEntry{
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v1"}},
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaChannel, Name: "fast"}},
+			}},
+		},
+		Others: []declcfg.Meta{
+			{Schema: "other", Package: "foo"},
+			{Schema: "other", Package: "bar"},
+		},
+	}
+}
+
+func TestChain(t *testing.T) {
+	t.Run("WHEN chaining filters THEN Each one filters the output of the previous one", func(t *testing.T) {
+		actual, err := Chain(NewPackageFilter("foo", "bar"), NewPackageFilter("foo", "baz")).FilterCatalog(context.Background(), combineTestCatalog())
+		require.NoError(t, err)
+		expected := combineTestCatalog()
+		_, err = NewPackageFilter("foo").FilterCatalog(context.Background(), expected)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+	t.Run("WHEN a filter fails THEN Returns its error", func(t *testing.T) {
+		failing := catalogFilterFunc(func(*declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+			return nil, assert.AnError
+		})
+		_, err := Chain(NewPackageFilter("foo"), failing).FilterCatalog(context.Background(), combineTestCatalog())
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.ErrorContains(t, err, "filter at index [1]: ")
+	})
+	t.Run("WHEN no filter THEN Returns the catalog", func(t *testing.T) {
+		actual, err := Chain().FilterCatalog(context.Background(), combineTestCatalog())
+		require.NoError(t, err)
+		assert.Equal(t, combineTestCatalog(), actual)
+	})
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      CatalogFilter
+		expected    *declcfg.DeclarativeConfig
+		expectedErr string
+	}{
+		{
+			name:   "WHEN filters keep different packages THEN Keeps both packages",
+			filter: Union(NewPackageFilter("bar"), NewPackageFilter("foo")),
+			expected: func() *declcfg.DeclarativeConfig {
+				fbc := combineTestCatalog()
+				return &declcfg.DeclarativeConfig{
+					Packages:     []declcfg.Package{fbc.Packages[1], fbc.Packages[0]},
+					Channels:     []declcfg.Channel{fbc.Channels[2], fbc.Channels[0], fbc.Channels[1]},
+					Bundles:      []declcfg.Bundle{fbc.Bundles[3], fbc.Bundles[0], fbc.Bundles[1], fbc.Bundles[2]},
+					Deprecations: fbc.Deprecations,
+					Others:       []declcfg.Meta{fbc.Others[1], fbc.Others[0]},
+				}
+			}(),
+		},
+		{
+			name: "WHEN filters keep different entries of a channel THEN Merges the entries and their upgrade edges",
+			filter: Union(
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v3", Replaces: "foo.v2"}, declcfg.ChannelEntry{Name: "foo.v2"}),
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v3", Skips: []string{"foo.v1"}}, declcfg.ChannelEntry{Name: "foo.v1"}),
+			),
+			expected: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+				Channels: []declcfg.Channel{{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+					{Name: "foo.v3", Replaces: "foo.v2", Skips: []string{"foo.v1"}},
+					{Name: "foo.v2"},
+					{Name: "foo.v1"},
+				}}},
+				Bundles: []declcfg.Bundle{
+					{Name: "foo.v2", Package: "foo"},
+					{Name: "foo.v3", Package: "foo"},
+					{Name: "foo.v1", Package: "foo"},
+				},
+				Deprecations: combineTestCatalog().Deprecations,
+			},
+		},
+		{
+			name: "WHEN entries replace different entries THEN The other replaces become skips",
+			filter: Union(
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v3", Replaces: "foo.v2"}, declcfg.ChannelEntry{Name: "foo.v2"}),
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v3", Replaces: "foo.v1"}, declcfg.ChannelEntry{Name: "foo.v1"}),
+			),
+			expected: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+				Channels: []declcfg.Channel{{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+					{Name: "foo.v3", Replaces: "foo.v2", Skips: []string{"foo.v1"}},
+					{Name: "foo.v2"},
+					{Name: "foo.v1"},
+				}}},
+				Bundles: []declcfg.Bundle{
+					{Name: "foo.v2", Package: "foo"},
+					{Name: "foo.v3", Package: "foo"},
+					{Name: "foo.v1", Package: "foo"},
+				},
+				Deprecations: combineTestCatalog().Deprecations,
+			},
+		},
+		{
+			name: "WHEN merged channel has more heads THEN Rebuilds it from the channel of the catalog",
+			filter: Union(
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v3"}),
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v2", Replaces: "foo.v1"}, declcfg.ChannelEntry{Name: "foo.v1"}),
+			),
+			expected: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+				Channels: []declcfg.Channel{combineTestCatalog().Channels[0]},
+				Bundles: []declcfg.Bundle{
+					{Name: "foo.v3", Package: "foo"},
+					{Name: "foo.v1", Package: "foo"},
+					{Name: "foo.v2", Package: "foo"},
+				},
+				Deprecations: combineTestCatalog().Deprecations,
+			},
+		},
+		{
+			name: "WHEN merged channel has more heads THEN Keeps the entries connecting the kept ones and their bundles",
+			filter: Union(
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v3", Replaces: "foo.v2"}),
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v1"}),
+			),
+			expected: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+				Channels: []declcfg.Channel{combineTestCatalog().Channels[0]},
+				Bundles: []declcfg.Bundle{
+					{Name: "foo.v3", Package: "foo"},
+					{Name: "foo.v1", Package: "foo"},
+					{Name: "foo.v2", Package: "foo"},
+				},
+				Deprecations: combineTestCatalog().Deprecations,
+			},
+		},
+		{
+			name: "WHEN merged channel has more heads and entries missing from the catalog THEN Returns error",
+			filter: Union(
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v4"}),
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v2", Replaces: "foo.v1"}, declcfg.ChannelEntry{Name: "foo.v1"}),
+			),
+			expectedErr: `package "foo" channel "stable" is invalid after merging: multiple channel heads found: [foo.v2 foo.v4]`,
+		},
+		{
+			name: "WHEN merged channel has a cycle THEN Returns error",
+			filter: Union(
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v3", Replaces: "foo.v2"}, declcfg.ChannelEntry{Name: "foo.v2"}),
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v2", Replaces: "foo.v3"}, declcfg.ChannelEntry{Name: "foo.v3"}),
+			),
+			expectedErr: `package "foo" channel "stable" is invalid after merging: `,
+		},
+		{
+			name:     "WHEN no filter THEN Keeps nothing",
+			filter:   Union(),
+			expected: &declcfg.DeclarativeConfig{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := combineTestCatalog()
+			actual, err := tt.filter.FilterCatalog(context.Background(), fbc)
+			assert.Equal(t, combineTestCatalog(), fbc, "the filters must run on copies of the catalog")
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      CatalogFilter
+		expected    *declcfg.DeclarativeConfig
+		expectedErr string
+	}{
+		{
+			name:   "WHEN filters keep different packages THEN Keeps the common packages",
+			filter: Intersect(NewPackageFilter("foo", "bar"), NewPackageFilter("foo", "baz")),
+			expected: func() *declcfg.DeclarativeConfig {
+				fbc := combineTestCatalog()
+				return &declcfg.DeclarativeConfig{
+					Packages:     fbc.Packages[:1],
+					Channels:     fbc.Channels[:2],
+					Bundles:      fbc.Bundles[:3],
+					Deprecations: fbc.Deprecations,
+					Others:       fbc.Others[:1],
+				}
+			}(),
+		},
+		{
+			name: "WHEN filters keep different entries of a channel THEN Keeps the common entries and repairs their upgrade edges",
+			filter: Intersect(
+				NewPackageFilter("foo"),
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v3", Skips: []string{"foo.v1"}}, declcfg.ChannelEntry{Name: "foo.v1"}),
+			),
+			expected: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+				Channels: []declcfg.Channel{{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+					{Name: "foo.v3", Replaces: "foo.v1", Skips: []string{"foo.v1"}},
+					{Name: "foo.v1"},
+				}}},
+				Bundles: []declcfg.Bundle{
+					{Name: "foo.v1", Package: "foo"},
+					{Name: "foo.v3", Package: "foo"},
+				},
+				Deprecations: []declcfg.Deprecation{{Package: "foo", Entries: []declcfg.DeprecationEntry{
+					{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v1"}},
+				}}},
+			},
+		},
+		{
+			name: "WHEN the default channel is removed THEN Uses the default channel of another filter",
+			filter: Intersect(
+				NewPackageFilter("foo"),
+				keepChannel("foo", "fast", declcfg.ChannelEntry{Name: "foo.v3"}),
+			),
+			expected: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "fast"}},
+				Channels: []declcfg.Channel{{Name: "fast", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v3"}}}},
+				Bundles:  []declcfg.Bundle{{Name: "foo.v3", Package: "foo"}},
+				Deprecations: []declcfg.Deprecation{{Package: "foo", Entries: []declcfg.DeprecationEntry{
+					{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaChannel, Name: "fast"}},
+				}}},
+			},
+		},
+		{
+			name: "WHEN no default channel remains THEN Returns error",
+			filter: Intersect(
+				Chain(NewPackageFilter("foo"), catalogFilterFunc(func(fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+					fbc.Packages[0].DefaultChannel = "candidate"
+					return fbc, nil
+				})),
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v3"}),
+				keepChannel("foo", "fast", declcfg.ChannelEntry{Name: "foo.v3"}),
+				NewPackageFilter("foo"),
+			),
+			expectedErr: `package "foo" has no bundles left after intersecting, the filters keep different bundles`,
+		},
+		{
+			name: "WHEN channels have no common entries THEN Returns error",
+			filter: Intersect(
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v3"}),
+				keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v2"}),
+			),
+			expectedErr: `package "foo" has no bundles left after intersecting, the filters keep different bundles`,
+		},
+		{
+			name: "WHEN a channel has no common entries THEN Removes the channel",
+			filter: Intersect(
+				NewPackageFilter("foo"),
+				catalogFilterFunc(func(fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+					out, err := keepChannel("foo", "stable", declcfg.ChannelEntry{Name: "foo.v2"}).FilterCatalog(context.Background(), fbc)
+					if err != nil {
+						return nil, err
+					}
+					out.Channels = append(out.Channels, declcfg.Channel{Name: "fast", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v2"}}})
+					return out, nil
+				}),
+			),
+			expected: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+				Channels: []declcfg.Channel{{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v2"}}}},
+				Bundles:  []declcfg.Bundle{{Name: "foo.v2", Package: "foo"}},
+			},
+		},
+		{
+			name: "WHEN the default channel differs and none remains THEN Returns error",
+			filter: Intersect(
+				Chain(NewPackageFilter("foo"), catalogFilterFunc(func(fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+					fbc.Packages[0].DefaultChannel = "candidate"
+					return fbc, nil
+				})),
+				Chain(NewPackageFilter("foo"), catalogFilterFunc(func(fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+					fbc.Packages[0].DefaultChannel = "beta"
+					return fbc, nil
+				})),
+			),
+			expectedErr: `package "foo" has no default channel left after intersecting, the filters keep different channels`,
+		},
+		{
+			name:     "WHEN no filter THEN Returns the catalog",
+			filter:   Intersect(),
+			expected: combineTestCatalog(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := combineTestCatalog()
+			actual, err := tt.filter.FilterCatalog(context.Background(), fbc)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestCombinators_KeepMeta(t *testing.T) {
+	foo := &declcfg.Meta{Schema: declcfg.SchemaPackage, Name: "foo"}
+	bar := &declcfg.Meta{Schema: declcfg.SchemaBundle, Package: "bar"}
+	notMetaFilter := catalogFilterFunc(func(fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) { return fbc, nil })
+	tests := []struct {
+		name     string
+		filter   CatalogFilter
+		expected []bool
+	}{
+		{name: "Chain", filter: Chain(NewPackageFilter("foo", "bar"), NewPackageFilter("foo")), expected: []bool{true, false}},
+		{name: "Chain_NotMetaFilter", filter: Chain(notMetaFilter, NewPackageFilter("bar")), expected: []bool{false, true}},
+		{name: "Union", filter: Union(NewPackageFilter("foo"), NewPackageFilter("bar")), expected: []bool{true, true}},
+		{name: "Union_NotMetaFilter", filter: Union(NewPackageFilter("foo"), notMetaFilter), expected: []bool{true, true}},
+		{name: "Union_None", filter: Union(), expected: []bool{false, false}},
+		{name: "Intersect", filter: Intersect(NewPackageFilter("foo", "bar"), NewPackageFilter("bar")), expected: []bool{false, true}},
+		{name: "Intersect_None", filter: Intersect(), expected: []bool{true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mf, ok := tt.filter.(MetaFilter)
+			require.True(t, ok)
+			assert.Equal(t, tt.expected, []bool{mf.KeepMeta(foo), mf.KeepMeta(bar)})
+		})
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
+
+	filter_package "github.com/sherine-k/catalog-filter/pkg/filter"
 )
 
 // FilterConfigurationV1 is a configuration for filtering a set of packages and channels from a catalog.
@@ -18,6 +20,10 @@ type FilterConfiguration struct {
 
 	// Packages is a list of packages to include in the filtered catalog.
 	Packages []Package `json:"packages"`
+
+	// DefaultChannelPolicy decides the new default channel of the packages whose default channel is filtered
+	// out, when none is configured for them. If not set, such packages are an error.
+	DefaultChannelPolicy filter_package.DefaultChannelPolicy `json:"defaultChannelPolicy,omitempty"`
 }
 
 type Package struct {
@@ -29,6 +35,9 @@ type Package struct {
 	// If the original default channel is not in the filtered catalog, this field must be set.
 	DefaultChannel string `json:"defaultChannel,omitempty"`
 
+	// DefaultChannelPolicy overrides, for the package, the DefaultChannelPolicy of the configuration.
+	DefaultChannelPolicy filter_package.DefaultChannelPolicy `json:"defaultChannelPolicy,omitempty"`
+
 	// Channels is a list of channels to include in the filtered catalog.
 	// If not set, all channels will be included.
 	Channels []Channel `json:"channels,omitempty"`
@@ -36,6 +45,8 @@ type Package struct {
 
 type Channel struct {
 	// Name is the name of the channel to include in the filtered catalog.
+	// It can also be a glob, such as `stable-*`, or a regular expression prefixed with `re:`,
+	// such as `re:^stable-4\.1[4-6]$`, selecting all the channels of the package it matches.
 	Name string `json:"name"`
 
 	// VersionRange is a semver range to filter the versions of the channel.
@@ -60,23 +71,34 @@ func LoadFilterConfiguration(r io.Reader) (*FilterConfiguration, error) {
 
 func (f *FilterConfiguration) Validate() error {
 	var errs []error
-	if f.APIVersion != "olm.operatorframework.io/v1alpha1" {
+	if f.APIVersion != FilterAPIVersion {
 		errs = append(errs, fmt.Errorf("unexpected API version %q", f.APIVersion))
 	}
-	if f.Kind != "FilterConfiguration" {
+	if f.Kind != FilterKind {
 		errs = append(errs, fmt.Errorf("unexpected kind %q", f.Kind))
 	}
 	if len(f.Packages) == 0 {
 		errs = append(errs, errors.New("at least one package must be specified"))
 	}
+	if err := f.DefaultChannelPolicy.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("defaultChannelPolicy is invalid: %v", err))
+	}
 	for i, pkg := range f.Packages {
 		if pkg.Name == "" {
 			errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: name must be specified", pkg.Name, i))
 		}
+		if err := pkg.DefaultChannelPolicy.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: %v", pkg.Name, i, err))
+		}
 		for j, channel := range pkg.Channels {
 			if channel.Name == "" {
 				errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: channel %q at index [%d] is invalid: name must be specified", pkg.Name, i, channel.Name, j))
 			}
+			if filter_package.IsNamePattern(channel.Name) {
+				if _, err := filter_package.CompileNamePattern(channel.Name); err != nil {
+					errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: channel %q at index [%d] is invalid: %v", pkg.Name, i, channel.Name, j, err))
+				}
+			}
 		}
 	}
 	return errors.Join(errs...)
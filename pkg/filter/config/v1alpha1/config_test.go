@@ -96,6 +96,10 @@ func TestLoadFilterConfiguration(t *testing.T) {
 				assert.ErrorContains(t, err, `package "" at index [3] is invalid: channel "" at index [0] is invalid: name must be specified`)
 				assert.ErrorContains(t, err, `package "" at index [3] is invalid: channel "" at index [1] is invalid: name must be specified`)
 				assert.ErrorContains(t, err, `package "baz" at index [4] is invalid: channel "" at index [0] is invalid: name must be specified`)
+				assert.ErrorContains(t, err, `package "qux" at index [5] is invalid: channel "stable-[" at index [0] is invalid: invalid glob "stable-["`)
+				assert.ErrorContains(t, err, `package "qux" at index [5] is invalid: channel "re:stable-(" at index [1] is invalid: invalid regular expression "stable-("`)
+				assert.ErrorContains(t, err, `defaultChannelPolicy is invalid: unknown default channel policy "newest"`)
+				assert.ErrorContains(t, err, `package "quux" at index [6] is invalid: unknown default channel policy "oldest"`)
 			},
 		},
 		{
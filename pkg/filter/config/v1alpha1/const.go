@@ -0,0 +1,6 @@
+package v1alpha1
+
+const (
+	FilterAPIVersion = "olm.operatorframework.io/v1alpha1"
+	FilterKind       = "FilterConfiguration"
+)
@@ -15,18 +15,21 @@ import (
 	"github.com/operator-framework/operator-registry/alpha/property"
 
 	filter_package "github.com/sherine-k/catalog-filter/pkg/filter"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
 )
 
 type filterOptions struct {
-	Log *logrus.Entry
+	Log           *logrus.Entry
+	MultipleHeads filter_package.MultipleHeadsPolicy
 }
 
 type FilterOption func(*filterOptions)
 
 type filter struct {
-	pkgConfigs map[string]Package
-	chConfigs  map[string]map[string]Channel
-	opts       filterOptions
+	pkgConfigs           map[string]Package
+	chConfigs            map[string]map[string]Channel
+	defaultChannelPolicy filter_package.DefaultChannelPolicy
+	opts                 filterOptions
 }
 
 func WithLogger(log *logrus.Entry) FilterOption {
@@ -35,6 +38,14 @@ func WithLogger(log *logrus.Entry) FilterOption {
 	}
 }
 
+// WithMultipleHeads sets how FilterCatalog filters the channels that have several heads, see
+// filter.MultipleHeadsPolicy. By default, such channels make FilterCatalog fail.
+func WithMultipleHeads(policy filter_package.MultipleHeadsPolicy) FilterOption {
+	return func(opts *filterOptions) {
+		opts.MultipleHeads = policy
+	}
+}
+
 func nullLogger() *logrus.Entry {
 	l := logrus.New()
 	l.SetOutput(io.Discard)
@@ -62,9 +73,10 @@ func NewFilter(config FilterConfiguration, filterOpts ...FilterOption) filter_pa
 		chConfigs[pkg.Name] = pkgChannels
 	}
 	return &filter{
-		pkgConfigs: pkgConfigs,
-		chConfigs:  chConfigs,
-		opts:       opts,
+		pkgConfigs:           pkgConfigs,
+		chConfigs:            chConfigs,
+		defaultChannelPolicy: config.DefaultChannelPolicy,
+		opts:                 opts,
 	}
 }
 
@@ -72,6 +84,20 @@ func (f *filter) FilterCatalog(_ context.Context, fbc *declcfg.DeclarativeConfig
 	if fbc == nil {
 		return nil, nil
 	}
+	if err := f.opts.MultipleHeads.Validate(); err != nil {
+		return nil, err
+	}
+	if filter_package.HasChannelPatterns(f.chConfigs) {
+		chConfigs, err := filter_package.ExpandChannelPatterns(f.configuredChannels(), filter_package.ChannelNames(fbc),
+			func(ch Channel) string { return ch.Name }, func(ch Channel, name string) Channel { ch.Name = name; return ch })
+		if err != nil {
+			return nil, err
+		}
+		// the expanded configuration only applies to fbc, f is left untouched for the next catalogs
+		expanded := *f
+		expanded.chConfigs = chConfigs
+		f = &expanded
+	}
 	fbc.Packages = slices.DeleteFunc(fbc.Packages, func(pkg declcfg.Package) bool {
 		_, ok := f.chConfigs[pkg.Name]
 		return !ok
@@ -109,13 +135,6 @@ func (f *filter) FilterCatalog(_ context.Context, fbc *declcfg.DeclarativeConfig
 		pkgChannels.Insert(ch.Name)
 		remainingChannels[ch.Package] = pkgChannels
 	}
-	for i, pkg := range fbc.Packages {
-		pkgConfig := f.pkgConfigs[pkg.Name]
-		if err := setDefaultChannel(&fbc.Packages[i], pkgConfig, remainingChannels[pkg.Name]); err != nil {
-			return nil, fmt.Errorf("invalid default channel configuration for package %q: %v", pkg.Name, err)
-		}
-	}
-
 	getVersion := func(b declcfg.Bundle) (*mmsemver.Version, error) {
 		for _, p := range b.Properties {
 			if p.Type != property.TypePackage {
@@ -144,6 +163,27 @@ func (f *filter) FilterCatalog(_ context.Context, fbc *declcfg.DeclarativeConfig
 		versionMap[b.Package] = bundleVersions
 	}
 
+	if f.opts.MultipleHeads == filter_package.MultipleHeadsPolicyKeepHighest {
+		for i, ch := range fbc.Channels {
+			fbc.Channels[i].Entries = graph.KeepHighestHead(ch, versionMap[ch.Package], f.opts.Log)
+		}
+	}
+
+	for i, pkg := range fbc.Packages {
+		pkgConfig := f.pkgConfigs[pkg.Name]
+		headVersion := func(channel string) (*mmsemver.Version, error) {
+			return f.channelHeadVersion(fbc.Channels, pkg.Name, channel, versionMap[pkg.Name])
+		}
+		selected, err := setDefaultChannel(&fbc.Packages[i], pkgConfig, f.policyFor(pkgConfig), remainingChannels[pkg.Name], headVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default channel configuration for package %q: %v", pkg.Name, err)
+		}
+		if selected {
+			f.opts.Log.Infof("package %q: the default channel %q was filtered out, channel %q was selected by the %q default channel policy",
+				pkg.Name, pkg.DefaultChannel, fbc.Packages[i].DefaultChannel, f.policyFor(pkgConfig))
+		}
+	}
+
 	keepBundles := map[string]sets.Set[string]{}
 	for i, fbcCh := range fbc.Channels {
 		keepEntries := sets.New[string]()
@@ -161,11 +201,13 @@ func (f *filter) FilterCatalog(_ context.Context, fbc *declcfg.DeclarativeConfig
 			if err != nil {
 				return nil, fmt.Errorf("error parsing version range: %v", err)
 			}
-			ch, err := newChannel(fbcCh, f.opts.Log)
+			heads, err := f.channelHeads(fbcCh)
 			if err != nil {
 				return nil, err
 			}
-			keepEntries = ch.filterByVersionRange(versionRange, versionMap[fbcCh.Package])
+			for _, ch := range heads {
+				keepEntries = keepEntries.Union(ch.FilterByVersionRange(versionRange, versionMap[fbcCh.Package], f.opts.Log))
+			}
 			if len(keepEntries) == 0 {
 				return nil, fmt.Errorf("package %q channel %q has version range %q that results in an empty channel", fbcCh.Package, fbcCh.Name, chConfig.VersionRange)
 			}
@@ -215,26 +257,85 @@ func (f *filter) KeepMeta(meta *declcfg.Meta) bool {
 	return ok
 }
 
-func setDefaultChannel(pkg *declcfg.Package, pkgConfig Package, channels sets.Set[string]) error {
+// configuredChannels returns the channels configured for each package, in order.
+func (f *filter) configuredChannels() map[string][]Channel {
+	channels := make(map[string][]Channel, len(f.pkgConfigs))
+	for name, pkg := range f.pkgConfigs {
+		channels[name] = pkg.Channels
+	}
+	return channels
+}
+
+// setDefaultChannel updates the default channel of pkg to the one configured in pkgConfig or, when the original
+// default channel is not among channels, to the one selected by policy. It reports whether policy selected it.
+func setDefaultChannel(pkg *declcfg.Package, pkgConfig Package, policy filter_package.DefaultChannelPolicy, channels sets.Set[string], headVersion func(string) (*mmsemver.Version, error)) (bool, error) {
 	// If both the FBC and package config leave the default channel unspecified, then we don't need to do anything.
 	if pkg.DefaultChannel == "" && pkgConfig.DefaultChannel == "" {
-		return nil
+		return false, nil
 	}
 
 	// If the default channel was specified in the filter configuration, then we need to check if it exists after filtering.
 	// If it does, then we update the model's default channel to the specified channel. Otherwise, we error.
 	if pkgConfig.DefaultChannel != "" {
 		if !channels.Has(pkgConfig.DefaultChannel) {
-			return fmt.Errorf("specified default channel override %q does not exist in the filtered output", pkgConfig.DefaultChannel)
+			return false, fmt.Errorf("specified default channel override %q does not exist in the filtered output", pkgConfig.DefaultChannel)
 		}
 		pkg.DefaultChannel = pkgConfig.DefaultChannel
-		return nil
+		return false, nil
 	}
 
 	// At this point, we know that the default channel was not configured in the filter configuration for this package.
-	// If the original default channel does not exist after filtering, error
+	// If the original default channel does not exist after filtering, the policy selects a new one or errors
 	if !channels.Has(pkg.DefaultChannel) {
-		return fmt.Errorf("the default channel %q was filtered out, a new default channel must be configured for this package", pkg.DefaultChannel)
+		selected, err := policy.SelectDefaultChannel(channels, headVersion)
+		if err != nil {
+			return false, fmt.Errorf("the default channel %q was filtered out, %v", pkg.DefaultChannel, err)
+		}
+		pkg.DefaultChannel = selected
+		return true, nil
+	}
+	return false, nil
+}
+
+// policyFor returns the default channel policy of pkgConfig, or the one of the configuration if it has none.
+func (f *filter) policyFor(pkgConfig Package) filter_package.DefaultChannelPolicy {
+	if pkgConfig.DefaultChannelPolicy != "" {
+		return pkgConfig.DefaultChannelPolicy
+	}
+	return f.defaultChannelPolicy
+}
+
+// channelHeadVersion returns the version of the head of the channel named name of pkg among channels, or of its
+// highest head when it has several.
+func (f *filter) channelHeadVersion(channels []declcfg.Channel, pkg, name string, versions map[string]*mmsemver.Version) (*mmsemver.Version, error) {
+	for _, ch := range channels {
+		if ch.Package != pkg || ch.Name != name {
+			continue
+		}
+		heads, err := f.channelHeads(ch)
+		if err != nil {
+			return nil, err
+		}
+		c := graph.HighestHead(heads, versions)
+		version, ok := versions[c.Head.Name]
+		if !ok {
+			return nil, fmt.Errorf("bundle %q not found", c.Head.Name)
+		}
+		return version, nil
+	}
+	return nil, fmt.Errorf("channel not found")
+}
+
+// channelHeads returns a channel for each head of ch that is filtered: all of them with
+// filter.MultipleHeadsPolicyKeepAll, and the single head of ch otherwise, which fails when ch has several heads.
+// With filter.MultipleHeadsPolicyKeepHighest, the other heads were already dropped by graph.KeepHighestHead.
+func (f *filter) channelHeads(ch declcfg.Channel) ([]*graph.Channel, error) {
+	if f.opts.MultipleHeads == filter_package.MultipleHeadsPolicyKeepAll {
+		return graph.NewChannelHeads(ch)
+	}
+	c, err := graph.NewChannel(ch)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return []*graph.Channel{c}, nil
 }
@@ -394,6 +394,47 @@ func TestFilter_FilterCatalog(t *testing.T) {
 				assert.ErrorContains(t, err, "empty channel")
 			},
 		},
+		{
+			name: "channel name patterns",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "pkg1", Channels: []Channel{{Name: "stable-4.14", VersionRange: ">=1.0.0"}, {Name: "stable-*"}, {Name: `re:^fast-4\.1[56]$`}}},
+			}},
+			in: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "pkg1", DefaultChannel: "stable-4.15"}},
+				Channels: []declcfg.Channel{
+					{Name: "stable-4.14", Package: "pkg1", Entries: []declcfg.ChannelEntry{{Name: "b1", Replaces: "b0"}, {Name: "b0"}}},
+					{Name: "stable-4.15", Package: "pkg1", Entries: []declcfg.ChannelEntry{{Name: "b0"}}},
+					{Name: "fast-4.14", Package: "pkg1", Entries: []declcfg.ChannelEntry{{Name: "b0"}}},
+					{Name: "fast-4.15", Package: "pkg1", Entries: []declcfg.ChannelEntry{{Name: "b0"}}},
+				},
+				Bundles: []declcfg.Bundle{
+					{Name: "b0", Package: "pkg1", Properties: []property.Property{property.MustBuildPackage("pkg1", "0.1.0")}},
+					{Name: "b1", Package: "pkg1", Properties: []property.Property{property.MustBuildPackage("pkg1", "1.0.0")}},
+				},
+			},
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.Channel{
+					{Name: "stable-4.14", Package: "pkg1", Entries: []declcfg.ChannelEntry{{Name: "b1", Replaces: "b0"}}},
+					{Name: "stable-4.15", Package: "pkg1", Entries: []declcfg.ChannelEntry{{Name: "b0"}}},
+					{Name: "fast-4.15", Package: "pkg1", Entries: []declcfg.ChannelEntry{{Name: "b0"}}},
+				}, actual.Channels)
+			},
+		},
+		{
+			name: "channel name pattern matches no channel",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "pkg1", Channels: []Channel{{Name: "stable-*"}, {Name: "re:^candidate"}}},
+			}},
+			in: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "pkg1", DefaultChannel: "stable-4.15"}},
+				Channels: []declcfg.Channel{{Name: "stable-4.15", Package: "pkg1"}},
+			},
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				assert.Nil(t, actual)
+				assert.EqualError(t, err, `package "pkg1" channel "re:^candidate": pattern matches no channel of the catalog`)
+			},
+		},
 		{
 			name: "FBC default channel specified, configuration default channel unspecified, channel remains",
 			config: FilterConfiguration{Packages: []Package{
@@ -435,6 +476,74 @@ func TestFilter_FilterCatalog(t *testing.T) {
 				assert.ErrorContains(t, err, `invalid default channel configuration for package "pkg1": the default channel "ch1" was filtered out, a new default channel must be configured for this package`)
 			},
 		},
+		{
+			name: "FBC default channel removed, highestVersion default channel policy",
+			config: FilterConfiguration{
+				Packages: []Package{
+					{Name: "pkg1", Channels: []Channel{{Name: "ch2"}, {Name: "ch3"}}},
+				},
+				DefaultChannelPolicy: filter_package.DefaultChannelPolicyHighestVersion,
+			},
+			in: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "pkg1", DefaultChannel: "ch1"}},
+				Channels: []declcfg.Channel{
+					{Name: "ch1", Package: "pkg1", Entries: []declcfg.ChannelEntry{{Name: "b3"}}},
+					{Name: "ch2", Package: "pkg1", Entries: []declcfg.ChannelEntry{{Name: "b2", Replaces: "b1"}, {Name: "b1"}}},
+					{Name: "ch3", Package: "pkg1", Entries: []declcfg.ChannelEntry{{Name: "b1"}}},
+				},
+				Bundles: []declcfg.Bundle{
+					{Name: "b1", Package: "pkg1", Properties: propertiesForBundle("pkg1", "0.1.0")},
+					{Name: "b2", Package: "pkg1", Properties: propertiesForBundle("pkg1", "0.2.0")},
+					{Name: "b3", Package: "pkg1", Properties: propertiesForBundle("pkg1", "0.3.0")},
+				},
+			},
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.Package{{Name: "pkg1", DefaultChannel: "ch2"}}, actual.Packages)
+			},
+		},
+		{
+			name: "FBC default channel removed, package stable default channel policy",
+			config: FilterConfiguration{
+				Packages: []Package{
+					{Name: "pkg1", Channels: []Channel{{Name: "fast"}, {Name: "stable-*"}}, DefaultChannelPolicy: filter_package.DefaultChannelPolicyStable},
+				},
+				DefaultChannelPolicy: filter_package.DefaultChannelPolicyError,
+			},
+			in: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "pkg1", DefaultChannel: "stable"}},
+				Channels: []declcfg.Channel{
+					{Name: "stable", Package: "pkg1"},
+					{Name: "stable-v1", Package: "pkg1"},
+					{Name: "stable-v2", Package: "pkg1"},
+					{Name: "fast", Package: "pkg1"},
+				},
+			},
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.Package{{Name: "pkg1", DefaultChannel: "stable-v2"}}, actual.Packages)
+			},
+		},
+		{
+			name: "FBC default channel removed, stable default channel policy without stable channel",
+			config: FilterConfiguration{
+				Packages: []Package{
+					{Name: "pkg1", Channels: []Channel{{Name: "fast"}}},
+				},
+				DefaultChannelPolicy: filter_package.DefaultChannelPolicyStable,
+			},
+			in: &declcfg.DeclarativeConfig{
+				Packages: []declcfg.Package{{Name: "pkg1", DefaultChannel: "stable"}},
+				Channels: []declcfg.Channel{
+					{Name: "stable", Package: "pkg1"},
+					{Name: "fast", Package: "pkg1"},
+				},
+			},
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				assert.Nil(t, actual)
+				assert.EqualError(t, err, `invalid default channel configuration for package "pkg1": the default channel "stable" was filtered out, no remaining channel matches the "stable" default channel policy, a new default channel must be configured for this package`)
+			},
+		},
 		{
 			name: "Configuration default channel specified, channel remains",
 			config: FilterConfiguration{Packages: []Package{
@@ -587,3 +696,55 @@ func propertiesForBundle(pkg, version string) []property.Property {
 		{Type: property.TypePackage, Value: []byte(fmt.Sprintf(`{"packageName": %q, "version": %q}`, pkg, version))},
 	}
 }
+
+func TestFilter_FilterCatalog_WithMultipleHeads(t *testing.T) {
+	multipleHeads := func() *declcfg.DeclarativeConfig {
+		return &declcfg.DeclarativeConfig{
+			Packages: []declcfg.Package{{Name: "pkg", DefaultChannel: "ch"}},
+			Channels: []declcfg.Channel{{Name: "ch", Package: "pkg", Entries: []declcfg.ChannelEntry{
+				{Name: "b1"},
+				{Name: "b2", Replaces: "b1"},
+				{Name: "b3", Skips: []string{"b1"}},
+				{Name: "b4", Replaces: "b3"},
+			}}},
+			Bundles: []declcfg.Bundle{
+				{Name: "b1", Package: "pkg", Properties: propertiesForBundle("pkg", "1.0.0")},
+				{Name: "b2", Package: "pkg", Properties: propertiesForBundle("pkg", "1.1.0")},
+				{Name: "b3", Package: "pkg", Properties: propertiesForBundle("pkg", "2.0.0")},
+				{Name: "b4", Package: "pkg", Properties: propertiesForBundle("pkg", "2.1.0")},
+			},
+		}
+	}
+	bundleNames := func(fbc *declcfg.DeclarativeConfig) []string {
+		var names []string
+		for _, b := range fbc.Bundles {
+			names = append(names, b.Name)
+		}
+		return names
+	}
+	withRange := FilterConfiguration{Packages: []Package{
+		{Name: "pkg", Channels: []Channel{{Name: "ch", VersionRange: ">=1.1.0 <2.1.0"}}},
+	}}
+
+	t.Run("error", func(t *testing.T) {
+		_, err := NewFilter(withRange).FilterCatalog(context.Background(), multipleHeads())
+		assert.ErrorContains(t, err, `multiple channel heads found: [b2 b4]`)
+	})
+	t.Run("keepAll", func(t *testing.T) {
+		f := NewFilter(withRange, WithMultipleHeads(filter_package.MultipleHeadsPolicyKeepAll))
+		out, err := f.FilterCatalog(context.Background(), multipleHeads())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b2", "b3"}, bundleNames(out))
+	})
+	t.Run("keepHighest", func(t *testing.T) {
+		f := NewFilter(FilterConfiguration{Packages: []Package{{Name: "pkg"}}}, WithMultipleHeads(filter_package.MultipleHeadsPolicyKeepHighest))
+		out, err := f.FilterCatalog(context.Background(), multipleHeads())
+		require.NoError(t, err)
+		assert.Equal(t, []declcfg.ChannelEntry{
+			{Name: "b1"},
+			{Name: "b3", Skips: []string{"b1"}},
+			{Name: "b4", Replaces: "b3"},
+		}, out.Channels[0].Entries)
+		assert.Equal(t, []string{"b1", "b3", "b4"}, bundleNames(out))
+	})
+}
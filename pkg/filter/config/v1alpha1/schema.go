@@ -0,0 +1,60 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+
+	filter_package "github.com/sherine-k/catalog-filter/pkg/filter"
+	"github.com/sherine-k/catalog-filter/pkg/schema"
+)
+
+// JSONSchema returns the JSON Schema of FilterConfiguration.
+func JSONSchema() *schema.Schema {
+	return schema.Generate(FilterConfiguration{})
+}
+
+// ExtendJSONSchema sets the API version and kind of the configuration, which must list packages.
+func (FilterConfiguration) ExtendJSONSchema(s *schema.Schema) {
+	s.Schema = schema.Draft
+	s.Title = FilterKind + " " + FilterAPIVersion
+	s.Properties["apiVersion"].Const = FilterAPIVersion
+	s.Properties["kind"].Const = FilterKind
+	s.Properties["packages"].MinItems = schema.Ptr(1)
+	s.Required = append(s.Required, "apiVersion", "kind", "packages")
+	s.Properties["defaultChannelPolicy"].Enum = schema.Enum(filter_package.DefaultChannelPolicies()...)
+}
+
+// ExtendJSONSchema requires the name of the package.
+func (Package) ExtendJSONSchema(s *schema.Schema) {
+	s.Properties["name"].MinLength = schema.Ptr(1)
+	s.Properties["defaultChannelPolicy"].Enum = schema.Enum(filter_package.DefaultChannelPolicies()...)
+}
+
+// ExtendJSONSchema requires the name of the channel, and adds the format of its version range.
+func (Channel) ExtendJSONSchema(s *schema.Schema) {
+	s.Properties["name"].MinLength = schema.Ptr(1)
+	schema.VersionRange(s.Properties["versionRange"])
+}
+
+// LoadFilterConfigurationStrict loads a FilterConfiguration like LoadFilterConfiguration, after validating it
+// against JSONSchema. Unknown fields, values of the wrong type and the other schema violations are reported
+// with their line and column.
+func LoadFilterConfigurationStrict(r io.Reader) (*FilterConfiguration, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.ValidateYAML(JSONSchema(), data); err != nil {
+		return nil, fmt.Errorf("invalid filter configuration: %w", err)
+	}
+	cfg := &FilterConfiguration{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,67 @@
+package v1alpha1
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFilterConfigurationStrict(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		path     string
+		expected []string
+	}{
+		{
+			name: "WHEN configuration is valid THEN Loads it",
+			data: "apiVersion: olm.operatorframework.io/v1alpha1\nkind: FilterConfiguration\npackages:\n- name: foo\n  channels:\n  - name: stable\n    versionRange: '>=1.0.0 <2.0.0'\n",
+		},
+		{
+			name: "WHEN fields are unknown THEN Returns their position",
+			data: "apiVersion: olm.operatorframework.io/v1alpha1\nkind: FilterConfiguration\npackages:\n- name: foo\n  minVersion: 1.0.0\n  channels:\n  - name: stable\n    versionRange: latest\n",
+			expected: []string{
+				`line 5, column 3: packages[0]: unknown field "minVersion"`,
+				`line 8, column 19: packages[0].channels[0].versionRange: "latest" must be a semver range, such as ">=1.0.0 <2.0.0"`,
+			},
+		},
+		{
+			name: "WHEN configuration has errors THEN Returns their position",
+			path: "testdata/configs/invalid_multipleerrors.yaml",
+			expected: []string{
+				`line 1, column 13: apiVersion: must be "olm.operatorframework.io/v1alpha1"`,
+				`line 2, column 7: kind: must be "FilterConfiguration"`,
+			},
+		},
+		{
+			name:     "WHEN no packages THEN Returns error",
+			path:     "testdata/configs/invalid_nopackages.yaml",
+			expected: []string{`line 1, column 1: missing required field "packages"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.data)
+			if tt.path != "" {
+				var err error
+				data, err = os.ReadFile(tt.path)
+				require.NoError(t, err)
+			}
+			cfg, err := LoadFilterConfigurationStrict(strings.NewReader(string(data)))
+			if len(tt.expected) == 0 {
+				require.NoError(t, err)
+				expected, err := LoadFilterConfiguration(strings.NewReader(string(data)))
+				require.NoError(t, err)
+				assert.Equal(t, expected, cfg)
+				return
+			}
+			assert.Nil(t, cfg)
+			for _, expected := range tt.expected {
+				assert.ErrorContains(t, err, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// DefaultChannelPolicy decides which channel becomes the default channel of a package whose default channel
+// was filtered out, when no default channel is configured for the package.
+type DefaultChannelPolicy string
+
+const (
+	// DefaultChannelPolicyError fails the filtering, so that a default channel has to be configured.
+	// It is the policy used when none is set.
+	DefaultChannelPolicyError DefaultChannelPolicy = "error"
+	// DefaultChannelPolicyHighestVersion picks the remaining channel whose head has the highest version.
+	// Channels whose heads have the same version are ordered by name, the alphabetically highest is picked.
+	DefaultChannelPolicyHighestVersion DefaultChannelPolicy = "highestVersion"
+	// DefaultChannelPolicyStable picks the alphabetically highest remaining channel whose name starts
+	// with "stable", such as "stable-v2" rather than "stable" or "stable-v1".
+	DefaultChannelPolicyStable DefaultChannelPolicy = "stable"
+)
+
+// Validate checks that p is one of the known policies, or is empty.
+func (p DefaultChannelPolicy) Validate() error {
+	switch p {
+	case "", DefaultChannelPolicyError, DefaultChannelPolicyHighestVersion, DefaultChannelPolicyStable:
+		return nil
+	}
+	return fmt.Errorf("unknown default channel policy %q, expected one of %q, %q or %q", p,
+		DefaultChannelPolicyError, DefaultChannelPolicyHighestVersion, DefaultChannelPolicyStable)
+}
+
+// DefaultChannelPolicies returns the known policies, in the order they are documented.
+func DefaultChannelPolicies() []DefaultChannelPolicy {
+	return []DefaultChannelPolicy{DefaultChannelPolicyError, DefaultChannelPolicyHighestVersion, DefaultChannelPolicyStable}
+}
+
+// SelectDefaultChannel returns the channel that p picks among channels, the remaining channels of a package.
+// headVersion returns the version of the head of a channel, it is only called by DefaultChannelPolicyHighestVersion.
+func (p DefaultChannelPolicy) SelectDefaultChannel(channels sets.Set[string], headVersion func(channel string) (*mmsemver.Version, error)) (string, error) {
+	var selected string
+	switch p {
+	case DefaultChannelPolicyHighestVersion:
+		var highest *mmsemver.Version
+		for _, name := range sets.List(channels) {
+			version, err := headVersion(name)
+			if err != nil {
+				return "", fmt.Errorf("unable to find the head version of channel %q: %v", name, err)
+			}
+			if highest == nil || !version.LessThan(highest) {
+				selected, highest = name, version
+			}
+		}
+	case DefaultChannelPolicyStable:
+		for _, name := range sets.List(channels) {
+			if strings.HasPrefix(name, "stable") {
+				selected = name
+			}
+		}
+	case "", DefaultChannelPolicyError:
+		return "", fmt.Errorf("a new default channel must be configured for this package")
+	default:
+		return "", p.Validate()
+	}
+	if selected == "" {
+		return "", fmt.Errorf("no remaining channel matches the %q default channel policy, a new default channel must be configured for this package", p)
+	}
+	return selected, nil
+}
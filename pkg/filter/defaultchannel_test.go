@@ -0,0 +1,99 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestDefaultChannelPolicy_SelectDefaultChannel(t *testing.T) {
+	heads := map[string]string{
+		"fast":      "3.0.0",
+		"stable":    "2.1.0",
+		"stable-v1": "1.5.0",
+		"stable-v2": "2.1.0",
+	}
+	headVersion := func(channel string) (*mmsemver.Version, error) {
+		v, ok := heads[channel]
+		if !ok {
+			return nil, fmt.Errorf("no head")
+		}
+		return mmsemver.NewVersion(v)
+	}
+	tests := []struct {
+		name        string
+		policy      DefaultChannelPolicy
+		channels    []string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:        "NoPolicy",
+			channels:    []string{"fast", "stable-v1"},
+			expectedErr: "a new default channel must be configured for this package",
+		},
+		{
+			name:        "Error",
+			policy:      DefaultChannelPolicyError,
+			channels:    []string{"fast", "stable-v1"},
+			expectedErr: "a new default channel must be configured for this package",
+		},
+		{
+			name:     "HighestVersion",
+			policy:   DefaultChannelPolicyHighestVersion,
+			channels: []string{"stable", "fast", "stable-v1"},
+			expected: "fast",
+		},
+		{
+			name:     "HighestVersion_SameVersion",
+			policy:   DefaultChannelPolicyHighestVersion,
+			channels: []string{"stable-v2", "stable", "stable-v1"},
+			expected: "stable-v2",
+		},
+		{
+			name:        "HighestVersion_UnknownHead",
+			policy:      DefaultChannelPolicyHighestVersion,
+			channels:    []string{"stable", "candidate"},
+			expectedErr: `unable to find the head version of channel "candidate": no head`,
+		},
+		{
+			name:     "Stable",
+			policy:   DefaultChannelPolicyStable,
+			channels: []string{"fast", "stable", "stable-v2", "stable-v1"},
+			expected: "stable-v2",
+		},
+		{
+			name:        "Stable_NoMatch",
+			policy:      DefaultChannelPolicyStable,
+			channels:    []string{"fast", "candidate"},
+			expectedErr: `no remaining channel matches the "stable" default channel policy`,
+		},
+		{
+			name:        "Unknown",
+			policy:      "newest",
+			channels:    []string{"fast"},
+			expectedErr: `unknown default channel policy "newest"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := tt.policy.SelectDefaultChannel(sets.New(tt.channels...), headVersion)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestDefaultChannelPolicies(t *testing.T) {
+	for _, policy := range DefaultChannelPolicies() {
+		assert.NoError(t, policy.Validate())
+	}
+	assert.Error(t, DefaultChannelPolicy("latest").Validate())
+}
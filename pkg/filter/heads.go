@@ -0,0 +1,27 @@
+package filter
+
+import "fmt"
+
+// MultipleHeadsPolicy decides how the channels that have more than one head are filtered.
+type MultipleHeadsPolicy string
+
+const (
+	// MultipleHeadsPolicyError fails the filtering of channels that have several heads.
+	// It is the policy used when none is set.
+	MultipleHeadsPolicyError MultipleHeadsPolicy = "error"
+	// MultipleHeadsPolicyKeepAll filters the entries each head upgrades from separately, and keeps all the heads.
+	MultipleHeadsPolicyKeepAll MultipleHeadsPolicy = "keepAll"
+	// MultipleHeadsPolicyKeepHighest keeps the head with the highest version, and drops the other heads along
+	// with the entries that only they upgrade from.
+	MultipleHeadsPolicyKeepHighest MultipleHeadsPolicy = "keepHighest"
+)
+
+// Validate checks that p is one of the known policies, or is empty.
+func (p MultipleHeadsPolicy) Validate() error {
+	switch p {
+	case "", MultipleHeadsPolicyError, MultipleHeadsPolicyKeepAll, MultipleHeadsPolicyKeepHighest:
+		return nil
+	}
+	return fmt.Errorf("unknown multiple heads policy %q, expected one of %q, %q or %q", p,
+		MultipleHeadsPolicyError, MultipleHeadsPolicyKeepAll, MultipleHeadsPolicyKeepHighest)
+}
@@ -0,0 +1,68 @@
+package v1alpha1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
+)
+
+// ChannelGraph returns the upgrade graph of the channel named channel of package pkg in fbc, along with the
+// options to render it, labelled with the versions of its bundles. Channels with several heads are accepted.
+//
+// When filtered, the output of FilterCatalog for fbc, is not nil, the entries are marked as kept or dropped
+// depending on whether they are entries of the same channel in filtered. The kept entries that the report
+// records as required to preserve a single head, although they are outside of the version range, are
+// highlighted. Passing filtered as fbc renders the channel after filtering.
+func ChannelGraph(fbc, filtered *declcfg.DeclarativeConfig, report *FilterReport, pkg, channel string) (*graph.Graph, graph.RenderOptions, error) {
+	opts := graph.RenderOptions{Title: pkg + "/" + channel}
+	index, err := indexFromDeclCfg(fbc)
+	if err != nil {
+		return nil, opts, err
+	}
+	ch, ok := findChannel(index.Channels[pkg], channel)
+	if !ok {
+		return nil, opts, fmt.Errorf("package %q channel %q not found", pkg, channel)
+	}
+	g, err := graph.New(ch, graph.WithMultipleHeads(true))
+	if err != nil {
+		return nil, opts, fmt.Errorf("package %q channel %q: %v", pkg, channel, err)
+	}
+	opts.Versions = index.BundleVersionsByPkgAndName[pkg]
+	if filtered == nil {
+		return g, opts, nil
+	}
+
+	kept := sets.New[string]()
+	for _, c := range filtered.Channels {
+		if c.Package != pkg || c.Name != channel {
+			continue
+		}
+		for _, e := range c.Entries {
+			kept.Insert(e.Name)
+		}
+	}
+	keptOutOfRange := sets.New[string]()
+	if report != nil {
+		pkgReport, _ := report.Package(pkg)
+		chReport, _ := pkgReport.Channel(channel)
+		for _, e := range chReport.Entries {
+			if e.Decision == Kept && e.Reason == ReasonSingleHead {
+				keptOutOfRange.Insert(e.Name)
+			}
+		}
+	}
+	opts.Marks = g.MarkEntries(kept, keptOutOfRange.Intersection(kept))
+	return g, opts, nil
+}
+
+func findChannel(channels []declcfg.Channel, name string) (declcfg.Channel, bool) {
+	for _, ch := range channels {
+		if ch.Name == name {
+			return ch, true
+		}
+	}
+	return declcfg.Channel{}, false
+}
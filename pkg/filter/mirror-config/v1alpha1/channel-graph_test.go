@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
+)
+
+func TestChannelGraph(t *testing.T) {
+	catalog := func() *declcfg.DeclarativeConfig {
+		return &declcfg.DeclarativeConfig{
+			Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+			Channels: []declcfg.Channel{{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.1.0", Replaces: "foo.v2.0.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.0.0", Replaces: "foo.v0.1.0"},
+				{Name: "foo.v0.1.0"},
+			}}},
+			Bundles: []declcfg.Bundle{
+				{Name: "foo.v0.1.0", Package: "foo", Properties: propertiesForBundle("foo", "0.1.0")},
+				{Name: "foo.v1.0.0", Package: "foo", Properties: propertiesForBundle("foo", "1.0.0")},
+				{Name: "foo.v1.1.0", Package: "foo", Properties: propertiesForBundle("foo", "1.1.0")},
+				{Name: "foo.v2.0.0", Package: "foo", Properties: propertiesForBundle("foo", "2.0.0")},
+			},
+		}
+	}
+	config := FilterConfiguration{Packages: []Package{{Name: "foo", Channels: []Channel{{Name: "stable", VersionRange: ">=1.0.0 <2.0.0"}}}}}
+
+	fbc := catalog()
+	report := &FilterReport{}
+	filtered, err := NewMirrorFilter(config, WithLogger(nullLogger()), WithReport(report)).FilterCatalog(context.Background(), fbc)
+	require.NoError(t, err)
+	assert.Equal(t, catalog(), fbc, "filtering must leave the catalog untouched")
+
+	t.Run("WHEN no filtered catalog THEN Returns the graph with versions and without marks", func(t *testing.T) {
+		g, opts, err := ChannelGraph(fbc, nil, nil, "foo", "stable")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"foo.v1.1.0", "foo.v2.0.0", "foo.v1.0.0", "foo.v0.1.0"}, g.TopologicalOrder())
+		assert.Equal(t, "foo/stable", opts.Title)
+		assert.Equal(t, "2.0.0", opts.Versions["foo.v2.0.0"].String())
+		assert.Nil(t, opts.Marks)
+	})
+	t.Run("WHEN before filtering THEN Marks kept, dropped and out of range entries", func(t *testing.T) {
+		g, opts, err := ChannelGraph(fbc, filtered, report, "foo", "stable")
+		require.NoError(t, err)
+		assert.Len(t, g.TopologicalOrder(), 4)
+		assert.Equal(t, map[string]graph.Mark{
+			"foo.v1.1.0": graph.MarkKept,
+			"foo.v2.0.0": graph.MarkKeptOutOfRange,
+			"foo.v1.0.0": graph.MarkKept,
+			"foo.v0.1.0": graph.MarkDropped,
+		}, opts.Marks)
+	})
+	t.Run("WHEN after filtering THEN Marks the remaining entries", func(t *testing.T) {
+		g, opts, err := ChannelGraph(filtered, filtered, report, "foo", "stable")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"foo.v1.1.0", "foo.v2.0.0", "foo.v1.0.0"}, g.TopologicalOrder())
+		assert.Equal(t, map[string]graph.Mark{
+			"foo.v1.1.0": graph.MarkKept,
+			"foo.v2.0.0": graph.MarkKeptOutOfRange,
+			"foo.v1.0.0": graph.MarkKept,
+		}, opts.Marks)
+	})
+	t.Run("WHEN channel does not exist THEN Returns error", func(t *testing.T) {
+		_, _, err := ChannelGraph(fbc, filtered, report, "foo", "fast")
+		assert.EqualError(t, err, `package "foo" channel "fast" not found`)
+	})
+}
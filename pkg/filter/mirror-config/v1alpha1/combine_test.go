@@ -0,0 +1,117 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+
+	filter_package "github.com/sherine-k/catalog-filter/pkg/filter"
+)
+
+func combineTestCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v1.0.0"},
+			{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			{Name: "foo.v2.0.0", Replaces: "foo.v1.1.0"},
+			{Name: "foo.v3.0.0", Replaces: "foo.v2.0.0"},
+		}}},
+		Bundles: []declcfg.Bundle{
+			{Name: "foo.v1.0.0", Package: "foo", Image: "quay.io/foo/foo.v1.0.0", Properties: propertiesForBundle("foo", "1.0.0")},
+			{Name: "foo.v1.1.0", Package: "foo", Image: "quay.io/foo/foo.v1.1.0", Properties: propertiesForBundle("foo", "1.1.0")},
+			{Name: "foo.v2.0.0", Package: "foo", Image: "quay.io/foo/foo.v2.0.0", Properties: propertiesForBundle("foo", "2.0.0")},
+			{Name: "foo.v3.0.0", Package: "foo", Image: "quay.io/foo/foo.v3.0.0", Properties: propertiesForBundle("foo", "3.0.0")},
+		},
+	}
+}
+
+func TestUnion_MirrorFilters(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    filter_package.CatalogFilter
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, error)
+	}{
+		{
+			name: "WHEN a filter keeps the channel head and another older entries THEN Keeps the entries connecting them",
+			filter: filter_package.Union(
+				NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo"}}}),
+				NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo", VersionRange: "<2.0.0"}}}),
+			),
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, combineTestCatalog().Channels, actual.Channels)
+				assert.ElementsMatch(t, []string{"foo.v1.0.0", "foo.v1.1.0", "foo.v2.0.0", "foo.v3.0.0"}, bundleNamesOf(actual))
+				_, err = declcfg.ConvertToModel(*actual)
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "WHEN filters keep overlapping version ranges THEN Merges them",
+			filter: filter_package.Union(
+				NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo", VersionRange: "<1.1.0"}}}),
+				NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo", VersionRange: ">=1.1.0 <3.0.0"}}}),
+			),
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []string{"foo.v1.0.0", "foo.v1.1.0", "foo.v2.0.0"}, bundleNamesOf(actual))
+				_, err = declcfg.ConvertToModel(*actual)
+				assert.NoError(t, err)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := tt.filter.FilterCatalog(context.Background(), combineTestCatalog())
+			tt.assertion(t, actual, err)
+		})
+	}
+}
+
+func TestIntersect_MirrorFilters(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    filter_package.CatalogFilter
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, error)
+	}{
+		{
+			name: "WHEN filters keep overlapping version ranges THEN Keeps the common entries",
+			filter: filter_package.Intersect(
+				NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo", VersionRange: ">=1.1.0"}}}),
+				NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo", VersionRange: "<3.0.0"}}}),
+			),
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				require.Len(t, actual.Channels, 1)
+				assert.Equal(t, []declcfg.ChannelEntry{
+					{Name: "foo.v1.1.0"},
+					{Name: "foo.v2.0.0", Replaces: "foo.v1.1.0"},
+				}, actual.Channels[0].Entries)
+				assert.Equal(t, []string{"foo.v1.1.0", "foo.v2.0.0"}, bundleNamesOf(actual))
+				_, err = declcfg.ConvertToModel(*actual)
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "WHEN a filter keeps the channel head and another older entries THEN Returns error",
+			filter: filter_package.Intersect(
+				NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo"}}}),
+				NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo", VersionRange: "<2.0.0"}}}),
+			),
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				assert.Nil(t, actual)
+				assert.EqualError(t, err, `package "foo" has no bundles left after intersecting, the filters keep different bundles`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := tt.filter.FilterCatalog(context.Background(), combineTestCatalog())
+			tt.assertion(t, actual, err)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	configv1alpha1 "github.com/sherine-k/catalog-filter/pkg/filter/config/v1alpha1"
+)
+
+// ConversionError is returned when a configuration sets fields that have no equivalent in the API version it is
+// converted to. The converted configuration is returned along with it, without those fields.
+type ConversionError struct {
+	// APIVersion is the API version the configuration was converted to.
+	APIVersion string
+	// Fields are the paths of the fields that could not be converted, such as packages[0].shortestPath, and of
+	// the packages and channels whose selection has no equivalent, such as packages[0].channels[1] when the
+	// channel only keeps its head.
+	Fields []string
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("fields cannot be converted to %s: %s", e.APIVersion, strings.Join(e.Fields, ", "))
+}
+
+// ConvertFromConfigV1alpha1 converts a configuration of the config/v1alpha1 API into this API. All of its fields
+// have an equivalent. Both filters select packages, channels and version ranges alike, but NewMirrorFilter only
+// keeps the channel heads of the packages and channels without a version range, unless filtering InFull.
+func ConvertFromConfigV1alpha1(in *configv1alpha1.FilterConfiguration) *FilterConfiguration {
+	out := &FilterConfiguration{
+		TypeMeta:             metav1.TypeMeta{APIVersion: FilterAPIVersion, Kind: FilterKind},
+		DefaultChannelPolicy: in.DefaultChannelPolicy,
+	}
+	for _, pkg := range in.Packages {
+		outPkg := Package{
+			Name:                 pkg.Name,
+			DefaultChannel:       pkg.DefaultChannel,
+			DefaultChannelPolicy: pkg.DefaultChannelPolicy,
+		}
+		for _, ch := range pkg.Channels {
+			outPkg.Channels = append(outPkg.Channels, Channel{Name: ch.Name, VersionRange: ch.VersionRange})
+		}
+		out.Packages = append(out.Packages, outPkg)
+	}
+	return out
+}
+
+// ConvertToConfigV1alpha1 converts in into a configuration of the config/v1alpha1 API. The version range of a
+// package, or its minVersion and maxVersion, is set on each of its channels, which requires the package to list
+// its channels. The config/v1alpha1 filter keeps all the bundles of the channels without a version range, so the
+// packages and channels that only keep their channel heads have no equivalent. The fields that have no equivalent
+// are reported in a *ConversionError, returned along with the converted configuration.
+func ConvertToConfigV1alpha1(in *FilterConfiguration) (*configv1alpha1.FilterConfiguration, error) {
+	out := &configv1alpha1.FilterConfiguration{
+		TypeMeta:             metav1.TypeMeta{APIVersion: configv1alpha1.FilterAPIVersion, Kind: configv1alpha1.FilterKind},
+		DefaultChannelPolicy: in.DefaultChannelPolicy,
+	}
+	var fields []string
+	if len(in.Packages) == 0 {
+		// the config/v1alpha1 API requires at least one package, it cannot keep the whole catalog
+		fields = append(fields, "packages")
+	}
+	if len(in.Selectors) > 0 {
+		fields = append(fields, "selectors")
+	}
+	if len(in.ExcludePackages) > 0 {
+		fields = append(fields, "excludePackages")
+	}
+	if in.SkipDeprecated {
+		fields = append(fields, "skipDeprecated")
+	}
+	for i, pkg := range in.Packages {
+		path := fmt.Sprintf("packages[%d]", i)
+		outPkg := configv1alpha1.Package{
+			Name:                 pkg.Name,
+			DefaultChannel:       pkg.DefaultChannel,
+			DefaultChannelPolicy: pkg.DefaultChannelPolicy,
+		}
+		if pkg.effectiveVersionRange() != "" && len(pkg.Channels) == 0 {
+			fields = append(fields, versionRangeFields(path, pkg.VersionRange, pkg.MinVersion, pkg.MaxVersion)...)
+		}
+		// the channel heads of the package are kept, the bundle selections are reported below
+		if len(pkg.Channels) == 0 && pkg.effectiveVersionRange() == "" && !pkg.ShortestPath && len(pkg.SelectedBundles) == 0 {
+			fields = append(fields, path)
+		}
+		if pkg.ShortestPath {
+			fields = append(fields, path+".shortestPath")
+		}
+		if len(pkg.SelectedBundles) > 0 {
+			fields = append(fields, path+".bundles")
+		}
+		if len(pkg.ExcludeChannels) > 0 {
+			fields = append(fields, path+".excludeChannels")
+		}
+		if len(pkg.ExcludeBundles) > 0 {
+			fields = append(fields, path+".excludeBundles")
+		}
+		if pkg.SkipDeprecated {
+			fields = append(fields, path+".skipDeprecated")
+		}
+		for j, ch := range pkg.Channels {
+			versionRange := ch.effectiveVersionRange()
+			if versionRange == "" {
+				versionRange = pkg.effectiveVersionRange()
+			}
+			if ch.ShortestPath {
+				fields = append(fields, fmt.Sprintf("%s.channels[%d].shortestPath", path, j))
+			}
+			if versionRange == "" && !ch.ShortestPath && !pkg.ShortestPath {
+				fields = append(fields, fmt.Sprintf("%s.channels[%d]", path, j))
+			}
+			outPkg.Channels = append(outPkg.Channels, configv1alpha1.Channel{Name: ch.Name, VersionRange: versionRange})
+		}
+		out.Packages = append(out.Packages, outPkg)
+	}
+	if len(fields) > 0 {
+		return out, &ConversionError{APIVersion: configv1alpha1.FilterAPIVersion, Fields: fields}
+	}
+	return out, nil
+}
+
+// versionRangeFields returns the paths of the version range fields that are set under path.
+func versionRangeFields(path, versionRange, minVersion, maxVersion string) []string {
+	var fields []string
+	for _, field := range []struct{ name, value string }{{"versionRange", versionRange}, {"minVersion", minVersion}, {"maxVersion", maxVersion}} {
+		if field.value != "" {
+			fields = append(fields, path+"."+field.name)
+		}
+	}
+	return fields
+}
+
+// LoadAnyFilterConfiguration loads a FilterConfiguration of any of the supported API versions, dispatching on its
+// apiVersion and kind, and converts it into this API. Configurations of unknown API versions or kinds are loaded
+// like LoadFilterConfiguration loads them, and fail validation.
+func LoadAnyFilterConfiguration(r io.Reader) (*FilterConfiguration, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFilterConfiguration(data, false)
+}
+
+// decodeFilterConfiguration loads data like LoadAnyFilterConfiguration does, or like the
+// LoadFilterConfigurationStrict function of its API when strict is set.
+func decodeFilterConfiguration(data []byte, strict bool) (*FilterConfiguration, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.APIVersion == configv1alpha1.FilterAPIVersion && typeMeta.Kind == configv1alpha1.FilterKind {
+		load := configv1alpha1.LoadFilterConfiguration
+		if strict {
+			load = configv1alpha1.LoadFilterConfigurationStrict
+		}
+		cfg, err := load(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return ConvertFromConfigV1alpha1(cfg), nil
+	}
+	if strict {
+		return LoadFilterConfigurationStrict(bytes.NewReader(data))
+	}
+	return LoadFilterConfiguration(bytes.NewReader(data))
+}
@@ -0,0 +1,206 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sherine-k/catalog-filter/pkg/filter"
+	configv1alpha1 "github.com/sherine-k/catalog-filter/pkg/filter/config/v1alpha1"
+)
+
+func configV1alpha1TestConfig() *configv1alpha1.FilterConfiguration {
+	return &configv1alpha1.FilterConfiguration{
+		TypeMeta:             metav1.TypeMeta{APIVersion: configv1alpha1.FilterAPIVersion, Kind: configv1alpha1.FilterKind},
+		DefaultChannelPolicy: filter.DefaultChannelPolicyHighestVersion,
+		Packages: []configv1alpha1.Package{
+			{Name: "foo"},
+			{
+				Name:           "bar",
+				DefaultChannel: "bar-channel1",
+				Channels: []configv1alpha1.Channel{
+					{Name: "bar-channel1", VersionRange: ">=1.0.0 <2.0.0"},
+					{Name: "bar-channel2", VersionRange: ">=2.0.0 <3.0.0"},
+				},
+			},
+		},
+	}
+}
+
+func convertedTestConfig() *FilterConfiguration {
+	return &FilterConfiguration{
+		TypeMeta:             metav1.TypeMeta{APIVersion: FilterAPIVersion, Kind: FilterKind},
+		DefaultChannelPolicy: filter.DefaultChannelPolicyHighestVersion,
+		Packages: []Package{
+			{Name: "foo"},
+			{
+				Name:           "bar",
+				DefaultChannel: "bar-channel1",
+				Channels: []Channel{
+					{Name: "bar-channel1", VersionRange: ">=1.0.0 <2.0.0"},
+					{Name: "bar-channel2", VersionRange: ">=2.0.0 <3.0.0"},
+				},
+			},
+		},
+	}
+}
+
+func TestConvertFromConfigV1alpha1(t *testing.T) {
+	t.Run("WHEN converting THEN Converts all the fields", func(t *testing.T) {
+		actual := ConvertFromConfigV1alpha1(configV1alpha1TestConfig())
+		require.NoError(t, actual.Validate())
+		assert.Equal(t, convertedTestConfig(), actual)
+	})
+	t.Run("WHEN converting back THEN Returns the same configuration and reports the packages keeping their heads", func(t *testing.T) {
+		actual, err := ConvertToConfigV1alpha1(ConvertFromConfigV1alpha1(configV1alpha1TestConfig()))
+		var conversionErr *ConversionError
+		require.ErrorAs(t, err, &conversionErr)
+		assert.Equal(t, []string{"packages[0]"}, conversionErr.Fields)
+		assert.Equal(t, configV1alpha1TestConfig(), actual)
+	})
+}
+
+func TestConvertToConfigV1alpha1(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         *FilterConfiguration
+		expected       *configv1alpha1.FilterConfiguration
+		expectedFields []string
+	}{
+		{
+			name: "WHEN package has version bounds and channels THEN Sets the range on each channel",
+			config: &FilterConfiguration{Packages: []Package{{
+				Name:       "foo",
+				MinVersion: "1.0.0",
+				MaxVersion: "2.0.0",
+				Channels:   []Channel{{Name: "stable"}, {Name: "fast"}},
+			}}},
+			expected: &configv1alpha1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{APIVersion: configv1alpha1.FilterAPIVersion, Kind: configv1alpha1.FilterKind},
+				Packages: []configv1alpha1.Package{{
+					Name: "foo",
+					Channels: []configv1alpha1.Channel{
+						{Name: "stable", VersionRange: ">=1.0.0 <=2.0.0"},
+						{Name: "fast", VersionRange: ">=1.0.0 <=2.0.0"},
+					},
+				}},
+			},
+		},
+		{
+			name: "WHEN fields have no equivalent THEN Reports them along with the converted configuration",
+			config: &FilterConfiguration{
+				Selectors:       []Selector{{Provider: "acme"}},
+				ExcludePackages: []string{"baz"},
+				SkipDeprecated:  true,
+				Packages: []Package{
+					{Name: "foo", MinVersion: "1.0.0", ShortestPath: true, ExcludeBundles: []string{"foo.v1.1.0"}},
+					{Name: "bar", SelectedBundles: []SelectedBundle{{Name: "bar.v1.0.0"}}, ExcludeChannels: []string{"beta"}, SkipDeprecated: true},
+					{Name: "baz", Channels: []Channel{{Name: "stable", ShortestPath: true}}},
+				},
+			},
+			expected: &configv1alpha1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{APIVersion: configv1alpha1.FilterAPIVersion, Kind: configv1alpha1.FilterKind},
+				Packages: []configv1alpha1.Package{
+					{Name: "foo"},
+					{Name: "bar"},
+					{Name: "baz", Channels: []configv1alpha1.Channel{{Name: "stable"}}},
+				},
+			},
+			expectedFields: []string{
+				"selectors", "excludePackages", "skipDeprecated",
+				"packages[0].minVersion", "packages[0].shortestPath", "packages[0].excludeBundles",
+				"packages[1].bundles", "packages[1].excludeChannels", "packages[1].skipDeprecated",
+				"packages[2].channels[0].shortestPath",
+			},
+		},
+		{
+			name: "WHEN packages and channels keep their heads THEN Reports them",
+			config: &FilterConfiguration{Packages: []Package{
+				{Name: "foo"},
+				{Name: "bar", Channels: []Channel{{Name: "stable"}, {Name: "fast", VersionRange: ">=1.0.0"}}},
+				{Name: "baz", ShortestPath: true, Channels: []Channel{{Name: "stable"}}},
+			}},
+			expected: &configv1alpha1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{APIVersion: configv1alpha1.FilterAPIVersion, Kind: configv1alpha1.FilterKind},
+				Packages: []configv1alpha1.Package{
+					{Name: "foo"},
+					{Name: "bar", Channels: []configv1alpha1.Channel{{Name: "stable"}, {Name: "fast", VersionRange: ">=1.0.0"}}},
+					{Name: "baz", Channels: []configv1alpha1.Channel{{Name: "stable"}}},
+				},
+			},
+			expectedFields: []string{"packages[0]", "packages[1].channels[0]", "packages[2].shortestPath"},
+		},
+		{
+			name:   "WHEN no package THEN Reports packages",
+			config: &FilterConfiguration{},
+			expected: &configv1alpha1.FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{APIVersion: configv1alpha1.FilterAPIVersion, Kind: configv1alpha1.FilterKind},
+			},
+			expectedFields: []string{"packages"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ConvertToConfigV1alpha1(tt.config)
+			assert.Equal(t, tt.expected, actual)
+			if len(tt.expectedFields) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			var conversionErr *ConversionError
+			require.ErrorAs(t, err, &conversionErr)
+			assert.Equal(t, configv1alpha1.FilterAPIVersion, conversionErr.APIVersion)
+			assert.Equal(t, tt.expectedFields, conversionErr.Fields)
+		})
+	}
+}
+
+func TestLoadAnyFilterConfiguration(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		expected    *FilterConfiguration
+		expectedErr string
+	}{
+		{
+			name:     "WHEN config/v1alpha1 THEN Converts the configuration",
+			path:     "testdata/configs/valid_configv1alpha1.yaml",
+			expected: convertedTestConfig(),
+		},
+		{
+			name: "WHEN mirror-config/v1alpha1 THEN Loads the configuration",
+			path: "testdata/configs/valid.yaml",
+			expected: func() *FilterConfiguration {
+				cfg := convertedTestConfig()
+				cfg.DefaultChannelPolicy = ""
+				return cfg
+			}(),
+		},
+		{
+			name:        "WHEN unknown API version THEN Returns error",
+			path:        "testdata/configs/invalid_apiversion.yaml",
+			expectedErr: "unexpected API version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(tt.path)
+			require.NoError(t, err)
+			actual, err := LoadAnyFilterConfiguration(bytes.NewReader(data))
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+	t.Run("WHEN config/v1alpha1 is invalid THEN Returns its validation error", func(t *testing.T) {
+		_, err := LoadAnyFilterConfiguration(bytes.NewReader([]byte("apiVersion: olm.operatorframework.io/v1alpha1\nkind: FilterConfiguration\n")))
+		assert.EqualError(t, err, "at least one package must be specified")
+	})
+}
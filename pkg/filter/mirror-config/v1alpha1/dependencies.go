@@ -0,0 +1,395 @@
+package v1alpha1
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
+)
+
+type bundleRef struct {
+	Package string
+	Name    string
+}
+
+// dependencyResolver completes a filtered catalog with the bundles of the unfiltered catalog that provide
+// the packages (olm.package.required) and GVKs (olm.gvk.required) required by the kept bundles.
+type dependencyResolver struct {
+	// full is the index of the unfiltered catalog
+	full         operatorIndex
+	deprecations []declcfg.Deprecation
+	gvkProviders map[property.GVK][]bundleRef
+	// defaultHeads caches the head of the default channel of each package
+	defaultHeads map[string]string
+
+	fbc *declcfg.DeclarativeConfig
+	// kept holds the bundles of fbc, as well as the dependencies that are about to be added to it
+	kept        map[string]sets.Set[string]
+	keptGVKs    sets.Set[property.GVK]
+	touchedPkgs sets.Set[string]
+
+	log    *logrus.Entry
+	report *reportBuilder
+}
+
+func newDependencyResolver(full operatorIndex, deprecations []declcfg.Deprecation, log *logrus.Entry, report *reportBuilder) (*dependencyResolver, error) {
+	r := &dependencyResolver{
+		full:         full,
+		deprecations: deprecations,
+		gvkProviders: make(map[property.GVK][]bundleRef),
+		defaultHeads: make(map[string]string),
+		log:          log,
+		report:       report,
+	}
+	for pkg, bundles := range full.BundlesByPkgAndName {
+		for name, b := range bundles {
+			props, err := property.Parse(b.Properties)
+			if err != nil {
+				return nil, fmt.Errorf("package %q bundle %q has invalid properties: %v", pkg, name, err)
+			}
+			for _, gvk := range props.GVKs {
+				r.gvkProviders[gvk] = append(r.gvkProviders[gvk], bundleRef{Package: pkg, Name: name})
+			}
+		}
+	}
+	return r, nil
+}
+
+// resolve adds the missing dependencies of the bundles of fbc, and the dependencies of these dependencies,
+// until every requirement that the unfiltered catalog can satisfy is satisfied.
+// Bundles are placed in their channels along with the bundles needed to preserve a single channel head.
+func (r *dependencyResolver) resolve(fbc *declcfg.DeclarativeConfig) error {
+	r.fbc = fbc
+	r.kept = map[string]sets.Set[string]{}
+	r.keptGVKs = sets.New[property.GVK]()
+	r.touchedPkgs = sets.New[string]()
+	queue := []bundleRef{}
+	for _, b := range fbc.Bundles {
+		if err := r.keep(b.Package, b.Name); err != nil {
+			return err
+		}
+		queue = append(queue, bundleRef{Package: b.Package, Name: b.Name})
+	}
+
+	for len(queue) > 0 {
+		needed := map[string]sets.Set[string]{}
+		for _, ref := range queue {
+			deps, err := r.missingDependencies(ref)
+			if err != nil {
+				return err
+			}
+			for _, dep := range deps {
+				if _, ok := needed[dep.Package]; !ok {
+					needed[dep.Package] = sets.New[string]()
+				}
+				needed[dep.Package].Insert(dep.Name)
+			}
+		}
+		queue = nil
+		for _, pkg := range sets.List(sets.KeySet(needed)) {
+			added, err := r.place(pkg, needed[pkg])
+			if err != nil {
+				return err
+			}
+			queue = append(queue, added...)
+		}
+	}
+
+	r.restoreDeprecations()
+	slices.SortFunc(fbc.Bundles, compareBundles)
+	return nil
+}
+
+// missingDependencies returns the bundles to add to the catalog in order to satisfy the requirements of ref,
+// and marks them as kept.
+func (r *dependencyResolver) missingDependencies(ref bundleRef) ([]bundleRef, error) {
+	props, err := property.Parse(r.full.BundlesByPkgAndName[ref.Package][ref.Name].Properties)
+	if err != nil {
+		return nil, fmt.Errorf("package %q bundle %q has invalid properties: %v", ref.Package, ref.Name, err)
+	}
+	var deps []bundleRef
+	for _, required := range props.PackagesRequired {
+		requirement := fmt.Sprintf("%s %s %q", property.TypePackageRequired, required.PackageName, required.VersionRange)
+		versionRange, err := mmsemver.NewConstraint(required.VersionRange)
+		if err != nil {
+			return nil, fmt.Errorf("package %q bundle %q has invalid requirement %s: %v", ref.Package, ref.Name, requirement, err)
+		}
+		inRange := func(name string) bool {
+			v := r.full.BundleVersionsByPkgAndName[required.PackageName][name]
+			return v != nil && versionRange.Check(v)
+		}
+		if slices.ContainsFunc(sets.List(r.kept[required.PackageName]), inRange) {
+			continue
+		}
+		var candidates []bundleRef
+		for name := range r.full.BundlesByPkgAndName[required.PackageName] {
+			if inRange(name) {
+				candidates = append(candidates, bundleRef{Package: required.PackageName, Name: name})
+			}
+		}
+		dep, err := r.addDependency(ref, requirement, candidates)
+		if err != nil {
+			return nil, err
+		}
+		if dep != nil {
+			deps = append(deps, *dep)
+		}
+	}
+	for _, required := range props.GVKsRequired {
+		gvk := property.GVK(required)
+		if r.keptGVKs.Has(gvk) {
+			continue
+		}
+		requirement := fmt.Sprintf("%s %s/%s %s", property.TypeGVKRequired, gvk.Group, gvk.Version, gvk.Kind)
+		dep, err := r.addDependency(ref, requirement, r.gvkProviders[gvk])
+		if err != nil {
+			return nil, err
+		}
+		if dep != nil {
+			deps = append(deps, *dep)
+		}
+	}
+	return deps, nil
+}
+
+// addDependency picks the best candidate to satisfy requirement, marks it as kept and reports it.
+// A requirement without candidate is only logged: it may be satisfied by another catalog on the cluster.
+func (r *dependencyResolver) addDependency(requiredBy bundleRef, requirement string, candidates []bundleRef) (*bundleRef, error) {
+	if len(candidates) == 0 {
+		r.log.Warnf("package %q bundle %q requires %s, which no bundle of the catalog provides", requiredBy.Package, requiredBy.Name, requirement)
+		return nil, nil
+	}
+	dep := slices.MinFunc(candidates, r.compareCandidates)
+	r.log.Infof("including bundle %q of package %q: it satisfies %s, required by bundle %q", dep.Name, dep.Package, requirement, requiredBy.Name)
+	r.report.dependency(DependencyReport{
+		Package:           dep.Package,
+		Bundle:            dep.Name,
+		RequiredByPackage: requiredBy.Package,
+		RequiredByBundle:  requiredBy.Name,
+		Requirement:       requirement,
+	})
+	if err := r.keep(dep.Package, dep.Name); err != nil {
+		return nil, err
+	}
+	return &dep, nil
+}
+
+// compareCandidates orders the candidates satisfying a requirement from the best to the worst:
+// bundles of packages that are already kept first, then the heads of default channels, then the highest versions.
+func (r *dependencyResolver) compareCandidates(a, b bundleRef) int {
+	rank := func(ref bundleRef) int {
+		rank := 0
+		if _, ok := r.kept[ref.Package]; ok {
+			rank -= 2
+		}
+		if r.isDefaultChannelHead(ref) {
+			rank--
+		}
+		return rank
+	}
+	if c := cmp.Compare(rank(a), rank(b)); c != 0 {
+		return c
+	}
+	va, vb := r.full.BundleVersionsByPkgAndName[a.Package][a.Name], r.full.BundleVersionsByPkgAndName[b.Package][b.Name]
+	if va != nil && vb != nil {
+		if c := vb.Compare(va); c != 0 {
+			return c
+		}
+	}
+	return cmp.Or(strings.Compare(a.Package, b.Package), strings.Compare(a.Name, b.Name))
+}
+
+func (r *dependencyResolver) isDefaultChannelHead(ref bundleRef) bool {
+	head, ok := r.defaultHeads[ref.Package]
+	if !ok {
+		c, err := graph.NewChannel(r.fullChannel(ref.Package, r.full.Packages[ref.Package].DefaultChannel))
+		if err == nil {
+			head = c.Head.Name
+		}
+		r.defaultHeads[ref.Package] = head
+	}
+	return head == ref.Name
+}
+
+func (r *dependencyResolver) keep(pkg, name string) error {
+	if _, ok := r.kept[pkg]; !ok {
+		r.kept[pkg] = sets.New[string]()
+	}
+	r.kept[pkg].Insert(name)
+	props, err := property.Parse(r.full.BundlesByPkgAndName[pkg][name].Properties)
+	if err != nil {
+		return fmt.Errorf("package %q bundle %q has invalid properties: %v", pkg, name, err)
+	}
+	r.keptGVKs.Insert(props.GVKs...)
+	return nil
+}
+
+// place adds the named bundles of pkg to the catalog, in the channels of the unfiltered catalog that contain them.
+// Existing channels of the filtered catalog are extended, and missing channels are added, starting with the
+// default channel. It returns the bundles that were added, including the bundles required to keep a single head.
+func (r *dependencyResolver) place(pkg string, names sets.Set[string]) ([]bundleRef, error) {
+	r.touchedPkgs.Insert(pkg)
+	if !slices.ContainsFunc(r.fbc.Packages, func(p declcfg.Package) bool { return p.Name == pkg }) {
+		r.fbc.Packages = append(r.fbc.Packages, r.full.Packages[pkg])
+		r.report.pkg(pkg, Kept, ReasonDependency)
+	}
+
+	uncovered := names.Clone()
+	for _, ch := range r.fbc.Channels {
+		if ch.Package != pkg {
+			continue
+		}
+		for _, e := range ch.Entries {
+			uncovered.Delete(e.Name)
+		}
+	}
+
+	var added []bundleRef
+	var placedChannels []string
+	for _, chName := range r.channelsOf(pkg) {
+		if uncovered.Len() == 0 {
+			break
+		}
+		fullEntries := r.full.ChannelEntries[pkg][chName]
+		want := sets.New[string]()
+		for name := range uncovered {
+			if _, ok := fullEntries[name]; ok {
+				want.Insert(name)
+			}
+		}
+		if want.Len() == 0 {
+			continue
+		}
+		chIndex := slices.IndexFunc(r.fbc.Channels, func(ch declcfg.Channel) bool { return ch.Package == pkg && ch.Name == chName })
+		if chIndex >= 0 {
+			for _, e := range r.fbc.Channels[chIndex].Entries {
+				want.Insert(e.Name)
+			}
+		}
+		filteringChannel, err := graph.NewChannel(r.fullChannel(pkg, chName))
+		if err != nil {
+			return nil, fmt.Errorf("package %q channel %q unable to add dependencies: %v", pkg, chName, err)
+		}
+		keepEntries := filterByEntries(filteringChannel, want, r.log)
+		ch := declcfg.Channel{Schema: declcfg.SchemaChannel, Package: pkg, Name: chName}
+		if chIndex >= 0 {
+			ch = r.fbc.Channels[chIndex]
+		}
+		ch.Entries = nil
+		for _, name := range sets.List(keepEntries) {
+			ch.Entries = append(ch.Entries, fullEntries[name])
+		}
+		if chIndex >= 0 {
+			r.fbc.Channels[chIndex] = ch
+		} else {
+			r.fbc.Channels = append(r.fbc.Channels, ch)
+		}
+		r.report.channel(pkg, chName, Kept, ReasonDependency)
+		placedChannels = append(placedChannels, chName)
+
+		for _, name := range sets.List(keepEntries) {
+			version := r.full.BundleVersionsByPkgAndName[pkg][name]
+			if !slices.ContainsFunc(r.fbc.Bundles, func(b declcfg.Bundle) bool { return b.Package == pkg && b.Name == name }) {
+				r.fbc.Bundles = append(r.fbc.Bundles, r.full.BundlesByPkgAndName[pkg][name])
+				r.report.bundle(pkg, name, version, Kept, ReasonDependency)
+				if !r.kept[pkg].Has(name) {
+					// kept to preserve a single head, its own dependencies must be resolved too
+					if err := r.keep(pkg, name); err != nil {
+						return nil, err
+					}
+					added = append(added, bundleRef{Package: pkg, Name: name})
+				} else if names.Has(name) {
+					added = append(added, bundleRef{Package: pkg, Name: name})
+				}
+			}
+			r.report.entry(pkg, chName, name, version, Kept, ReasonDependency)
+		}
+		uncovered = uncovered.Difference(keepEntries)
+	}
+	if uncovered.Len() > 0 {
+		return nil, fmt.Errorf("package %q: unable to add dependencies %v, they are not entries of any channel", pkg, sets.List(uncovered))
+	}
+
+	for i, p := range r.fbc.Packages {
+		if p.Name != pkg || len(placedChannels) == 0 {
+			continue
+		}
+		if !slices.ContainsFunc(r.fbc.Channels, func(ch declcfg.Channel) bool { return ch.Package == pkg && ch.Name == p.DefaultChannel }) {
+			r.log.Infof("package %q: default channel %q does not contain any required bundle, using channel %q instead", pkg, p.DefaultChannel, placedChannels[0])
+			r.fbc.Packages[i].DefaultChannel = placedChannels[0]
+		}
+	}
+	return added, nil
+}
+
+// channelsOf returns the channel names of pkg in the unfiltered catalog, default channel first.
+func (r *dependencyResolver) channelsOf(pkg string) []string {
+	defaultChannel := r.full.Packages[pkg].DefaultChannel
+	channels := sets.List(r.full.ChannelNames[pkg])
+	slices.SortStableFunc(channels, func(a, b string) int {
+		switch {
+		case a == defaultChannel:
+			return -1
+		case b == defaultChannel:
+			return 1
+		}
+		return 0
+	})
+	return channels
+}
+
+// fullChannel rebuilds a channel of the unfiltered catalog from its index.
+func (r *dependencyResolver) fullChannel(pkg, name string) declcfg.Channel {
+	ch := declcfg.Channel{Schema: declcfg.SchemaChannel, Package: pkg, Name: name}
+	entries := r.full.ChannelEntries[pkg][name]
+	for _, entryName := range sets.List(sets.KeySet(entries)) {
+		ch.Entries = append(ch.Entries, entries[entryName])
+	}
+	return ch
+}
+
+// restoreDeprecations sets the deprecations of the packages that received dependencies
+// to the deprecations of the unfiltered catalog that refer to kept objects.
+func (r *dependencyResolver) restoreDeprecations() {
+	r.fbc.Deprecations = slices.DeleteFunc(r.fbc.Deprecations, func(d declcfg.Deprecation) bool {
+		return r.touchedPkgs.Has(d.Package)
+	})
+	for _, d := range r.deprecations {
+		if !r.touchedPkgs.Has(d.Package) {
+			continue
+		}
+		d.Entries = slices.DeleteFunc(slices.Clone(d.Entries), func(e declcfg.DeprecationEntry) bool {
+			switch e.Reference.Schema {
+			case declcfg.SchemaBundle:
+				return !r.kept[d.Package].Has(e.Reference.Name)
+			case declcfg.SchemaChannel:
+				return !slices.ContainsFunc(r.fbc.Channels, func(ch declcfg.Channel) bool {
+					return ch.Package == d.Package && ch.Name == e.Reference.Name
+				})
+			}
+			return false
+		})
+		r.fbc.Deprecations = append(r.fbc.Deprecations, d)
+	}
+}
+
+// cloneDeprecations copies deprecations, so that filtering does not alter the entries of the unfiltered catalog.
+func cloneDeprecations(deprecations []declcfg.Deprecation) []declcfg.Deprecation {
+	if deprecations == nil {
+		return nil
+	}
+	clone := make([]declcfg.Deprecation, 0, len(deprecations))
+	for _, d := range deprecations {
+		d.Entries = slices.Clone(d.Entries)
+		clone = append(clone, d)
+	}
+	return clone
+}
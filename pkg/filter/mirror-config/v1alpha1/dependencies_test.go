@@ -0,0 +1,160 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+
+	filter_package "github.com/sherine-k/catalog-filter/pkg/filter"
+)
+
+func dependenciesTestCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Name: "foo", DefaultChannel: "stable"},
+			{Name: "bar", DefaultChannel: "stable"},
+			{Name: "baz", DefaultChannel: "stable"},
+			{Name: "qux", DefaultChannel: "stable"},
+			{Name: "other", DefaultChannel: "stable"},
+		},
+		Channels: []declcfg.Channel{
+			{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v2", Replaces: "foo.v1"},
+				{Name: "foo.v1"},
+			}},
+			{Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{
+				{Name: "bar.v2.0.0", Replaces: "bar.v1.1.0"},
+				{Name: "bar.v1.1.0", Replaces: "bar.v1.0.0"},
+				{Name: "bar.v1.0.0"},
+			}},
+			{Name: "stable", Package: "baz", Entries: []declcfg.ChannelEntry{{Name: "baz.v1"}}},
+			{Name: "stable", Package: "qux", Entries: []declcfg.ChannelEntry{{Name: "qux.v1"}}},
+			{Name: "stable", Package: "other", Entries: []declcfg.ChannelEntry{{Name: "other.v1"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			{Name: "foo.v1", Package: "foo", Properties: propertiesForBundle("foo", "1.0.0")},
+			{Name: "foo.v2", Package: "foo", Properties: append(propertiesForBundle("foo", "2.0.0"),
+				property.MustBuildPackageRequired("bar", ">=1.0.0 <2.0.0"),
+				property.MustBuildGVKRequired("baz.io", "v1", "Baz"),
+			)},
+			{Name: "bar.v1.0.0", Package: "bar", Properties: propertiesForBundle("bar", "1.0.0")},
+			{Name: "bar.v1.1.0", Package: "bar", Properties: propertiesForBundle("bar", "1.1.0")},
+			{Name: "bar.v2.0.0", Package: "bar", Properties: propertiesForBundle("bar", "2.0.0")},
+			{Name: "baz.v1", Package: "baz", Properties: append(propertiesForBundle("baz", "1.0.0"),
+				property.MustBuildGVK("baz.io", "v1", "Baz"),
+				property.MustBuildPackageRequired("qux", ">=1.0.0"),
+			)},
+			{Name: "qux.v1", Package: "qux", Properties: propertiesForBundle("qux", "1.0.0")},
+			{Name: "other.v1", Package: "other", Properties: append(propertiesForBundle("other", "0.1.0"),
+				property.MustBuildGVK("baz.io", "v1", "Baz"),
+			)},
+		},
+		Deprecations: []declcfg.Deprecation{
+			{Package: "bar", Entries: []declcfg.DeprecationEntry{
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "bar.v1.0.0"}},
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "bar.v1.1.0"}},
+			}},
+		},
+	}
+}
+
+func bundleNamesOf(fbc *declcfg.DeclarativeConfig) []string {
+	names := []string{}
+	for _, b := range fbc.Bundles {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+func TestFilter_FilterCatalog_WithDependencies(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    FilterConfiguration
+		options   []FilterOption
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, FilterReport)
+	}{
+		{
+			name:    "WHEN dependencies are resolved THEN returns the minimal set of required bundles",
+			config:  FilterConfiguration{Packages: []Package{{Name: "foo"}}},
+			options: []FilterOption{WithDependencies(true)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport) {
+				assert.Equal(t, []string{"bar.v1.1.0", "baz.v1", "foo.v2", "qux.v1"}, bundleNamesOf(out))
+				assert.Len(t, out.Packages, 4)
+				assert.Contains(t, out.Channels, declcfg.Channel{Schema: declcfg.SchemaChannel, Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{
+					{Name: "bar.v1.1.0", Replaces: "bar.v1.0.0"},
+				}})
+				assert.Equal(t, []declcfg.Deprecation{{Package: "bar", Entries: []declcfg.DeprecationEntry{
+					{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "bar.v1.1.0"}},
+				}}}, out.Deprecations)
+				assert.Equal(t, []DependencyReport{
+					{Package: "bar", Bundle: "bar.v1.1.0", RequiredByPackage: "foo", RequiredByBundle: "foo.v2", Requirement: `olm.package.required bar ">=1.0.0 <2.0.0"`},
+					{Package: "baz", Bundle: "baz.v1", RequiredByPackage: "foo", RequiredByBundle: "foo.v2", Requirement: "olm.gvk.required baz.io/v1 Baz"},
+					{Package: "qux", Bundle: "qux.v1", RequiredByPackage: "baz", RequiredByBundle: "baz.v1", Requirement: `olm.package.required qux ">=1.0.0"`},
+				}, report.Dependencies)
+				bar, ok := report.Package("bar")
+				require.True(t, ok)
+				assert.Equal(t, Kept, bar.Decision)
+				assert.Equal(t, ReasonDependency, bar.Reason)
+			},
+		},
+		{
+			name: "WHEN dependency belongs to a kept package THEN extends its channel with a single head",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "foo"},
+				{Name: "bar"},
+				{Name: "baz"},
+				{Name: "qux"},
+			}},
+			options: []FilterOption{WithDependencies(true)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport) {
+				assert.Equal(t, []string{"bar.v1.1.0", "bar.v2.0.0", "baz.v1", "foo.v2", "qux.v1"}, bundleNamesOf(out))
+				assert.Contains(t, out.Channels, declcfg.Channel{Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{
+					{Name: "bar.v1.1.0", Replaces: "bar.v1.0.0"},
+					{Name: "bar.v2.0.0", Replaces: "bar.v1.1.0"},
+				}})
+				bar, _ := report.Package("bar")
+				assert.Contains(t, bar.Bundles, BundleReport{Name: "bar.v1.1.0", Version: "1.1.0", Decision: Kept, Reason: ReasonDependency})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := FilterReport{}
+			f := NewMirrorFilter(tt.config, append(tt.options, WithReport(&report))...)
+			out, err := f.FilterCatalog(context.Background(), dependenciesTestCatalog())
+			require.NoError(t, err)
+			tt.assertion(t, out, report)
+		})
+	}
+}
+
+func TestFilterFS_WithDependencies(t *testing.T) {
+	root := catalogFS(t, dependenciesTestCatalog())
+	f := NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo"}}}, WithDependencies(true))
+	out, err := filter_package.FilterFS(context.Background(), root, f)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar.v1.1.0", "baz.v1", "foo.v2", "qux.v1"}, bundleNamesOf(out))
+}
+
+func TestFilter_FilterCatalog_WithoutDependencies(t *testing.T) {
+	f := NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo"}}})
+
+	out, err := f.FilterCatalog(context.Background(), dependenciesTestCatalog())
+	assert.Nil(t, out)
+	assert.ErrorContains(t, err, `package "foo" bundle "foo.v2": required package "bar" is not in the filtered catalog`)
+}
+
+func TestFilter_FilterCatalog_WithDependencies_Unresolvable(t *testing.T) {
+	fbc := dependenciesTestCatalog()
+	fbc.Bundles[1].Properties = append(fbc.Bundles[1].Properties, property.MustBuildPackageRequired("missing", ">=1.0.0"))
+	f := NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo"}}}, WithDependencies(true))
+
+	out, err := f.FilterCatalog(context.Background(), fbc)
+	assert.Nil(t, out)
+	assert.ErrorContains(t, err, `package "foo" bundle "foo.v2": required package "missing" is not in the filtered catalog`)
+}
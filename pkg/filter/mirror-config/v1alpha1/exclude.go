@@ -0,0 +1,180 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
+)
+
+// excludedBundle is a bundle removed by exclude, along with the reason it was removed for.
+type excludedBundle struct {
+	declcfg.Bundle
+	reason string
+}
+
+func (f *mirrorFilter) hasExclusions() bool {
+	if len(f.excludePackages) > 0 || f.skipDeprecated {
+		return true
+	}
+	for _, pkg := range f.pkgConfigs {
+		if len(pkg.ExcludeChannels) > 0 || len(pkg.ExcludeBundles) > 0 || pkg.SkipDeprecated {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsPackages reports whether the configuration restricts the catalog to some of its packages.
+func (f *mirrorFilter) selectsPackages() bool {
+	if len(f.selectors) > 0 {
+		return true
+	}
+	for _, pkg := range f.pkgConfigs {
+		if !pkg.isExclusionOnly() {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *mirrorFilter) skipsDeprecated(pkg string) bool {
+	return f.skipDeprecated || f.pkgConfigs[pkg].SkipDeprecated
+}
+
+// deprecatedReferences returns, by package, the channels and bundles marked as deprecated by the deprecations
+// of fbc, for the packages whose deprecated channels and bundles are skipped.
+func (f *mirrorFilter) deprecatedReferences(fbc *declcfg.DeclarativeConfig) (map[string]sets.Set[string], map[string]sets.Set[string]) {
+	channels := map[string]sets.Set[string]{}
+	bundles := map[string]sets.Set[string]{}
+	for _, d := range fbc.Deprecations {
+		if !f.skipsDeprecated(d.Package) {
+			continue
+		}
+		for _, e := range d.Entries {
+			switch e.Reference.Schema {
+			case declcfg.SchemaChannel:
+				if _, ok := channels[d.Package]; !ok {
+					channels[d.Package] = sets.New[string]()
+				}
+				channels[d.Package].Insert(e.Reference.Name)
+			case declcfg.SchemaBundle:
+				if _, ok := bundles[d.Package]; !ok {
+					bundles[d.Package] = sets.New[string]()
+				}
+				bundles[d.Package].Insert(e.Reference.Name)
+			}
+		}
+	}
+	return channels, bundles
+}
+
+// exclude returns a copy of fbc without the excluded packages, and without the channels and bundles excluded
+// from each package or skipped because they are deprecated, along with the removed bundles. The channels that
+// had removed entries are repaired by graph.RemoveEntries and must still have a single head and no cycle. Channels
+// left empty are removed, and deprecations are cleaned the same way filterDeprecations does. Skipping the
+// deprecated channels and bundles must leave the default channel of the package in place.
+func (f *mirrorFilter) exclude(fbc *declcfg.DeclarativeConfig, report *reportBuilder) (*declcfg.DeclarativeConfig, []excludedBundle, error) {
+	excludedChannels := map[string]sets.Set[string]{}
+	excludedBundles := map[string]sets.Set[string]{}
+	for name, pkg := range f.pkgConfigs {
+		excludedChannels[name] = sets.New(pkg.ExcludeChannels...)
+		excludedBundles[name] = sets.New(pkg.ExcludeBundles...)
+	}
+	deprecatedChannels, deprecatedBundles := f.deprecatedReferences(fbc)
+	removedBundles := maps.Clone(excludedBundles)
+	for name, bundles := range deprecatedBundles {
+		removedBundles[name] = bundles.Union(excludedBundles[name])
+	}
+
+	defaultChannels := map[string]string{}
+	out := &declcfg.DeclarativeConfig{}
+	for _, pkg := range fbc.Packages {
+		if f.excludePackages.Has(pkg.Name) {
+			report.pkg(pkg.Name, Removed, ReasonPackageExcluded)
+			continue
+		}
+		defaultChannels[pkg.Name] = pkg.DefaultChannel
+		if f.pkgConfigs[pkg.Name].DefaultChannel != "" {
+			defaultChannels[pkg.Name] = f.pkgConfigs[pkg.Name].DefaultChannel
+		}
+		out.Packages = append(out.Packages, pkg)
+	}
+	for _, ch := range fbc.Channels {
+		if f.excludePackages.Has(ch.Package) {
+			continue
+		}
+		if excludedChannels[ch.Package].Has(ch.Name) {
+			report.channel(ch.Package, ch.Name, Removed, ReasonChannelExcluded)
+			continue
+		}
+		isDefault := ch.Name == defaultChannels[ch.Package]
+		if deprecatedChannels[ch.Package].Has(ch.Name) {
+			if isDefault {
+				return nil, nil, fmt.Errorf("package %q channel %q is deprecated and cannot be skipped: it is the default channel, a defaultChannel that is not deprecated must be configured", ch.Package, ch.Name)
+			}
+			report.channel(ch.Package, ch.Name, Removed, ReasonChannelDeprecated)
+			continue
+		}
+		if !slices.ContainsFunc(ch.Entries, func(e declcfg.ChannelEntry) bool { return removedBundles[ch.Package].Has(e.Name) }) {
+			out.Channels = append(out.Channels, ch)
+			continue
+		}
+		ch.Entries = graph.RemoveEntries(ch.Entries, removedBundles[ch.Package])
+		if len(ch.Entries) == 0 {
+			if isDefault && f.skipsDeprecated(ch.Package) {
+				return nil, nil, fmt.Errorf("package %q channel %q cannot be emptied by skipping deprecated bundles: it is the default channel, a defaultChannel with bundles that are not deprecated must be configured", ch.Package, ch.Name)
+			}
+			report.channel(ch.Package, ch.Name, Removed, ReasonEmptyChannel)
+			continue
+		}
+		if _, err := f.channelHeads(ch); err != nil {
+			return nil, nil, fmt.Errorf("package %q channel %q is invalid after excluding bundles: %v", ch.Package, ch.Name, err)
+		}
+		out.Channels = append(out.Channels, ch)
+	}
+
+	var removed []excludedBundle
+	for _, b := range fbc.Bundles {
+		if f.excludePackages.Has(b.Package) {
+			continue
+		}
+		switch {
+		case excludedBundles[b.Package].Has(b.Name):
+			removed = append(removed, excludedBundle{Bundle: b, reason: ReasonBundleExcluded})
+		case deprecatedBundles[b.Package].Has(b.Name):
+			removed = append(removed, excludedBundle{Bundle: b, reason: ReasonBundleDeprecated})
+		default:
+			out.Bundles = append(out.Bundles, b)
+		}
+	}
+	for _, d := range fbc.Deprecations {
+		if f.excludePackages.Has(d.Package) {
+			continue
+		}
+		d.Entries = slices.Clone(d.Entries)
+		out.Deprecations = append(out.Deprecations, d)
+	}
+	for _, o := range fbc.Others {
+		if !f.excludePackages.Has(o.Package) {
+			out.Others = append(out.Others, o)
+		}
+	}
+
+	index, err := indexFromDeclCfg(out)
+	if err != nil {
+		return nil, nil, err
+	}
+	keptBundles := map[string]sets.Set[string]{}
+	for _, b := range out.Bundles {
+		if _, ok := keptBundles[b.Package]; !ok {
+			keptBundles[b.Package] = sets.New[string]()
+		}
+		keptBundles[b.Package].Insert(b.Name)
+	}
+	return filterDeprecations(out, index, keptBundles), removed, nil
+}
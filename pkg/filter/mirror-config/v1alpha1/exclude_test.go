@@ -0,0 +1,212 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func excludeTestCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Name: "foo", DefaultChannel: "stable"},
+			{Name: "bar", DefaultChannel: "stable"},
+		},
+		Channels: []declcfg.Channel{
+			{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v3", Replaces: "foo.v2"},
+				{Name: "foo.v2", Replaces: "foo.v1"},
+				{Name: "foo.v1"},
+			}},
+			{Name: "fast", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v3"}}},
+			{Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{{Name: "bar.v1"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			{Name: "foo.v1", Package: "foo", Properties: propertiesForBundle("foo", "1.0.0")},
+			{Name: "foo.v2", Package: "foo", Properties: propertiesForBundle("foo", "2.0.0")},
+			{Name: "foo.v3", Package: "foo", Properties: propertiesForBundle("foo", "3.0.0")},
+			{Name: "bar.v1", Package: "bar", Properties: propertiesForBundle("bar", "1.0.0")},
+		},
+		Deprecations: []declcfg.Deprecation{
+			{Package: "foo", Entries: []declcfg.DeprecationEntry{
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaChannel, Name: "fast"}},
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v1"}},
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v2"}},
+			}},
+			{Package: "bar", Entries: []declcfg.DeprecationEntry{
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaPackage}},
+			}},
+		},
+		Others: []declcfg.Meta{{Schema: "other", Package: "bar"}},
+	}
+}
+
+func TestFilter_FilterCatalog_WithExclusions(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    FilterConfiguration
+		options   []FilterOption
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, FilterReport, error)
+	}{
+		{
+			name:    "WHEN excluding a package THEN Returns the whole catalog but that package",
+			config:  FilterConfiguration{ExcludePackages: []string{"bar"}},
+			options: []FilterOption{InFull(true)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}}, out.Packages)
+				assert.Equal(t, []string{"foo.v1", "foo.v2", "foo.v3"}, bundleNamesOf(out))
+				assert.Len(t, out.Deprecations, 1)
+				assert.Empty(t, out.Others)
+				bar, _ := report.Package("bar")
+				assert.Equal(t, ReasonPackageExcluded, bar.Reason)
+			},
+		},
+		{
+			name: "WHEN only excluding channels and bundles THEN Returns the whole catalog with a repaired channel graph",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "foo", ExcludeChannels: []string{"fast"}, ExcludeBundles: []string{"foo.v2"}},
+			}},
+			options: []FilterOption{InFull(true)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Len(t, out.Packages, 2)
+				assert.Equal(t, []declcfg.Channel{
+					{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+						{Name: "foo.v3", Replaces: "foo.v1"},
+						{Name: "foo.v1"},
+					}},
+					{Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{{Name: "bar.v1"}}},
+				}, out.Channels)
+				assert.Equal(t, []string{"bar.v1", "foo.v1", "foo.v3"}, bundleNamesOf(out))
+				assert.Equal(t, []declcfg.DeprecationEntry{
+					{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v1"}},
+				}, out.Deprecations[0].Entries)
+				foo, _ := report.Package("foo")
+				fast, _ := foo.Channel("fast")
+				assert.Equal(t, ReasonChannelExcluded, fast.Reason)
+				assert.Contains(t, foo.Bundles, BundleReport{Name: "foo.v2", Version: "2.0.0", Decision: Removed, Reason: ReasonBundleExcluded})
+			},
+		},
+		{
+			name: "WHEN excluding bundles of a selected package THEN Returns only that package without them",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "foo", Channels: []Channel{{Name: "stable", MinVersion: "1.0.0"}}, ExcludeBundles: []string{"foo.v2"}},
+			}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []string{"foo.v1", "foo.v3"}, bundleNamesOf(out))
+			},
+		},
+		{
+			name: "WHEN excluding the default channel without configuring a new one THEN Returns error",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "foo", ExcludeChannels: []string{"stable"}},
+			}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				assert.Nil(t, out)
+				assert.ErrorContains(t, err, `invalid default channel configuration for package "foo": the default channel "stable" was filtered out`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := FilterReport{}
+			f := NewMirrorFilter(tt.config, append(tt.options, WithReport(&report))...)
+			in := excludeTestCatalog()
+			out, err := f.FilterCatalog(context.Background(), in)
+			tt.assertion(t, out, report, err)
+		})
+	}
+}
+
+func TestFilter_FilterCatalog_SkipDeprecated(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    FilterConfiguration
+		options   []FilterOption
+		catalog   func(*declcfg.DeclarativeConfig)
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, FilterReport, error)
+	}{
+		{
+			name:    "WHEN skipping deprecated objects of all packages THEN Returns the catalog without deprecated channels and bundles",
+			config:  FilterConfiguration{SkipDeprecated: true},
+			options: []FilterOption{InFull(true)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Len(t, out.Packages, 2)
+				assert.Equal(t, []declcfg.Channel{
+					{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v3"}}},
+					{Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{{Name: "bar.v1"}}},
+				}, out.Channels)
+				assert.Equal(t, []string{"bar.v1", "foo.v3"}, bundleNamesOf(out))
+				assert.Empty(t, out.Deprecations[0].Entries)
+				assert.Len(t, out.Deprecations[1].Entries, 1, "package deprecations are kept")
+				foo, _ := report.Package("foo")
+				fast, _ := foo.Channel("fast")
+				assert.Equal(t, ReasonChannelDeprecated, fast.Reason)
+				assert.Contains(t, foo.Bundles, BundleReport{Name: "foo.v2", Version: "2.0.0", Decision: Removed, Reason: ReasonBundleDeprecated})
+			},
+		},
+		{
+			name: "WHEN skipping deprecated objects of a selected package THEN Returns that package without them",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "foo", Channels: []Channel{{Name: "stable", MinVersion: "1.0.0"}}, SkipDeprecated: true},
+			}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}}, out.Packages)
+				assert.Equal(t, []string{"foo.v3"}, bundleNamesOf(out))
+			},
+		},
+		{
+			name: "WHEN skipping deprecated objects with exclusions only THEN Returns the whole catalog without them",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "bar", ExcludeChannels: []string{"fast"}, SkipDeprecated: true},
+			}},
+			options: []FilterOption{InFull(true)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []string{"bar.v1", "foo.v1", "foo.v2", "foo.v3"}, bundleNamesOf(out), "foo does not skip its deprecated bundles")
+			},
+		},
+		{
+			name: "WHEN the configured default channel is deprecated THEN Returns error",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "foo", DefaultChannel: "fast", SkipDeprecated: true},
+			}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				assert.Nil(t, out)
+				assert.EqualError(t, err, `package "foo" channel "fast" is deprecated and cannot be skipped: it is the default channel, a defaultChannel that is not deprecated must be configured`)
+			},
+		},
+		{
+			name:   "WHEN all bundles of the default channel are deprecated THEN Returns error",
+			config: FilterConfiguration{SkipDeprecated: true},
+			catalog: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Deprecations[0].Entries = append(fbc.Deprecations[0].Entries,
+					declcfg.DeprecationEntry{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v3"}})
+			},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				assert.Nil(t, out)
+				assert.EqualError(t, err, `package "foo" channel "stable" cannot be emptied by skipping deprecated bundles: it is the default channel, a defaultChannel with bundles that are not deprecated must be configured`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := FilterReport{}
+			f := NewMirrorFilter(tt.config, append(tt.options, WithReport(&report))...)
+			in := excludeTestCatalog()
+			if tt.catalog != nil {
+				tt.catalog(in)
+			}
+			out, err := f.FilterCatalog(context.Background(), in)
+			tt.assertion(t, out, report, err)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package v1alpha1
+
+import (
+	"slices"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/sherine-k/catalog-filter/pkg/filter"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
+)
+
+// channelHeads returns a channel for each head of ch that is filtered: all of them with
+// filter.MultipleHeadsPolicyKeepAll, and the single head of ch otherwise, which fails when ch has several heads.
+// With filter.MultipleHeadsPolicyKeepHighest, the other heads were already dropped by keepHighestHeads.
+func (f *mirrorFilter) channelHeads(ch declcfg.Channel) ([]*graph.Channel, error) {
+	if f.opts.MultipleHeads == filter.MultipleHeadsPolicyKeepAll {
+		return graph.NewChannelHeads(ch)
+	}
+	c, err := graph.NewChannel(ch)
+	if err != nil {
+		return nil, err
+	}
+	return []*graph.Channel{c}, nil
+}
+
+// keepHighestHeads returns a copy of fbc in which the channels that have several heads only keep the head
+// with the highest version, along with the entries it upgrades from, as graph.KeepHighestHead does.
+func keepHighestHeads(fbc *declcfg.DeclarativeConfig, log *logrus.Entry) *declcfg.DeclarativeConfig {
+	versions := map[string]map[string]*mmsemver.Version{}
+	for _, b := range fbc.Bundles {
+		if _, ok := versions[b.Package]; !ok {
+			versions[b.Package] = map[string]*mmsemver.Version{}
+		}
+		if v, err := getBundleVersion(b); err == nil {
+			versions[b.Package][b.Name] = v
+		}
+	}
+
+	out := *fbc
+	out.Channels = slices.Clone(fbc.Channels)
+	for i, ch := range out.Channels {
+		out.Channels[i].Entries = graph.KeepHighestHead(ch, versions[ch.Package], log)
+	}
+	return &out
+}
+
+// filterHeadsByVersionRange applies graph.Channel.FilterByVersionRange to each of heads, and returns all the
+// entries kept.
+func filterHeadsByVersionRange(heads []*graph.Channel, versionRange *mmsemver.Constraints, versionMap map[string]*mmsemver.Version, log *logrus.Entry) sets.Set[string] {
+	keepEntries := sets.New[string]()
+	for _, c := range heads {
+		keepEntries = keepEntries.Union(c.FilterByVersionRange(versionRange, versionMap, log))
+	}
+	return keepEntries
+}
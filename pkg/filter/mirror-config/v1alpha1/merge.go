@@ -0,0 +1,292 @@
+package v1alpha1
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/sherine-k/catalog-filter/pkg/filter"
+)
+
+// ConfigurationSource is a named stream of FilterConfiguration YAML documents, separated by `---`, such as a file.
+type ConfigurationSource struct {
+	// Name identifies the source, typically its file path, in the errors.
+	Name   string
+	Reader io.Reader
+}
+
+// configDocument is a FilterConfiguration, along with the source and the index of the YAML document it was read from.
+type configDocument struct {
+	source string
+	index  int
+	config *FilterConfiguration
+}
+
+func (d configDocument) String() string {
+	return fmt.Sprintf("%q document [%d]", d.source, d.index)
+}
+
+// LoadFilterConfigurationFiles reads the files at paths and merges all of their documents, like
+// LoadFilterConfigurations.
+func LoadFilterConfigurationFiles(paths ...string) (*FilterConfiguration, error) {
+	sources, err := fileSources(paths)
+	if err != nil {
+		return nil, err
+	}
+	return LoadFilterConfigurations(sources...)
+}
+
+// LoadFilterConfigurationFilesStrict reads the files at paths and merges all of their documents, like
+// LoadFilterConfigurationsStrict.
+func LoadFilterConfigurationFilesStrict(paths ...string) (*FilterConfiguration, error) {
+	sources, err := fileSources(paths)
+	if err != nil {
+		return nil, err
+	}
+	return LoadFilterConfigurationsStrict(sources...)
+}
+
+func fileSources(paths []string) ([]ConfigurationSource, error) {
+	sources := make([]ConfigurationSource, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, ConfigurationSource{Name: path, Reader: bytes.NewReader(data)})
+	}
+	return sources, nil
+}
+
+// LoadFilterConfigurations reads all the YAML documents of the sources, validates each of them and merges
+// them into one effective FilterConfiguration. Documents of the config/v1alpha1 API are converted first, like
+// LoadAnyFilterConfiguration does. Merging follows these rules:
+//   - Packages with the same name are merged into one. Their Channels with the same name are merged as well,
+//     their SelectedBundles, ExcludeChannels and ExcludeBundles are unioned, and ShortestPath and
+//     SkipDeprecated are set when any document sets them. A setting made by a single document applies to
+//     the merged package.
+//   - A package that a document lists without channels, and without bundles, selects all of its channels, unless
+//     the document only excludes channels or bundles of it. Other documents listing channels of that package
+//     would narrow it down, so they conflict with it.
+//   - Selectors are appended, ExcludePackages are unioned and SkipDeprecated is set when any document sets it.
+//
+// Two documents setting different DefaultChannel or DefaultChannelPolicy values, or different version ranges on
+// the same package or channel, or on a package and on one of its channels, are conflicts as well. Conflicts are
+// reported with the source and index of both documents. The merged configuration is validated again, as a whole.
+func LoadFilterConfigurations(sources ...ConfigurationSource) (*FilterConfiguration, error) {
+	return loadFilterConfigurations(false, sources)
+}
+
+// LoadFilterConfigurationsStrict merges the documents of the sources like LoadFilterConfigurations, after
+// validating each of them against the JSON Schema of its API, like LoadFilterConfigurationStrict does. The
+// lines and columns of the schema violations are counted from the start of their document.
+func LoadFilterConfigurationsStrict(sources ...ConfigurationSource) (*FilterConfiguration, error) {
+	return loadFilterConfigurations(true, sources)
+}
+
+func loadFilterConfigurations(strict bool, sources []ConfigurationSource) (*FilterConfiguration, error) {
+	var docs []configDocument
+	var errs []error
+	for _, source := range sources {
+		reader := utilyaml.NewYAMLReader(bufio.NewReader(source.Reader))
+		for index := 0; ; index++ {
+			data, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%q document [%d]: %v", source.Name, index, err)
+			}
+			// documents holding comments only are not configurations
+			if data, err := yaml.YAMLToJSON(data); err == nil && bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+				continue
+			}
+			doc := configDocument{source: source.Name, index: index}
+			if doc.config, err = decodeFilterConfiguration(data, strict); err != nil {
+				errs = append(errs, fmt.Errorf("%s is invalid: %v", doc, err))
+				continue
+			}
+			docs = append(docs, doc)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(docs) == 0 {
+		return nil, errors.New("no filter configuration found")
+	}
+
+	cfg, err := mergeFilterConfigurations(docs)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("merged filter configuration is invalid: %v", err)
+	}
+	return cfg, nil
+}
+
+// setting is the value of a configuration setting, along with where it was set.
+type setting struct {
+	value string
+	from  string
+}
+
+// merge records the value set at from, unless it is empty. It returns an error when another value was
+// already recorded.
+func (s *setting) merge(name, value, from string) error {
+	if value == "" || value == s.value {
+		return nil
+	}
+	if s.value != "" {
+		return fmt.Errorf("%s conflicts with %s: %s %q differs from %q", from, s.from, name, value, s.value)
+	}
+	s.value, s.from = value, from
+	return nil
+}
+
+// packageSettings are the settings of a merged package that documents cannot set differently.
+type packageSettings struct {
+	defaultChannel       setting
+	defaultChannelPolicy setting
+	versionRange         setting
+	channelVersionRanges map[string]*setting
+	// allChannelsFrom and channelsFrom are where the package was first listed without channels, selecting all
+	// of them, and with channels.
+	allChannelsFrom string
+	channelsFrom    string
+}
+
+func mergeFilterConfigurations(docs []configDocument) (*FilterConfiguration, error) {
+	merged := &FilterConfiguration{TypeMeta: docs[0].config.TypeMeta}
+	var defaultChannelPolicy setting
+	packageIndex := map[string]int{}
+	settings := map[string]*packageSettings{}
+	var errs []error
+	for _, doc := range docs {
+		cfg := doc.config
+		if err := defaultChannelPolicy.merge("defaultChannelPolicy", string(cfg.DefaultChannelPolicy), doc.String()); err != nil {
+			errs = append(errs, err)
+		}
+		merged.DefaultChannelPolicy = filter.DefaultChannelPolicy(defaultChannelPolicy.value)
+		merged.SkipDeprecated = merged.SkipDeprecated || cfg.SkipDeprecated
+		merged.Selectors = append(merged.Selectors, cfg.Selectors...)
+		merged.ExcludePackages = appendMissing(merged.ExcludePackages, cfg.ExcludePackages...)
+
+		for i, pkg := range cfg.Packages {
+			from := fmt.Sprintf("package %q at index [%d] of %s", pkg.Name, i, doc)
+			j, ok := packageIndex[pkg.Name]
+			if !ok {
+				j = len(merged.Packages)
+				packageIndex[pkg.Name] = j
+				settings[pkg.Name] = &packageSettings{channelVersionRanges: map[string]*setting{}}
+				merged.Packages = append(merged.Packages, Package{Name: pkg.Name})
+			}
+			errs = append(errs, mergePackage(&merged.Packages[j], settings[pkg.Name], pkg, from)...)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return merged, nil
+}
+
+// mergePackage merges pkg, found at from, into the merged package, whose conflicting settings so far are s.
+func mergePackage(merged *Package, s *packageSettings, pkg Package, from string) []error {
+	var errs []error
+	if err := s.defaultChannel.merge("defaultChannel", pkg.DefaultChannel, from); err != nil {
+		errs = append(errs, err)
+	}
+	merged.DefaultChannel = s.defaultChannel.value
+	if err := s.defaultChannelPolicy.merge("defaultChannelPolicy", string(pkg.DefaultChannelPolicy), from); err != nil {
+		errs = append(errs, err)
+	}
+	merged.DefaultChannelPolicy = filter.DefaultChannelPolicy(s.defaultChannelPolicy.value)
+
+	if versionRange := pkg.effectiveVersionRange(); versionRange != "" {
+		if err := s.versionRange.merge("versionRange", versionRange, from); err != nil {
+			errs = append(errs, err)
+		} else {
+			merged.VersionRange, merged.MinVersion, merged.MaxVersion = pkg.VersionRange, pkg.MinVersion, pkg.MaxVersion
+		}
+		for _, ch := range merged.Channels {
+			if channelRange := s.channelVersionRanges[ch.Name]; channelRange.value != "" {
+				errs = append(errs, fmt.Errorf("%s conflicts with %s: versionRange %q of the package differs from versionRange %q of channel %q", from, channelRange.from, versionRange, channelRange.value, ch.Name))
+			}
+		}
+	}
+	merged.ShortestPath = merged.ShortestPath || pkg.ShortestPath
+	merged.SkipDeprecated = merged.SkipDeprecated || pkg.SkipDeprecated
+
+	// bundle selections are not restricted to channels, they are validated once merged, and exclusions alone
+	// select nothing
+	if len(pkg.SelectedBundles) == 0 && !pkg.isExclusionOnly() {
+		names := channelNames(pkg.Channels)
+		switch {
+		case len(names) == 0 && s.channelsFrom != "":
+			errs = append(errs, fmt.Errorf("%s conflicts with %s: selecting all channels differs from selecting channels %q", from, s.channelsFrom, channelNames(merged.Channels)))
+		case len(names) > 0 && s.allChannelsFrom != "":
+			errs = append(errs, fmt.Errorf("%s conflicts with %s: selecting channels %q differs from selecting all channels", from, s.allChannelsFrom, names))
+		}
+		if len(names) == 0 && s.allChannelsFrom == "" {
+			s.allChannelsFrom = from
+		} else if len(names) > 0 && s.channelsFrom == "" {
+			s.channelsFrom = from
+		}
+	}
+
+	for k, ch := range pkg.Channels {
+		channelFrom := fmt.Sprintf("channel %q at index [%d] of %s", ch.Name, k, from)
+		i := slices.IndexFunc(merged.Channels, func(c Channel) bool { return c.Name == ch.Name })
+		if i < 0 {
+			i = len(merged.Channels)
+			merged.Channels = append(merged.Channels, Channel{Name: ch.Name})
+			s.channelVersionRanges[ch.Name] = &setting{}
+		}
+		if versionRange := ch.effectiveVersionRange(); versionRange != "" {
+			if s.versionRange.value != "" {
+				errs = append(errs, fmt.Errorf("%s conflicts with %s: versionRange %q differs from versionRange %q of the package", channelFrom, s.versionRange.from, versionRange, s.versionRange.value))
+			} else if err := s.channelVersionRanges[ch.Name].merge("versionRange", versionRange, channelFrom); err != nil {
+				errs = append(errs, err)
+			} else {
+				merged.Channels[i].VersionRange, merged.Channels[i].MinVersion, merged.Channels[i].MaxVersion = ch.VersionRange, ch.MinVersion, ch.MaxVersion
+			}
+		}
+		merged.Channels[i].ShortestPath = merged.Channels[i].ShortestPath || ch.ShortestPath
+	}
+
+	for _, b := range pkg.SelectedBundles {
+		if !slices.Contains(merged.SelectedBundles, b) {
+			merged.SelectedBundles = append(merged.SelectedBundles, b)
+		}
+	}
+	merged.ExcludeChannels = appendMissing(merged.ExcludeChannels, pkg.ExcludeChannels...)
+	merged.ExcludeBundles = appendMissing(merged.ExcludeBundles, pkg.ExcludeBundles...)
+	return errs
+}
+
+// channelNames returns the names of channels.
+func channelNames(channels []Channel) []string {
+	var names []string
+	for _, ch := range channels {
+		names = append(names, ch.Name)
+	}
+	return names
+}
+
+// appendMissing appends to list the names it does not contain yet.
+func appendMissing(list []string, names ...string) []string {
+	for _, name := range names {
+		if !slices.Contains(list, name) {
+			list = append(list, name)
+		}
+	}
+	return list
+}
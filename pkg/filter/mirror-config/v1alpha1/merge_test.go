@@ -0,0 +1,266 @@
+package v1alpha1
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sherine-k/catalog-filter/pkg/filter"
+)
+
+func TestLoadFilterConfigurationFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		paths       []string
+		expected    *FilterConfiguration
+		expectedErr []string
+	}{
+		{
+			name:  "WHEN files have several documents THEN Merges all the documents",
+			paths: []string{"testdata/configs/merge/team-a.yaml", "testdata/configs/merge/team-b.yaml"},
+			expected: &FilterConfiguration{
+				TypeMeta:       metav1.TypeMeta{APIVersion: FilterAPIVersion, Kind: FilterKind},
+				SkipDeprecated: true,
+				Packages: []Package{
+					{
+						Name:           "foo",
+						DefaultChannel: "stable",
+						Channels: []Channel{
+							{Name: "stable", VersionRange: ">=1.0.0 <2.0.0", ShortestPath: true},
+							{Name: "fast", MinVersion: "2.0.0"},
+						},
+					},
+					{
+						Name:            "bar",
+						SelectedBundles: []SelectedBundle{{Name: "bar.v1.0.0"}, {Name: "bar.v1.1.0"}},
+						ExcludeChannels: []string{"beta"},
+					},
+				},
+				ExcludePackages: []string{"baz", "qux"},
+			},
+		},
+		{
+			name:  "WHEN documents conflict THEN Returns errors naming both documents",
+			paths: []string{"testdata/configs/merge/team-a.yaml", "testdata/configs/merge/conflicts.yaml"},
+			expectedErr: []string{
+				`package "foo" at index [1] of "testdata/configs/merge/conflicts.yaml" document [0] conflicts with package "foo" at index [0] of "testdata/configs/merge/team-a.yaml" document [0]: defaultChannel "fast" differs from "stable"`,
+				`channel "stable" at index [0] of package "foo" at index [1] of "testdata/configs/merge/conflicts.yaml" document [0] conflicts with channel "stable" at index [0] of package "foo" at index [0] of "testdata/configs/merge/team-a.yaml" document [0]: versionRange ">=1.5.0" differs from ">=1.0.0 <2.0.0"`,
+				`package "foo" at index [0] of "testdata/configs/merge/conflicts.yaml" document [1] conflicts with channel "stable" at index [0] of package "foo" at index [0] of "testdata/configs/merge/team-a.yaml" document [0]: versionRange ">=1.0.0" of the package differs from versionRange ">=1.0.0 <2.0.0" of channel "stable"`,
+				`package "foo" at index [0] of "testdata/configs/merge/conflicts.yaml" document [1] conflicts with package "foo" at index [0] of "testdata/configs/merge/team-a.yaml" document [0]: selecting all channels differs from selecting channels ["stable"]`,
+			},
+		},
+		{
+			name:        "WHEN a document is invalid THEN Returns an error naming the document",
+			paths:       []string{"testdata/configs/merge/team-a.yaml", "testdata/configs/invalid_kind.yaml"},
+			expectedErr: []string{`"testdata/configs/invalid_kind.yaml" document [0] is invalid: unexpected kind`},
+		},
+		{
+			name:        "WHEN a file does not exist THEN Returns error",
+			paths:       []string{"testdata/configs/merge/missing.yaml"},
+			expectedErr: []string{"no such file or directory"},
+		},
+		{
+			name:        "WHEN no file THEN Returns error",
+			expectedErr: []string{"no filter configuration found"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadFilterConfigurationFiles(tt.paths...)
+			if len(tt.expectedErr) > 0 {
+				assert.Nil(t, cfg)
+				for _, expected := range tt.expectedErr {
+					assert.ErrorContains(t, err, expected)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg)
+		})
+	}
+}
+
+func TestLoadFilterConfigurations(t *testing.T) {
+	const header = "apiVersion: olm.operatorframework.io/filter/mirror/v1alpha1\nkind: FilterConfiguration\n"
+	source := func(name string, docs ...string) ConfigurationSource {
+		for i := range docs {
+			docs[i] = header + docs[i]
+		}
+		return ConfigurationSource{Name: name, Reader: strings.NewReader(strings.Join(docs, "---\n"))}
+	}
+	tests := []struct {
+		name        string
+		sources     []ConfigurationSource
+		expected    *FilterConfiguration
+		expectedErr string
+	}{
+		{
+			name: "WHEN documents set the same values THEN Merges them",
+			sources: []ConfigurationSource{
+				source("a", "defaultChannelPolicy: highestVersion\npackages:\n- name: foo\n  minVersion: 1.0.0\n  defaultChannel: stable\n  excludeBundles: [foo.v1.1.0]\n"),
+				source("b", "defaultChannelPolicy: highestVersion\npackages:\n- name: foo\n  minVersion: 1.0.0\n  shortestPath: true\n  excludeBundles: [foo.v1.2.0, foo.v1.1.0]\n", "selectors:\n- provider: acme\n"),
+			},
+			expected: &FilterConfiguration{
+				TypeMeta:             metav1.TypeMeta{APIVersion: FilterAPIVersion, Kind: FilterKind},
+				DefaultChannelPolicy: filter.DefaultChannelPolicyHighestVersion,
+				Packages: []Package{{
+					Name:           "foo",
+					DefaultChannel: "stable",
+					MinVersion:     "1.0.0",
+					ShortestPath:   true,
+					ExcludeBundles: []string{"foo.v1.1.0", "foo.v1.2.0"},
+				}},
+				Selectors: []Selector{{Provider: "acme"}},
+			},
+		},
+		{
+			name: "WHEN a document is of the config/v1alpha1 API THEN Converts it before merging",
+			sources: []ConfigurationSource{
+				{Name: "a", Reader: strings.NewReader("apiVersion: olm.operatorframework.io/v1alpha1\nkind: FilterConfiguration\npackages:\n- name: foo\n  channels:\n  - name: stable\n    versionRange: '>=1.0.0'\n")},
+				source("b", "packages:\n- name: foo\n  defaultChannel: stable\n  channels:\n  - name: stable\n"),
+			},
+			expected: &FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{APIVersion: FilterAPIVersion, Kind: FilterKind},
+				Packages: []Package{{
+					Name:           "foo",
+					DefaultChannel: "stable",
+					Channels:       []Channel{{Name: "stable", VersionRange: ">=1.0.0"}},
+				}},
+			},
+		},
+		{
+			name: "WHEN documents set different policies THEN Returns error",
+			sources: []ConfigurationSource{
+				source("a", "defaultChannelPolicy: highestVersion\n"),
+				source("b", "packages:\n- name: foo\n", "defaultChannelPolicy: stable\n"),
+			},
+			expectedErr: `"b" document [1] conflicts with "a" document [0]: defaultChannelPolicy "stable" differs from "highestVersion"`,
+		},
+		{
+			name: "WHEN documents set different package version ranges THEN Returns error",
+			sources: []ConfigurationSource{
+				source("a", "packages:\n- name: foo\n  versionRange: '>=1.0.0'\n- name: bar\n  channels:\n  - name: stable\n    maxVersion: 1.0.0\n"),
+				source("b", "packages:\n- name: foo\n  maxVersion: 2.0.0\n- name: bar\n  versionRange: '<3.0.0'\n"),
+			},
+			expectedErr: `package "foo" at index [0] of "b" document [0] conflicts with package "foo" at index [0] of "a" document [0]: versionRange "<=2.0.0" differs from ">=1.0.0"` + "\n" +
+				`package "bar" at index [1] of "b" document [0] conflicts with channel "stable" at index [0] of package "bar" at index [1] of "a" document [0]: versionRange "<3.0.0" of the package differs from versionRange "<=1.0.0" of channel "stable"` + "\n" +
+				`package "bar" at index [1] of "b" document [0] conflicts with package "bar" at index [1] of "a" document [0]: selecting all channels differs from selecting channels ["stable"]`,
+		},
+		{
+			name: "WHEN a channel range differs from the package range of another document THEN Returns error",
+			sources: []ConfigurationSource{
+				source("a", "packages:\n- name: foo\n  versionRange: '>=1.0.0'\n  channels:\n  - name: fast\n"),
+				source("b", "packages:\n- name: foo\n  channels:\n  - name: stable\n    versionRange: '>=1.2.0'\n"),
+			},
+			expectedErr: `channel "stable" at index [0] of package "foo" at index [0] of "b" document [0] conflicts with package "foo" at index [0] of "a" document [0]: versionRange ">=1.2.0" differs from versionRange ">=1.0.0" of the package`,
+		},
+		{
+			name: "WHEN a document selects all channels of a package that another lists channels of THEN Returns error",
+			sources: []ConfigurationSource{
+				source("a", "packages:\n- name: foo\n"),
+				source("b", "packages:\n- name: foo\n  channels:\n  - name: stable\n"),
+			},
+			expectedErr: `package "foo" at index [0] of "b" document [0] conflicts with package "foo" at index [0] of "a" document [0]: selecting channels ["stable"] differs from selecting all channels`,
+		},
+		{
+			name: "WHEN a document only excludes bundles of a package that another lists channels of THEN Merges them",
+			sources: []ConfigurationSource{
+				source("a", "packages:\n- name: foo\n  excludeBundles: [foo.v1.0.0]\n"),
+				source("b", "packages:\n- name: foo\n  channels:\n  - name: stable\n"),
+			},
+			expected: &FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{APIVersion: FilterAPIVersion, Kind: FilterKind},
+				Packages: []Package{{
+					Name:           "foo",
+					Channels:       []Channel{{Name: "stable"}},
+					ExcludeBundles: []string{"foo.v1.0.0"},
+				}},
+			},
+		},
+		{
+			name: "WHEN the merged configuration is invalid THEN Returns error",
+			sources: []ConfigurationSource{
+				source("a", "packages:\n- name: foo\n  bundles:\n  - name: foo.v1.0.0\n"),
+				source("b", "packages:\n- name: foo\n  channels:\n  - name: stable\n"),
+			},
+			expectedErr: `merged filter configuration is invalid: package "foo" at index [0] is invalid: mixing both filtering by bundles and filtering by channels or versionRange is not allowed`,
+		},
+		{
+			name:        "WHEN a document cannot be parsed THEN Returns error",
+			sources:     []ConfigurationSource{{Name: "a", Reader: strings.NewReader(header + "---\n{\n")}},
+			expectedErr: `"a" document [1] is invalid: error converting YAML to JSON: yaml: line 1: did not find expected node content`,
+		},
+		{
+			name:        "WHEN a source cannot be read THEN Returns error",
+			sources:     []ConfigurationSource{{Name: "a", Reader: iotest.ErrReader(errors.New("read failure"))}},
+			expectedErr: `"a" document [0]: read failure`,
+		},
+		{
+			name:        "WHEN documents hold comments only THEN Returns error",
+			sources:     []ConfigurationSource{{Name: "a", Reader: strings.NewReader("# nothing yet\n---\n---\n")}},
+			expectedErr: "no filter configuration found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadFilterConfigurations(tt.sources...)
+			if tt.expectedErr != "" {
+				assert.Nil(t, cfg)
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg)
+		})
+	}
+}
+
+func TestLoadFilterConfigurationsStrict(t *testing.T) {
+	const header = "apiVersion: olm.operatorframework.io/filter/mirror/v1alpha1\nkind: FilterConfiguration\n"
+	tests := []struct {
+		name        string
+		data        string
+		expected    *FilterConfiguration
+		expectedErr string
+	}{
+		{
+			name: "WHEN documents match their schema THEN Merges them",
+			data: header + "packages:\n- name: foo\n---\napiVersion: olm.operatorframework.io/v1alpha1\nkind: FilterConfiguration\npackages:\n- name: bar\n",
+			expected: &FilterConfiguration{
+				TypeMeta: metav1.TypeMeta{APIVersion: FilterAPIVersion, Kind: FilterKind},
+				Packages: []Package{{Name: "foo"}, {Name: "bar"}},
+			},
+		},
+		{
+			name:        "WHEN a document has an unknown field THEN Returns its line and column in the document",
+			data:        header + "packages:\n- name: foo\n---\n" + header + "packages:\n- name: bar\n  versionRnage: '>=1.0.0'\n",
+			expectedErr: `"a" document [1] is invalid: invalid filter configuration: line 5, column 3: packages[0]: unknown field "versionRnage"`,
+		},
+		{
+			name:        "WHEN a config/v1alpha1 document has an unknown field THEN Returns error",
+			data:        "apiVersion: olm.operatorframework.io/v1alpha1\nkind: FilterConfiguration\npackages:\n- name: bar\n  minVersion: 1.0.0\n",
+			expectedErr: `"a" document [0] is invalid: invalid filter configuration: line 5, column 3: packages[0]: unknown field "minVersion"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadFilterConfigurationsStrict(ConfigurationSource{Name: "a", Reader: strings.NewReader(tt.data)})
+			if tt.expectedErr != "" {
+				assert.Nil(t, cfg)
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg)
+
+			cfg, err = LoadFilterConfigurations(ConfigurationSource{Name: "a", Reader: strings.NewReader(tt.data)})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg)
+		})
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 
 	"github.com/Masterminds/semver/v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/yaml"
+
+	"github.com/sherine-k/catalog-filter/pkg/filter"
 )
 
 // FilterConfigurationV1 is a configuration for filtering a set of packages and channels from a catalog.
@@ -18,6 +23,49 @@ type FilterConfiguration struct {
 
 	// Packages is a list of packages to include in the filtered catalog.
 	Packages []Package `json:"packages"`
+
+	// Selectors select packages by their properties and by the CSV metadata of their head bundle,
+	// rather than by name. Each package matching at least one selector is included in the filtered
+	// catalog, as if it was listed in Packages with its name only. Packages listed in Packages keep
+	// their own configuration.
+	Selectors []Selector `json:"selectors,omitempty"`
+
+	// ExcludePackages is a list of packages to remove from the filtered catalog, whatever the other
+	// settings of the configuration.
+	ExcludePackages []string `json:"excludePackages,omitempty"`
+
+	// SkipDeprecated removes, from all the packages, the channels and bundles that the olm.deprecations
+	// of the catalog mark as deprecated, as if they were listed in Package.ExcludeChannels and
+	// Package.ExcludeBundles.
+	SkipDeprecated bool `json:"skipDeprecated,omitempty"`
+
+	// DefaultChannelPolicy decides the new default channel of the packages whose default channel is filtered
+	// out, when none is configured for them. If not set, such packages are an error.
+	DefaultChannelPolicy filter.DefaultChannelPolicy `json:"defaultChannelPolicy,omitempty"`
+}
+
+// Selector matches the packages that satisfy all of its criteria. The CSV metadata criteria are matched
+// against the olm.csv.metadata property of the head bundle of the package's default channel.
+type Selector struct {
+	// Properties matches the properties of the olm.package: for each property type, the package must
+	// have a property of that type with the given value.
+	Properties map[string]string `json:"properties,omitempty"`
+
+	// Labels matches the labels of the CSV metadata, which must contain all of them.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Provider matches the provider name of the CSV metadata, ignoring case.
+	Provider string `json:"provider,omitempty"`
+
+	// Keywords matches the keywords of the CSV metadata, which must contain all of them, ignoring case.
+	Keywords []string `json:"keywords,omitempty"`
+
+	// Maturity matches the maturity of the CSV metadata, ignoring case.
+	Maturity string `json:"maturity,omitempty"`
+
+	// Categories matches the comma separated categories annotation of the CSV metadata, which must
+	// contain all of them, ignoring case.
+	Categories []string `json:"categories,omitempty"`
 }
 
 type Package struct {
@@ -33,20 +81,151 @@ type Package struct {
 	// If not set, all versions will be included.
 	VersionRange string `json:"versionRange,omitempty"`
 
+	// MinVersion is the lowest version to include, inclusive.
+	// It cannot be combined with VersionRange.
+	MinVersion string `json:"minVersion,omitempty"`
+
+	// MaxVersion is the highest version to include, inclusive.
+	// It cannot be combined with VersionRange.
+	MaxVersion string `json:"maxVersion,omitempty"`
+
+	// ShortestPath keeps, in each channel of the package, only the bundles on the shortest upgrade path
+	// from the lowest version in range to the channel head.
+	ShortestPath bool `json:"shortestPath,omitempty"`
+
 	// Channels is a list of channels to include in the filtered catalog.
 	// If not set, all channels will be included.
 	Channels []Channel `json:"channels,omitempty"`
 
 	SelectedBundles []SelectedBundle `json:"bundles,omitempty"`
+
+	// ExcludeChannels is a list of channels of the package to remove from the filtered catalog.
+	ExcludeChannels []string `json:"excludeChannels,omitempty"`
+
+	// ExcludeBundles is a list of bundles of the package to remove from the filtered catalog. The replaces
+	// and skips of the channel entries upgrading from an excluded bundle are re-pointed to the bundles it
+	// replaced and skipped.
+	// A package that only sets DefaultChannel, SkipDeprecated, ExcludeChannels and ExcludeBundles does not
+	// select it: when all the packages of the configuration are such, the whole catalog is filtered apart
+	// from the exclusions.
+	ExcludeBundles []string `json:"excludeBundles,omitempty"`
+
+	// SkipDeprecated removes the deprecated channels and bundles of the package, like
+	// FilterConfiguration.SkipDeprecated does for all the packages.
+	SkipDeprecated bool `json:"skipDeprecated,omitempty"`
+
+	// DefaultChannelPolicy overrides, for the package, the DefaultChannelPolicy of the configuration.
+	DefaultChannelPolicy filter.DefaultChannelPolicy `json:"defaultChannelPolicy,omitempty"`
+}
+
+// isExclusionOnly reports whether the package configuration only excludes channels or bundles of the package,
+// without selecting what to keep from it.
+func (p Package) isExclusionOnly() bool {
+	return (len(p.ExcludeChannels) > 0 || len(p.ExcludeBundles) > 0) &&
+		p.effectiveVersionRange() == "" && !p.ShortestPath && len(p.Channels) == 0 && len(p.SelectedBundles) == 0
 }
 
 type Channel struct {
 	// Name is the name of the channel to include in the filtered catalog.
+	// It can also be a glob, such as `stable-*`, or a regular expression prefixed with `re:`,
+	// such as `re:^stable-4\.1[4-6]$`, selecting all the channels of the package it matches.
 	Name string `json:"name"`
 
 	// VersionRange is a semver range to filter the versions of the channel.
 	// If not set, all versions will be included.
 	VersionRange string `json:"versionRange,omitempty"`
+
+	// MinVersion is the lowest version of the channel to include, inclusive.
+	// It cannot be combined with VersionRange.
+	MinVersion string `json:"minVersion,omitempty"`
+
+	// MaxVersion is the highest version of the channel to include, inclusive.
+	// It cannot be combined with VersionRange.
+	MaxVersion string `json:"maxVersion,omitempty"`
+
+	// ShortestPath keeps only the bundles on the shortest upgrade path from the lowest version in range
+	// to the channel head. Replaces and skips of the kept bundles are rewritten to follow that path.
+	ShortestPath bool `json:"shortestPath,omitempty"`
+}
+
+// effectiveVersionRange returns the semver range selecting the versions of the package, either
+// VersionRange or the range bounded by MinVersion and MaxVersion.
+func (p Package) effectiveVersionRange() string {
+	return boundedVersionRange(p.VersionRange, p.MinVersion, p.MaxVersion)
+}
+
+// effectiveVersionRange returns the semver range selecting the versions of the channel, either
+// VersionRange or the range bounded by MinVersion and MaxVersion.
+func (c Channel) effectiveVersionRange() string {
+	return boundedVersionRange(c.VersionRange, c.MinVersion, c.MaxVersion)
+}
+
+// boundedVersionRange returns versionRange when set, otherwise the inclusive range between minVersion and maxVersion.
+func boundedVersionRange(versionRange, minVersion, maxVersion string) string {
+	if versionRange != "" {
+		return versionRange
+	}
+	var bounds []string
+	if minVersion != "" {
+		bounds = append(bounds, ">="+minVersion)
+	}
+	if maxVersion != "" {
+		bounds = append(bounds, "<="+maxVersion)
+	}
+	return strings.Join(bounds, " ")
+}
+
+// validateVersionBounds checks that minVersion and maxVersion are valid semantic versions, in order,
+// and are not combined with a versionRange.
+func validateVersionBounds(versionRange, minVersion, maxVersion string) []error {
+	var errs []error
+	if versionRange != "" && (minVersion != "" || maxVersion != "") {
+		errs = append(errs, fmt.Errorf("versionRange and minVersion/maxVersion are exclusive"))
+	}
+	var min, max *semver.Version
+	if minVersion != "" {
+		v, err := semver.NewVersion(minVersion)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("minVersion is not in valid semantic versionning format: %v", err))
+		}
+		min = v
+	}
+	if maxVersion != "" {
+		v, err := semver.NewVersion(maxVersion)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("maxVersion is not in valid semantic versionning format: %v", err))
+		}
+		max = v
+	}
+	if min != nil && max != nil && min.GreaterThan(max) {
+		errs = append(errs, fmt.Errorf("minVersion %q is greater than maxVersion %q", minVersion, maxVersion))
+	}
+	return errs
+}
+
+// validateExclusions checks that the channels and bundles excluded from pkg are named, and are not selected.
+func validateExclusions(pkg Package) []error {
+	var errs []error
+	for j, name := range pkg.ExcludeChannels {
+		if name == "" {
+			errs = append(errs, fmt.Errorf("excluded channel at index [%d] is invalid: name must be specified", j))
+		}
+		if name == pkg.DefaultChannel {
+			errs = append(errs, fmt.Errorf("excluded channel %q at index [%d] is invalid: channel is the configured default channel", name, j))
+		}
+		if slices.ContainsFunc(pkg.Channels, func(ch Channel) bool { return ch.Name == name }) {
+			errs = append(errs, fmt.Errorf("excluded channel %q at index [%d] is invalid: channel is also listed in channels", name, j))
+		}
+	}
+	for j, name := range pkg.ExcludeBundles {
+		if name == "" {
+			errs = append(errs, fmt.Errorf("excluded bundle at index [%d] is invalid: name must be specified", j))
+		}
+		if slices.ContainsFunc(pkg.SelectedBundles, func(b SelectedBundle) bool { return b.Name == name }) {
+			errs = append(errs, fmt.Errorf("excluded bundle %q at index [%d] is invalid: bundle is also listed in bundles", name, j))
+		}
+	}
+	return errs
 }
 
 type SelectedBundle struct {
@@ -76,24 +255,44 @@ func (f *FilterConfiguration) Validate() error {
 	if f.Kind != FilterKind {
 		errs = append(errs, fmt.Errorf("unexpected kind %q", f.Kind))
 	}
+	if err := f.DefaultChannelPolicy.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("defaultChannelPolicy is invalid: %v", err))
+	}
 	for i, pkg := range f.Packages {
 		if pkg.Name == "" {
 			errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: name must be specified", pkg.Name, i))
 		}
-		if len(pkg.SelectedBundles) > 0 && (len(pkg.Channels) > 0 || pkg.VersionRange != "") {
+		if err := pkg.DefaultChannelPolicy.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: %v", pkg.Name, i, err))
+		}
+		if len(pkg.SelectedBundles) > 0 && (len(pkg.Channels) > 0 || pkg.effectiveVersionRange() != "") {
 			errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: mixing both filtering by bundles and filtering by channels or versionRange is not allowed", pkg.Name, i))
 		}
+		if len(pkg.SelectedBundles) > 0 && pkg.ShortestPath {
+			errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: mixing both filtering by bundles and shortestPath is not allowed", pkg.Name, i))
+		}
 		if pkg.VersionRange != "" {
 			_, err := semver.NewConstraint(pkg.VersionRange)
 			if err != nil {
 				errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: versionRange is not in valid semantic versionning format: %v", pkg.Name, i, err))
 			}
 		}
+		for _, err := range validateVersionBounds(pkg.VersionRange, pkg.MinVersion, pkg.MaxVersion) {
+			errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: %v", pkg.Name, i, err))
+		}
+		for _, err := range validateExclusions(pkg) {
+			errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: %v", pkg.Name, i, err))
+		}
 		for j, channel := range pkg.Channels {
 			if channel.Name == "" {
 				errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: channel %q at index [%d] is invalid: name must be specified", pkg.Name, i, channel.Name, j))
 			}
-			if channel.VersionRange != "" && pkg.VersionRange != "" {
+			if filter.IsNamePattern(channel.Name) {
+				if _, err := filter.CompileNamePattern(channel.Name); err != nil {
+					errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: channel %q at index [%d] is invalid: %v", pkg.Name, i, channel.Name, j, err))
+				}
+			}
+			if channel.effectiveVersionRange() != "" && pkg.effectiveVersionRange() != "" {
 				errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: package specifies a VersionRange, while channel %q at index [%d] equally specifies one: package.VersionRange and channel.VersionRange are exclusive", pkg.Name, i, channel.Name, j))
 			}
 			if channel.VersionRange != "" {
@@ -102,6 +301,26 @@ func (f *FilterConfiguration) Validate() error {
 					errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: channel %q at index [%d] is invalid: versionRange is not in valid semantic versionning format: %v", pkg.Name, i, channel.Name, j, err))
 				}
 			}
+			for _, err := range validateVersionBounds(channel.VersionRange, channel.MinVersion, channel.MaxVersion) {
+				errs = append(errs, fmt.Errorf("package %q at index [%d] is invalid: channel %q at index [%d] is invalid: %v", pkg.Name, i, channel.Name, j, err))
+			}
+		}
+	}
+	packageNames := sets.New[string]()
+	for _, pkg := range f.Packages {
+		packageNames.Insert(pkg.Name)
+	}
+	for i, name := range f.ExcludePackages {
+		if name == "" {
+			errs = append(errs, fmt.Errorf("excluded package at index [%d] is invalid: name must be specified", i))
+		}
+		if packageNames.Has(name) {
+			errs = append(errs, fmt.Errorf("excluded package %q at index [%d] is invalid: package is also listed in packages", name, i))
+		}
+	}
+	for i, selector := range f.Selectors {
+		if selector.isEmpty() {
+			errs = append(errs, fmt.Errorf("selector at index [%d] is invalid: at least one criterion must be specified", i))
 		}
 	}
 	return errors.Join(errs...)
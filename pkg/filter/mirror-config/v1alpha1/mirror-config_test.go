@@ -102,6 +102,80 @@ func TestLoadFilterConfiguration(t *testing.T) {
 				assert.ErrorContains(t, err, `package "quux" at index [6] is invalid: versionRange is not in valid semantic versionning format: improper constraint: not semver`)
 				assert.ErrorContains(t, err, `package "qux" at index [5] is invalid: package specifies a VersionRange, while channel "stable" at index [0] equally specifies one: package.VersionRange and channel.VersionRange are exclusive`)
 				assert.ErrorContains(t, err, `package "quuuux" at index [8] is invalid: mixing both filtering by bundles and filtering by channels or versionRange is not allowed`)
+				assert.ErrorContains(t, err, `package "quuuuux" at index [9] is invalid: mixing both filtering by bundles and shortestPath is not allowed`)
+				assert.ErrorContains(t, err, `package "corge" at index [10] is invalid: channel "stable-[" at index [0] is invalid: invalid glob "stable-["`)
+				assert.ErrorContains(t, err, `package "corge" at index [10] is invalid: channel "re:stable-(" at index [1] is invalid: invalid regular expression "stable-("`)
+				assert.ErrorContains(t, err, `selector at index [1] is invalid: at least one criterion must be specified`)
+				assert.NotContains(t, err.Error(), `selector at index [0]`)
+			},
+		},
+		{
+			name:     "InvalidVersionBounds",
+			openFile: func() (io.Reader, error) { return configsFS.Open("testdata/configs/invalid_versionbounds.yaml") },
+			assertion: func(t *testing.T, cfg *FilterConfiguration, err error) {
+				assert.Nil(t, cfg)
+				require.Error(t, err)
+				assert.ErrorContains(t, err, `package "foo" at index [0] is invalid: versionRange and minVersion/maxVersion are exclusive`)
+				assert.ErrorContains(t, err, `package "bar" at index [1] is invalid: channel "stable" at index [0] is invalid: minVersion "2.0.0" is greater than maxVersion "1.0.0"`)
+				assert.ErrorContains(t, err, `package "baz" at index [2] is invalid: minVersion is not in valid semantic versionning format: Invalid Semantic Version`)
+				assert.ErrorContains(t, err, `package "qux" at index [3] is invalid: package specifies a VersionRange, while channel "stable" at index [0] equally specifies one`)
+				assert.ErrorContains(t, err, `package "quux" at index [4] is invalid: mixing both filtering by bundles and filtering by channels or versionRange is not allowed`)
+			},
+		},
+		{
+			name:     "ValidVersionBounds",
+			openFile: func() (io.Reader, error) { return configsFS.Open("testdata/configs/valid_versionbounds.yaml") },
+			assertion: func(t *testing.T, cfg *FilterConfiguration, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, cfg)
+				assert.Len(t, cfg.Packages, 2)
+				assert.Equal(t, "1.0.0", cfg.Packages[0].MinVersion)
+				assert.Equal(t, ">=1.0.0", cfg.Packages[0].effectiveVersionRange())
+				assert.Equal(t, "1.0.0", cfg.Packages[1].Channels[0].MinVersion)
+				assert.Equal(t, "2.0.0", cfg.Packages[1].Channels[0].MaxVersion)
+				assert.Equal(t, ">=1.0.0 <=2.0.0", cfg.Packages[1].Channels[0].effectiveVersionRange())
+				assert.Equal(t, "<=3.0.0", cfg.Packages[1].Channels[1].effectiveVersionRange())
+			},
+		},
+		{
+			name:     "InvalidDefaultChannelPolicy",
+			openFile: func() (io.Reader, error) { return configsFS.Open("testdata/configs/invalid_defaultchannelpolicy.yaml") },
+			assertion: func(t *testing.T, cfg *FilterConfiguration, err error) {
+				assert.Nil(t, cfg)
+				require.Error(t, err)
+				assert.ErrorContains(t, err, `defaultChannelPolicy is invalid: unknown default channel policy "newest"`)
+				assert.ErrorContains(t, err, `package "bar" at index [1] is invalid: unknown default channel policy "oldest"`)
+				assert.NotContains(t, err.Error(), `package "foo"`)
+			},
+		},
+		{
+			name:     "InvalidExclusions",
+			openFile: func() (io.Reader, error) { return configsFS.Open("testdata/configs/invalid_exclusions.yaml") },
+			assertion: func(t *testing.T, cfg *FilterConfiguration, err error) {
+				assert.Nil(t, cfg)
+				require.Error(t, err)
+				assert.ErrorContains(t, err, `excluded package "foo" at index [0] is invalid: package is also listed in packages`)
+				assert.ErrorContains(t, err, `excluded package at index [1] is invalid: name must be specified`)
+				assert.ErrorContains(t, err, `package "foo" at index [0] is invalid: excluded channel "stable" at index [0] is invalid: channel is the configured default channel`)
+				assert.ErrorContains(t, err, `package "foo" at index [0] is invalid: excluded channel at index [1] is invalid: name must be specified`)
+				assert.ErrorContains(t, err, `package "bar" at index [1] is invalid: excluded channel "fast" at index [0] is invalid: channel is also listed in channels`)
+				assert.ErrorContains(t, err, `package "baz" at index [2] is invalid: excluded bundle "baz.v1.0.0" at index [0] is invalid: bundle is also listed in bundles`)
+				assert.ErrorContains(t, err, `package "baz" at index [2] is invalid: excluded bundle at index [1] is invalid: name must be specified`)
+			},
+		},
+		{
+			name:     "ValidExclusions",
+			openFile: func() (io.Reader, error) { return configsFS.Open("testdata/configs/valid_exclusions.yaml") },
+			assertion: func(t *testing.T, cfg *FilterConfiguration, err error) {
+				require.NoError(t, err)
+				require.NotNil(t, cfg)
+				assert.Equal(t, []string{"baz"}, cfg.ExcludePackages)
+				assert.True(t, cfg.SkipDeprecated)
+				assert.True(t, cfg.Packages[1].SkipDeprecated)
+				assert.True(t, cfg.Packages[0].isExclusionOnly())
+				assert.Equal(t, []string{"fast"}, cfg.Packages[0].ExcludeChannels)
+				assert.False(t, cfg.Packages[1].isExclusionOnly())
+				assert.Equal(t, []string{"bar.v2.0.0"}, cfg.Packages[1].ExcludeBundles)
 			},
 		},
 		{
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"slices"
 	"strings"
+	"sync"
 
 	mmsemver "github.com/Masterminds/semver/v3"
 	"github.com/sirupsen/logrus"
@@ -13,19 +15,79 @@ import (
 
 	"github.com/operator-framework/operator-registry/alpha/declcfg"
 	"github.com/sherine-k/catalog-filter/pkg/filter"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
 )
 
 type filterOptions struct {
-	Log  *logrus.Entry
-	Full bool
+	Log           *logrus.Entry
+	Full          bool
+	Report        *FilterReport
+	Dependencies  bool
+	MultipleHeads filter.MultipleHeadsPolicy
 }
 
 type FilterOption func(*filterOptions)
 
+// WithDependencies makes FilterCatalog keep, from the unfiltered catalog, the bundles providing the packages
+// (olm.package.required) and GVKs (olm.gvk.required) required by the kept bundles, along with their own
+// dependencies. The minimal set of bundles is added, and reported through WithReport. KeepMeta then keeps every
+// meta object, for the dependencies to be found when the catalog is streamed.
+func WithDependencies(resolve bool) FilterOption {
+	return func(opts *filterOptions) {
+		opts.Dependencies = resolve
+	}
+}
+
+// WithMultipleHeads sets how FilterCatalog filters the channels that have several heads, see
+// filter.MultipleHeadsPolicy. By default, such channels make FilterCatalog fail.
+func WithMultipleHeads(policy filter.MultipleHeadsPolicy) FilterOption {
+	return func(opts *filterOptions) {
+		opts.MultipleHeads = policy
+	}
+}
+
 type mirrorFilter struct {
 	pkgConfigs map[string]Package
 	chConfigs  map[string]map[string]Channel
-	opts       filterOptions
+	selectors  []Selector
+	// excludePackages are removed from the catalog before any other filtering
+	excludePackages sets.Set[string]
+	// skipDeprecated removes the deprecated channels and bundles of all packages
+	skipDeprecated bool
+	// defaultChannelPolicy applies to the packages that don't configure their own
+	defaultChannelPolicy filter.DefaultChannelPolicy
+	// selectedPackages are the packages of the catalog being filtered that were added by selectors
+	selectedPackages sets.Set[string]
+	// dropped are the packages KeepMeta dropped while the catalog was streamed, reported by FilterCatalog
+	dropped *droppedPackages
+	opts    filterOptions
+}
+
+// droppedPackages records the reason each package was dropped for. KeepMeta may be called concurrently.
+type droppedPackages struct {
+	mu      sync.Mutex
+	reasons map[string]string
+}
+
+func (d *droppedPackages) add(name, reason string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reasons[name] = reason
+}
+
+// take returns the packages dropped so far, and forgets them.
+func (d *droppedPackages) take() map[string]string {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	reasons := d.reasons
+	d.reasons = map[string]string{}
+	return reasons
 }
 
 func WithLogger(log *logrus.Entry) FilterOption {
@@ -46,6 +108,16 @@ func InFull(full bool) FilterOption {
 	}
 }
 
+// WithReport makes FilterCatalog fill report with the reason each package, channel and bundle
+// was kept or removed. The report is overwritten on each call to FilterCatalog, including when it fails.
+// The packages KeepMeta dropped since the previous call to FilterCatalog are reported by the next one, which
+// forgets them even when fbc is nil.
+func WithReport(report *FilterReport) FilterOption {
+	return func(opts *filterOptions) {
+		opts.Report = report
+	}
+}
+
 func NewMirrorFilter(config FilterConfiguration, filterOpts ...FilterOption) filter.CatalogFilter {
 	opts := filterOptions{
 		Log: nullLogger(),
@@ -66,10 +138,19 @@ func NewMirrorFilter(config FilterConfiguration, filterOpts ...FilterOption) fil
 		}
 		chConfigs[pkg.Name] = pkgChannels
 	}
+	var dropped *droppedPackages
+	if opts.Report != nil {
+		dropped = &droppedPackages{reasons: map[string]string{}}
+	}
 	return &mirrorFilter{
-		pkgConfigs: pkgConfigs,
-		chConfigs:  chConfigs,
-		opts:       opts,
+		pkgConfigs:           pkgConfigs,
+		chConfigs:            chConfigs,
+		selectors:            config.Selectors,
+		excludePackages:      sets.New(config.ExcludePackages...),
+		skipDeprecated:       config.SkipDeprecated,
+		defaultChannelPolicy: config.DefaultChannelPolicy,
+		dropped:              dropped,
+		opts:                 opts,
 	}
 }
 
@@ -80,16 +161,84 @@ func NewMirrorFilter(config FilterConfiguration, filterOpts ...FilterOption) fil
 // * Make a set called allEntries containing the names of entry in the channel.
 // * Subtract inChain from allEntries . If items remain in allEntries, fail (there are some dangling bundles)
 func (f *mirrorFilter) FilterCatalog(ctx context.Context, fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	// the packages dropped while streaming are taken first, so that none of them carries over to the next call
+	dropped := f.dropped.take()
 	if fbc == nil {
 		return nil, nil
 	}
+	var report *reportBuilder
+	if f.opts.Report != nil {
+		report = newReportBuilder()
+		defer func() { *f.opts.Report = report.report() }()
+	}
+	for name, reason := range dropped {
+		report.pkg(name, Removed, reason)
+	}
+	if err := f.opts.MultipleHeads.Validate(); err != nil {
+		return nil, err
+	}
+	if f.opts.MultipleHeads == filter.MultipleHeadsPolicyKeepHighest {
+		fbc = keepHighestHeads(fbc, f.opts.Log)
+	}
+	// the expanded configurations only apply to fbc, f is left untouched for the next catalogs
+	if len(f.selectors) > 0 {
+		index, err := indexFromDeclCfg(fbc)
+		if err != nil {
+			return nil, err
+		}
+		f = f.withSelectedPackages(selectPackages(f.selectors, index, f.opts.Log))
+	}
+	var excludedBundles []excludedBundle
+	if f.hasExclusions() {
+		var err error
+		fbc, excludedBundles, err = f.exclude(fbc, report)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filter.HasChannelPatterns(f.chConfigs) {
+		chConfigs, err := filter.ExpandChannelPatterns(f.configuredChannels(), filter.ChannelNames(fbc),
+			func(ch Channel) string { return ch.Name }, func(ch Channel, name string) Channel { ch.Name = name; return ch })
+		if err != nil {
+			return nil, err
+		}
+		expanded := *f
+		expanded.chConfigs = chConfigs
+		f = &expanded
+	}
+	var dependencies *dependencyResolver
+	if f.opts.Dependencies {
+		// the unfiltered catalog is indexed before filtering alters it
+		fullIndex, err := indexFromDeclCfg(fbc)
+		if err != nil {
+			return nil, err
+		}
+		dependencies, err = newDependencyResolver(fullIndex, cloneDeprecations(fbc.Deprecations), f.opts.Log, report)
+		if err != nil {
+			return nil, err
+		}
+	}
 	filteredFBC := &declcfg.DeclarativeConfig{}
-	if len(f.pkgConfigs) != 0 {
+	if f.selectsPackages() {
 		// keep in FBC only packages, channels and bundles
 		// that belong to the filtered packages
-		f.filterByPackageAndChannels(fbc, filteredFBC)
+		f.filterByPackageAndChannels(fbc, filteredFBC, report)
 	} else {
-		filteredFBC = fbc
+		// the packages and channels are copied, so that filtering them leaves fbc untouched
+		copied := *fbc
+		copied.Packages = slices.Clone(fbc.Packages)
+		copied.Channels = slices.Clone(fbc.Channels)
+		filteredFBC = &copied
+		for _, pkg := range fbc.Packages {
+			report.pkg(pkg.Name, Kept, ReasonAllPackages)
+		}
+		for _, ch := range fbc.Channels {
+			report.channel(ch.Package, ch.Name, Kept, ReasonAllChannels)
+		}
+	}
+	for _, b := range excludedBundles {
+		version, _ := getBundleVersion(b.Bundle)
+		report.bundle(b.Package, b.Name, version, Removed, b.reason)
 	}
 	catalogIndex, err := indexFromDeclCfg(filteredFBC)
 	if err != nil {
@@ -98,9 +247,19 @@ func (f *mirrorFilter) FilterCatalog(ctx context.Context, fbc *declcfg.Declarati
 	for pkgIndex, pkg := range filteredFBC.Packages {
 		pkgConfig, exists := f.pkgConfigs[pkg.Name]
 		if exists {
-			if err := setDefaultChannel(&pkg, pkgConfig, catalogIndex.ChannelNames[pkg.Name]); err != nil {
+			headVersion := func(channel string) (*mmsemver.Version, error) {
+				return f.channelHeadVersion(catalogIndex, pkg.Name, channel)
+			}
+			policy := f.policyFor(pkgConfig)
+			selected, err := setDefaultChannel(&pkg, pkgConfig, policy, catalogIndex.ChannelNames[pkg.Name], headVersion)
+			if err != nil {
 				return nil, fmt.Errorf("invalid default channel configuration for package %q: %v", pkg.Name, err)
 			}
+			if selected {
+				f.opts.Log.Infof("package %q: the default channel %q was filtered out, channel %q was selected by the %q default channel policy",
+					pkg.Name, filteredFBC.Packages[pkgIndex].DefaultChannel, pkg.DefaultChannel, policy)
+				report.defaultChannel(pkg.Name, pkg.DefaultChannel)
+			}
 			// TODO: not sure the following line is necessary
 			filteredFBC.Packages[pkgIndex].DefaultChannel = pkg.DefaultChannel
 
@@ -110,13 +269,16 @@ func (f *mirrorFilter) FilterCatalog(ctx context.Context, fbc *declcfg.Declarati
 	emptyChannels := []declcfg.Channel{}
 
 	for channelIndex, ch := range filteredFBC.Channels {
-		versionRange := f.chConfigs[ch.Package][ch.Name].VersionRange
-		if versionRange == "" && f.pkgConfigs[ch.Package].VersionRange != "" {
-			versionRange = f.pkgConfigs[ch.Package].VersionRange
+		versionRange := f.chConfigs[ch.Package][ch.Name].effectiveVersionRange()
+		if versionRange == "" {
+			versionRange = f.pkgConfigs[ch.Package].effectiveVersionRange()
 		}
+		shortestPath := f.chConfigs[ch.Package][ch.Name].ShortestPath || f.pkgConfigs[ch.Package].ShortestPath
 		switch {
 		case f.opts.Full && versionRange != "":
 			return nil, fmt.Errorf("Full: true cannot be mixed with versionRange")
+		case f.opts.Full && shortestPath:
+			return nil, fmt.Errorf("Full: true cannot be mixed with shortestPath")
 		case f.opts.Full && len(f.pkgConfigs[ch.Package].SelectedBundles) > 0:
 			return nil, fmt.Errorf("Full: true cannot be mixed with filtering by bundle selection")
 		case len(f.pkgConfigs[ch.Package].SelectedBundles) > 0 && versionRange != "":
@@ -126,12 +288,14 @@ func (f *mirrorFilter) FilterCatalog(ctx context.Context, fbc *declcfg.Declarati
 				keepBundles[ch.Package] = sets.New[string]()
 			}
 			keepBundles[ch.Package].Insert(bundleNames(f.pkgConfigs[ch.Package].SelectedBundles)...)
-			filteredFBC.Channels[channelIndex].Entries = slices.DeleteFunc(filteredFBC.Channels[channelIndex].Entries, func(e declcfg.ChannelEntry) bool {
+			filteredFBC.Channels[channelIndex].Entries = slices.DeleteFunc(slices.Clone(filteredFBC.Channels[channelIndex].Entries), func(e declcfg.ChannelEntry) bool {
 				for _, selectedEntry := range f.pkgConfigs[ch.Package].SelectedBundles {
 					if e.Name == selectedEntry.Name {
+						report.entry(ch.Package, ch.Name, e.Name, catalogIndex.BundleVersionsByPkgAndName[ch.Package][e.Name], Kept, ReasonSelectedBundle)
 						return false
 					}
 				}
+				report.entry(ch.Package, ch.Name, e.Name, catalogIndex.BundleVersionsByPkgAndName[ch.Package][e.Name], Removed, ReasonNotSelectedBundle)
 				return true
 			})
 			if len(filteredFBC.Channels[channelIndex].Entries) == 0 {
@@ -140,11 +304,12 @@ func (f *mirrorFilter) FilterCatalog(ctx context.Context, fbc *declcfg.Declarati
 				} else {
 					// mark the empty channel for removal from the list of channels
 					emptyChannels = append(emptyChannels, ch)
+					report.channel(ch.Package, ch.Name, Removed, ReasonEmptyChannel)
 				}
 			} else {
 				// verify the filtered channel is still valid
 				// we probably want to remove a channel that is empty? but not sure.
-				_, err := newChannel(filteredFBC.Channels[channelIndex], f.opts.Log)
+				_, err := f.channelHeads(filteredFBC.Channels[channelIndex])
 				if err != nil {
 					return nil, fmt.Errorf("filtering on the selected bundles leads to invalidating channel %q for package %q: %v", ch.Name, ch.Package, err)
 				}
@@ -155,27 +320,78 @@ func (f *mirrorFilter) FilterCatalog(ctx context.Context, fbc *declcfg.Declarati
 					keepBundles[ch.Package] = sets.New[string]()
 				}
 				keepBundles[ch.Package].Insert(entry.Name)
+				report.entry(ch.Package, ch.Name, entry.Name, catalogIndex.BundleVersionsByPkgAndName[ch.Package][entry.Name], Kept, ReasonInFull)
+			}
+		case shortestPath:
+			var rangeConstraint *mmsemver.Constraints
+			if versionRange != "" {
+				rangeConstraint, err = mmsemver.NewConstraint(versionRange)
+				if err != nil {
+					return nil, fmt.Errorf("error parsing version range: %v", err)
+				}
+			}
+			heads, err := f.channelHeads(ch)
+			if err != nil {
+				return nil, err
+			}
+			entryNames := sets.New[string]()
+			for _, e := range ch.Entries {
+				entryNames.Insert(e.Name)
+			}
+			// each head of the channel keeps its own shortest upgrade path
+			var paths [][]*graph.Node
+			keepEntries := sets.New[string]()
+			for _, c := range heads {
+				path := shortestUpgradePath(c, entryNames, rangeConstraint, catalogIndex.BundleVersionsByPkgAndName[ch.Package])
+				for _, e := range path {
+					keepEntries.Insert(e.Name)
+				}
+				paths = append(paths, path)
+			}
+			for _, e := range ch.Entries {
+				version := catalogIndex.BundleVersionsByPkgAndName[ch.Package][e.Name]
+				if keepEntries.Has(e.Name) {
+					report.entry(ch.Package, ch.Name, e.Name, version, Kept, ReasonShortestPath)
+				} else {
+					report.entry(ch.Package, ch.Name, e.Name, version, Removed, ReasonNotShortestPath)
+				}
+			}
+			if len(keepEntries) == 0 {
+				if ch.Name == catalogIndex.Packages[ch.Package].DefaultChannel {
+					return nil, fmt.Errorf("package %q channel %q has no shortest upgrade path in version range %q, which results in an empty channel", ch.Package, ch.Name, versionRange)
+				} else {
+					// mark the empty channel for removal from the list of channels
+					emptyChannels = append(emptyChannels, ch)
+					report.channel(ch.Package, ch.Name, Removed, ReasonEmptyChannel)
+				}
 			}
+			filteredFBC.Channels[channelIndex].Entries = upgradePathEntries(ch.Entries, paths...)
+			if _, ok := keepBundles[ch.Package]; !ok {
+				keepBundles[ch.Package] = sets.New[string]()
+			}
+			keepBundles[ch.Package] = keepBundles[ch.Package].Union(keepEntries)
 		case versionRange != "":
 			keepEntries := sets.New[string]()
 			rangeConstraint, err := mmsemver.NewConstraint(versionRange)
 			if err != nil {
 				return nil, fmt.Errorf("error parsing version range: %v", err)
 			}
-			filteringChannel, err := newChannel(ch, f.opts.Log)
+			heads, err := f.channelHeads(ch)
 			if err != nil {
 				return nil, err
 			}
-			keepEntries = filteringChannel.filterByVersionRange(rangeConstraint, catalogIndex.BundleVersionsByPkgAndName[ch.Package])
+			keepEntries = filterHeadsByVersionRange(heads, rangeConstraint, catalogIndex.BundleVersionsByPkgAndName[ch.Package], f.opts.Log)
+			reportVersionRangeEntries(report, ch, keepEntries, rangeConstraint, catalogIndex.BundleVersionsByPkgAndName[ch.Package])
 			if len(keepEntries) == 0 {
 				if ch.Name == catalogIndex.Packages[ch.Package].DefaultChannel {
 					return nil, fmt.Errorf("package %q channel %q has version range %q that results in an empty channel", ch.Package, ch.Name, versionRange)
 				} else {
 					// mark the empty channel for removal from the list of channels
 					emptyChannels = append(emptyChannels, ch)
+					report.channel(ch.Package, ch.Name, Removed, ReasonEmptyChannel)
 				}
 			}
-			filteredFBC.Channels[channelIndex].Entries = slices.DeleteFunc(filteredFBC.Channels[channelIndex].Entries, func(e declcfg.ChannelEntry) bool {
+			filteredFBC.Channels[channelIndex].Entries = slices.DeleteFunc(slices.Clone(filteredFBC.Channels[channelIndex].Entries), func(e declcfg.ChannelEntry) bool {
 				return !keepEntries.Has(e.Name)
 			})
 			if _, ok := keepBundles[ch.Package]; !ok {
@@ -183,15 +399,22 @@ func (f *mirrorFilter) FilterCatalog(ctx context.Context, fbc *declcfg.Declarati
 			}
 			keepBundles[ch.Package] = keepBundles[ch.Package].Union(keepEntries)
 		default:
-			filteredChannel, chHead, err := f.filterChannelHead(ch, catalogIndex)
+			filteredChannel, chHeads, err := f.filterChannelHead(ch, catalogIndex)
 			if err != nil {
 				return nil, fmt.Errorf("package %q channel %q unable to filter head of channel: %v", ch.Package, ch.Name, err)
 			}
 			filteredFBC.Channels[channelIndex] = filteredChannel
+			for _, e := range ch.Entries {
+				if slices.Contains(chHeads, e.Name) {
+					report.entry(ch.Package, ch.Name, e.Name, catalogIndex.BundleVersionsByPkgAndName[ch.Package][e.Name], Kept, ReasonChannelHead)
+				} else {
+					report.entry(ch.Package, ch.Name, e.Name, catalogIndex.BundleVersionsByPkgAndName[ch.Package][e.Name], Removed, ReasonNotChannelHead)
+				}
+			}
 			if _, ok := keepBundles[ch.Package]; !ok {
 				keepBundles[ch.Package] = sets.New[string]()
 			}
-			keepBundles[ch.Package] = keepBundles[ch.Package].Insert(chHead)
+			keepBundles[ch.Package] = keepBundles[ch.Package].Insert(chHeads...)
 		}
 	}
 
@@ -201,6 +424,9 @@ func (f *mirrorFilter) FilterCatalog(ctx context.Context, fbc *declcfg.Declarati
 			return chanInStudy.Name == anEmptyChan.Name && chanInStudy.Package == anEmptyChan.Package
 		})
 	})
+	for _, ch := range emptyChannels {
+		catalogIndex.ChannelNames[ch.Package].Delete(ch.Name)
+	}
 
 	if len(keepBundles) > 0 {
 		filteredFBC.Bundles = []declcfg.Bundle{}
@@ -216,24 +442,97 @@ func (f *mirrorFilter) FilterCatalog(ctx context.Context, fbc *declcfg.Declarati
 
 		filterDeprecations(filteredFBC, catalogIndex, keepBundles)
 	}
+	if report != nil {
+		for pkg, bundles := range catalogIndex.BundlesByPkgAndName {
+			for name := range bundles {
+				version := catalogIndex.BundleVersionsByPkgAndName[pkg][name]
+				switch {
+				case len(keepBundles) == 0:
+					report.bundle(pkg, name, version, Kept, ReasonNoChannelFiltering)
+				case keepBundles[pkg].Has(name):
+					report.bundle(pkg, name, version, Kept, ReasonKeptInChannel)
+				default:
+					report.bundle(pkg, name, version, Removed, ReasonNotKeptInChannel)
+				}
+			}
+		}
+	}
+	if dependencies != nil {
+		if err := dependencies.resolve(filteredFBC); err != nil {
+			return nil, fmt.Errorf("unable to resolve dependencies: %v", err)
+		}
+	}
+	if err := validateFilteredCatalog(filteredFBC, catalogIndex.BundlesByPkgAndName, f.opts.MultipleHeads == filter.MultipleHeadsPolicyKeepAll); err != nil {
+		return nil, fmt.Errorf("filtered catalog is invalid: %w", err)
+	}
 	return filteredFBC, nil
 }
 
+// reportVersionRangeEntries records why each entry of ch was kept or removed by filterHeadsByVersionRange.
+func reportVersionRangeEntries(report *reportBuilder, ch declcfg.Channel, keepEntries sets.Set[string], versionRange *mmsemver.Constraints, versionMap map[string]*mmsemver.Version) {
+	if report == nil {
+		return
+	}
+	for _, e := range ch.Entries {
+		version := versionMap[e.Name]
+		inRange := version != nil && versionRange.Check(version)
+		switch {
+		case keepEntries.Has(e.Name) && inRange:
+			report.entry(ch.Package, ch.Name, e.Name, version, Kept, ReasonInVersionRange)
+		case keepEntries.Has(e.Name):
+			report.entry(ch.Package, ch.Name, e.Name, version, Kept, ReasonSingleHead)
+		case inRange:
+			report.entry(ch.Package, ch.Name, e.Name, version, Removed, ReasonNotInUpgradeChain)
+		default:
+			report.entry(ch.Package, ch.Name, e.Name, version, Removed, ReasonOutsideRange)
+		}
+	}
+}
+
 func (f *mirrorFilter) KeepMeta(meta *declcfg.Meta) bool {
-	if len(f.chConfigs) == 0 {
-		return false
+	if f.opts.Dependencies {
+		// required packages and GVKs are looked up in the whole catalog, which must not be pruned while it is read
+		return true
+	}
+	if len(f.chConfigs) == 0 && len(f.selectors) == 0 && len(f.excludePackages) == 0 && !f.skipDeprecated {
+		return f.drop(meta, ReasonPackageNotSelected)
 	}
 
 	packageName := meta.Package
 	if meta.Schema == "olm.package" {
 		packageName = meta.Name
+	} else if packageName == "" {
+		// meta objects that don't belong to any package are kept by FilterCatalog
+		return true
+	}
+
+	if f.excludePackages.Has(packageName) {
+		return f.drop(meta, ReasonPackageExcluded)
+	}
+	if len(f.selectors) > 0 || !f.selectsPackages() {
+		// the packages matched by selectors are only known once the whole catalog is loaded,
+		// and exclusions alone keep every package that is not excluded
+		return true
 	}
+	if _, ok := f.chConfigs[packageName]; !ok {
+		return f.drop(meta, ReasonPackageNotSelected)
+	}
+	return true
+}
 
-	_, ok := f.chConfigs[packageName]
-	return ok
+// drop records that KeepMeta drops meta for reason when meta is a package, for FilterCatalog to report it. It
+// returns false.
+func (f *mirrorFilter) drop(meta *declcfg.Meta, reason string) bool {
+	if meta.Schema == declcfg.SchemaPackage {
+		f.dropped.add(meta.Name, reason)
+	}
+	return false
 }
 
+// filterDeprecations removes the deprecation entries of the bundles and channels fbc no longer holds. The
+// deprecations are copied first, for the catalog fbc was filtered from to keep its own.
 func filterDeprecations(fbc *declcfg.DeclarativeConfig, index operatorIndex, keptBundles map[string]sets.Set[string]) *declcfg.DeclarativeConfig {
+	fbc.Deprecations = cloneDeprecations(fbc.Deprecations)
 	for i := range fbc.Deprecations {
 		fbc.Deprecations[i].Entries = slices.DeleteFunc(fbc.Deprecations[i].Entries, func(e declcfg.DeprecationEntry) bool {
 			if e.Reference.Schema == declcfg.SchemaBundle {
@@ -249,45 +548,123 @@ func filterDeprecations(fbc *declcfg.DeclarativeConfig, index operatorIndex, kep
 	}
 	return fbc
 }
-func (f *mirrorFilter) filterChannelHead(ch declcfg.Channel, index operatorIndex) (declcfg.Channel, string, error) {
-	filteringChannel, err := newChannel(ch, f.opts.Log)
+func (f *mirrorFilter) filterChannelHead(ch declcfg.Channel, index operatorIndex) (declcfg.Channel, []string, error) {
+	heads, err := f.channelHeads(ch)
 	if err != nil {
-		return declcfg.Channel{}, "", err
+		return declcfg.Channel{}, nil, err
+	}
+	var headNames []string
+	ch.Entries = nil
+	for _, c := range heads {
+		headNames = append(headNames, c.Head.Name)
+		ch.Entries = append(ch.Entries, index.ChannelEntries[ch.Package][ch.Name][c.Head.Name])
+	}
+	return ch, headNames, nil
+}
+
+// withSelectedPackages returns a copy of f that also keeps the selected packages which are not configured yet,
+// with all their channels.
+func (f *mirrorFilter) withSelectedPackages(selected sets.Set[string]) *mirrorFilter {
+	pkgConfigs := maps.Clone(f.pkgConfigs)
+	chConfigs := maps.Clone(f.chConfigs)
+	added := sets.New[string]()
+	for name := range selected {
+		if _, ok := pkgConfigs[name]; ok {
+			continue
+		}
+		pkgConfigs[name] = Package{Name: name}
+		chConfigs[name] = map[string]Channel{}
+		added.Insert(name)
+	}
+	selectedFilter := *f
+	selectedFilter.pkgConfigs = pkgConfigs
+	selectedFilter.chConfigs = chConfigs
+	selectedFilter.selectedPackages = added
+	return &selectedFilter
+}
+
+// configuredChannels returns the channels configured for each package, in order.
+func (f *mirrorFilter) configuredChannels() map[string][]Channel {
+	channels := make(map[string][]Channel, len(f.pkgConfigs))
+	for name, pkg := range f.pkgConfigs {
+		channels[name] = pkg.Channels
 	}
-	ch.Entries = []declcfg.ChannelEntry{index.ChannelEntries[ch.Package][ch.Name][filteringChannel.head.Name]}
-	return ch, filteringChannel.head.Name, nil
+	return channels
 }
 
-func setDefaultChannel(pkg *declcfg.Package, pkgConfig Package, channels sets.Set[string]) error {
+// setDefaultChannel updates the default channel of pkg to the one configured in pkgConfig or, when the original
+// default channel is not among channels, to the one selected by policy. It reports whether policy selected it.
+func setDefaultChannel(pkg *declcfg.Package, pkgConfig Package, policy filter.DefaultChannelPolicy, channels sets.Set[string], headVersion func(string) (*mmsemver.Version, error)) (bool, error) {
 
 	// If both the FBC and package config leave the default channel unspecified, then we don't need to do anything.
 	if pkg.DefaultChannel == "" && pkgConfig.DefaultChannel == "" {
-		return nil
+		return false, nil
 	}
 
 	// If the default channel was specified in the filter configuration, then we need to check if it exists after filtering.
 	// If it does, then we update the model's default channel to the specified channel. Otherwise, we error.
 	if pkgConfig.DefaultChannel != "" {
 		if !channels.Has(pkgConfig.DefaultChannel) {
-			return fmt.Errorf("specified default channel override %q does not exist in the filtered output", pkgConfig.DefaultChannel)
+			return false, fmt.Errorf("specified default channel override %q does not exist in the filtered output", pkgConfig.DefaultChannel)
 		}
 		pkg.DefaultChannel = pkgConfig.DefaultChannel
-		return nil
+		return false, nil
 	}
 
 	// At this point, we know that the default channel was not configured in the filter configuration for this package.
-	// If the original default channel does not exist after filtering, error
+	// If the original default channel does not exist after filtering, the policy selects a new one or errors
 	if !channels.Has(pkg.DefaultChannel) {
-		return fmt.Errorf("the default channel %q was filtered out, a new default channel must be configured for this package", pkg.DefaultChannel)
+		selected, err := policy.SelectDefaultChannel(channels, headVersion)
+		if err != nil {
+			return false, fmt.Errorf("the default channel %q was filtered out, %v", pkg.DefaultChannel, err)
+		}
+		pkg.DefaultChannel = selected
+		return true, nil
 	}
-	return nil
+	return false, nil
 }
 
-func (f *mirrorFilter) filterByPackageAndChannels(fbc, filteredFBC *declcfg.DeclarativeConfig) {
+// policyFor returns the default channel policy of pkgConfig, or the one of the configuration if it has none.
+func (f *mirrorFilter) policyFor(pkgConfig Package) filter.DefaultChannelPolicy {
+	if pkgConfig.DefaultChannelPolicy != "" {
+		return pkgConfig.DefaultChannelPolicy
+	}
+	return f.defaultChannelPolicy
+}
+
+// channelHeadVersion returns the version of the head of the channel named name of pkg in index, or of its
+// highest head when it has several.
+func (f *mirrorFilter) channelHeadVersion(index operatorIndex, pkg, name string) (*mmsemver.Version, error) {
+	for _, ch := range index.Channels[pkg] {
+		if ch.Name != name {
+			continue
+		}
+		heads, err := f.channelHeads(ch)
+		if err != nil {
+			return nil, err
+		}
+		c := graph.HighestHead(heads, index.BundleVersionsByPkgAndName[pkg])
+		version, ok := index.BundleVersionsByPkgAndName[pkg][c.Head.Name]
+		if !ok {
+			return nil, fmt.Errorf("bundle %q not found", c.Head.Name)
+		}
+		return version, nil
+	}
+	return nil, fmt.Errorf("channel not found")
+}
+
+func (f *mirrorFilter) filterByPackageAndChannels(fbc, filteredFBC *declcfg.DeclarativeConfig, report *reportBuilder) {
 	filteredFBC.Packages = []declcfg.Package{}
 	for _, pkg := range fbc.Packages {
 		if _, ok := f.chConfigs[pkg.Name]; ok {
 			filteredFBC.Packages = append(filteredFBC.Packages, pkg)
+			if f.selectedPackages.Has(pkg.Name) {
+				report.pkg(pkg.Name, Kept, ReasonPackageMatchesSelector)
+			} else {
+				report.pkg(pkg.Name, Kept, ReasonPackageSelected)
+			}
+		} else {
+			report.pkg(pkg.Name, Removed, ReasonPackageNotSelected)
 		}
 	}
 	filteredFBC.Channels = []declcfg.Channel{}
@@ -298,9 +675,13 @@ func (f *mirrorFilter) filterByPackageAndChannels(fbc, filteredFBC *declcfg.Decl
 				_, foundChannel := chSet[ch.Name]
 				if foundChannel {
 					filteredFBC.Channels = append(filteredFBC.Channels, ch)
+					report.channel(ch.Package, ch.Name, Kept, ReasonChannelSelected)
+				} else {
+					report.channel(ch.Package, ch.Name, Removed, ReasonChannelNotSelected)
 				}
 			} else {
 				filteredFBC.Channels = append(filteredFBC.Channels, ch)
+				report.channel(ch.Package, ch.Name, Kept, ReasonAllChannels)
 			}
 		}
 	}
@@ -8,6 +8,7 @@ import (
 	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -86,6 +87,12 @@ func TestFilter_KeepMeta(t *testing.T) {
 			meta:     &declcfg.Meta{Schema: "other", Package: "foo"},
 			expected: true,
 		},
+		{
+			name:     "KeepFooBar_OtherWithoutPackage",
+			filter:   NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo"}, {Name: "bar"}}}).(filter_package.MetaFilter),
+			meta:     &declcfg.Meta{Schema: "other"},
+			expected: true,
+		},
 		{
 			name:     "KeepBarBaz_Package",
 			filter:   NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "bar"}, {Name: "baz"}}}).(filter_package.MetaFilter),
@@ -116,6 +123,48 @@ func TestFilter_KeepMeta(t *testing.T) {
 			meta:     &declcfg.Meta{Schema: "other", Package: "foo"},
 			expected: false,
 		},
+		{
+			name:     "Selectors_Bundle",
+			filter:   NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "bar"}}, Selectors: []Selector{{Provider: "Red Hat"}}}).(filter_package.MetaFilter),
+			meta:     &declcfg.Meta{Schema: declcfg.SchemaBundle, Package: "foo"},
+			expected: true,
+		},
+		{
+			name:     "ExcludePackages_Excluded",
+			filter:   NewMirrorFilter(FilterConfiguration{ExcludePackages: []string{"foo"}}).(filter_package.MetaFilter),
+			meta:     &declcfg.Meta{Schema: declcfg.SchemaBundle, Package: "foo"},
+			expected: false,
+		},
+		{
+			name:     "ExcludePackages_Other",
+			filter:   NewMirrorFilter(FilterConfiguration{ExcludePackages: []string{"foo"}}).(filter_package.MetaFilter),
+			meta:     &declcfg.Meta{Schema: declcfg.SchemaBundle, Package: "bar"},
+			expected: true,
+		},
+		{
+			name:     "ExcludeBundlesOnly_Other",
+			filter:   NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo", ExcludeBundles: []string{"foo.v1"}}}}).(filter_package.MetaFilter),
+			meta:     &declcfg.Meta{Schema: declcfg.SchemaChannel, Package: "bar"},
+			expected: true,
+		},
+		{
+			name:     "SkipDeprecated_Channel",
+			filter:   NewMirrorFilter(FilterConfiguration{SkipDeprecated: true}).(filter_package.MetaFilter),
+			meta:     &declcfg.Meta{Schema: declcfg.SchemaChannel, Package: "foo"},
+			expected: true,
+		},
+		{
+			name:     "ExcludePackages_WithSelectedPackage",
+			filter:   NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "bar"}}, ExcludePackages: []string{"foo"}}).(filter_package.MetaFilter),
+			meta:     &declcfg.Meta{Schema: declcfg.SchemaChannel, Package: "baz"},
+			expected: false,
+		},
+		{
+			name:     "WithDependencies_OtherPackage",
+			filter:   NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "bar"}}}, WithDependencies(true)).(filter_package.MetaFilter),
+			meta:     &declcfg.Meta{Schema: declcfg.SchemaBundle, Package: "foo"},
+			expected: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -683,6 +732,57 @@ func TestFilter_FilterCatalog_WithLogger(t *testing.T) {
 	assert.Contains(t, logOutput.String(), `including bundle "b2" with version "2.0.0"`)
 }
 
+func TestFilter_FilterCatalog_DoesNotModifyInput(t *testing.T) {
+	catalog := func() *declcfg.DeclarativeConfig {
+		return &declcfg.DeclarativeConfig{
+			Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+			Channels: []declcfg.Channel{{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.1.0"},
+			}}},
+			Bundles: []declcfg.Bundle{
+				{Name: "foo.v1.0.0", Package: "foo", Properties: propertiesForBundle("foo", "1.0.0")},
+				{Name: "foo.v1.1.0", Package: "foo", Properties: propertiesForBundle("foo", "1.1.0")},
+				{Name: "foo.v2.0.0", Package: "foo", Properties: propertiesForBundle("foo", "2.0.0")},
+			},
+			Deprecations: []declcfg.Deprecation{{Package: "foo", Entries: []declcfg.DeprecationEntry{
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v1.0.0"}},
+				{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v2.0.0"}},
+			}}},
+		}
+	}
+	tests := []struct {
+		name   string
+		config FilterConfiguration
+	}{
+		{
+			name:   "WHEN deprecated bundles are filtered out THEN Leaves the deprecations untouched",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo"}}},
+		},
+		{
+			name:   "WHEN no package is selected THEN Leaves the catalog untouched",
+			config: FilterConfiguration{},
+		},
+		{
+			name:   "WHEN filtering by versionRange THEN Leaves the catalog untouched",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", VersionRange: ">=1.1.0"}}},
+		},
+		{
+			name:   "WHEN selecting bundles THEN Leaves the catalog untouched",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", SelectedBundles: []SelectedBundle{{Name: "foo.v1.1.0"}}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fbc := catalog()
+			_, err := NewMirrorFilter(tt.config, WithLogger(nullLogger())).FilterCatalog(context.Background(), fbc)
+			require.NoError(t, err)
+			assert.Equal(t, catalog(), fbc)
+		})
+	}
+}
+
 func propertiesForBundle(pkg, version string) []property.Property {
 	return []property.Property{
 		{Type: property.TypePackage, Value: []byte(fmt.Sprintf(`{"packageName": %q, "version": %q}`, pkg, version))},
@@ -696,3 +796,430 @@ func loadDeclarativeConfig(t *testing.T, fs embed.FS) *declcfg.DeclarativeConfig
 	}
 	return declCfg
 }
+
+// catalogFS returns a file system holding fbc as a catalog.json file, for the catalog to be streamed.
+func catalogFS(t *testing.T, fbc *declcfg.DeclarativeConfig) fstest.MapFS {
+	for i := range fbc.Packages {
+		fbc.Packages[i].Schema = declcfg.SchemaPackage
+	}
+	for i := range fbc.Channels {
+		fbc.Channels[i].Schema = declcfg.SchemaChannel
+	}
+	for i := range fbc.Bundles {
+		fbc.Bundles[i].Schema = declcfg.SchemaBundle
+	}
+	for i := range fbc.Deprecations {
+		fbc.Deprecations[i].Schema = declcfg.SchemaDeprecation
+	}
+	data := &bytes.Buffer{}
+	require.NoError(t, declcfg.WriteJSON(*fbc, data))
+	return fstest.MapFS{"catalog.json": &fstest.MapFile{Data: data.Bytes()}}
+}
+
+func TestFilter_FilterCatalog_VersionBounds(t *testing.T) {
+	catalog := func() *declcfg.DeclarativeConfig {
+		return &declcfg.DeclarativeConfig{
+			Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+			Channels: []declcfg.Channel{
+				{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+					{Name: "foo.v1.0.0"},
+					{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+					{Name: "foo.v2.0.0", Replaces: "foo.v1.1.0"},
+					{Name: "foo.v3.0.0", Replaces: "foo.v2.0.0"},
+				}},
+				{Name: "candidate", Package: "foo", Entries: []declcfg.ChannelEntry{
+					{Name: "foo.v1.0.0"},
+					{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				}},
+			},
+			Bundles: []declcfg.Bundle{
+				{Name: "foo.v1.0.0", Package: "foo", Image: "quay.io/foo/foo.v1.0.0", Properties: propertiesForBundle("foo", "1.0.0")},
+				{Name: "foo.v1.1.0", Package: "foo", Image: "quay.io/foo/foo.v1.1.0", Properties: propertiesForBundle("foo", "1.1.0")},
+				{Name: "foo.v2.0.0", Package: "foo", Image: "quay.io/foo/foo.v2.0.0", Properties: propertiesForBundle("foo", "2.0.0")},
+				{Name: "foo.v3.0.0", Package: "foo", Image: "quay.io/foo/foo.v3.0.0", Properties: propertiesForBundle("foo", "3.0.0")},
+			},
+		}
+	}
+	tests := []struct {
+		name      string
+		config    FilterConfiguration
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, error)
+	}{
+		{
+			name:   "WHEN filter on 1 channel with minVersion and maxVersion THEN Returns the channel with all bundles within bounds",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", Channels: []Channel{{Name: "stable", MinVersion: "1.1.0", MaxVersion: "2.0.0"}}}}},
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				require.Len(t, actual.Channels, 1)
+				assert.Equal(t, "stable", actual.Channels[0].Name)
+				assert.Equal(t, []string{"foo.v1.1.0", "foo.v2.0.0"}, bundleNamesOf(actual))
+				_, validationError := declcfg.ConvertToModel(*actual)
+				assert.NoError(t, validationError)
+			},
+		},
+		{
+			name:   "WHEN filter on 1 package with minVersion THEN Returns the channels in range with all bundles from min to head",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", MinVersion: "2.0.0"}}},
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				require.Len(t, actual.Channels, 1)
+				assert.Equal(t, "stable", actual.Channels[0].Name)
+				assert.Equal(t, []string{"foo.v2.0.0", "foo.v3.0.0"}, bundleNamesOf(actual))
+				_, validationError := declcfg.ConvertToModel(*actual)
+				assert.NoError(t, validationError)
+			},
+		},
+		{
+			name:   "WHEN filter on 1 package with maxVersion THEN Returns the bundles up to max in each channel",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", MaxVersion: "1.1.0"}}},
+			assertion: func(t *testing.T, actual *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Len(t, actual.Channels, 2)
+				assert.Equal(t, []string{"foo.v1.0.0", "foo.v1.1.0"}, bundleNamesOf(actual))
+				_, validationError := declcfg.ConvertToModel(*actual)
+				assert.NoError(t, validationError)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := NewMirrorFilter(tt.config).FilterCatalog(context.Background(), catalog())
+			tt.assertion(t, actual, err)
+		})
+	}
+}
+
+func TestFilter_FilterCatalog_ShortestPath(t *testing.T) {
+	catalog := func() *declcfg.DeclarativeConfig {
+		return &declcfg.DeclarativeConfig{
+			Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+			Channels: []declcfg.Channel{{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.3.0", Replaces: "foo.v1.2.0", Skips: []string{"foo.v1.1.0"}},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.0.0"},
+			}}},
+			Bundles: []declcfg.Bundle{
+				{Name: "foo.v1.0.0", Package: "foo", Properties: propertiesForBundle("foo", "1.0.0")},
+				{Name: "foo.v1.1.0", Package: "foo", Properties: propertiesForBundle("foo", "1.1.0")},
+				{Name: "foo.v1.2.0", Package: "foo", Properties: propertiesForBundle("foo", "1.2.0")},
+				{Name: "foo.v1.3.0", Package: "foo", Properties: propertiesForBundle("foo", "1.3.0")},
+			},
+		}
+	}
+	tests := []struct {
+		name      string
+		config    FilterConfiguration
+		options   []FilterOption
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, FilterReport, error)
+	}{
+		{
+			name:   "WHEN channel has shortestPath and minVersion THEN Returns the shortest upgrade path from minVersion to head",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", Channels: []Channel{{Name: "stable", MinVersion: "1.1.0", ShortestPath: true}}}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.ChannelEntry{
+					{Name: "foo.v1.3.0", Skips: []string{"foo.v1.1.0"}},
+					{Name: "foo.v1.1.0"},
+				}, out.Channels[0].Entries)
+				assert.Equal(t, []string{"foo.v1.1.0", "foo.v1.3.0"}, bundleNamesOf(out))
+				foo, _ := report.Package("foo")
+				stable, _ := foo.Channel("stable")
+				assert.Contains(t, stable.Entries, BundleReport{Name: "foo.v1.2.0", Version: "1.2.0", Decision: Removed, Reason: ReasonNotShortestPath})
+			},
+		},
+		{
+			name:   "WHEN package has shortestPath without versions THEN Returns the shortest upgrade path from the lowest version to head",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", ShortestPath: true}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.ChannelEntry{
+					{Name: "foo.v1.3.0", Skips: []string{"foo.v1.1.0"}},
+					{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+					{Name: "foo.v1.0.0"},
+				}, out.Channels[0].Entries)
+			},
+		},
+		{
+			name:    "WHEN shortestPath AND full:true THEN Returns error",
+			config:  FilterConfiguration{Packages: []Package{{Name: "foo", ShortestPath: true}}},
+			options: []FilterOption{InFull(true)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				assert.Nil(t, out)
+				assert.EqualError(t, err, "Full: true cannot be mixed with shortestPath")
+			},
+		},
+		{
+			name:   "WHEN shortestPath range excludes all entries of the default channel THEN Returns error",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", MinVersion: "2.0.0", ShortestPath: true}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				assert.Nil(t, out)
+				assert.EqualError(t, err, `package "foo" channel "stable" has no shortest upgrade path in version range ">=2.0.0", which results in an empty channel`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := FilterReport{}
+			f := NewMirrorFilter(tt.config, append(tt.options, WithReport(&report))...)
+			out, err := f.FilterCatalog(context.Background(), catalog())
+			tt.assertion(t, out, report, err)
+		})
+	}
+}
+
+func TestFilter_FilterCatalog_ChannelPatterns(t *testing.T) {
+	catalog := func() *declcfg.DeclarativeConfig {
+		return &declcfg.DeclarativeConfig{
+			Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable-4.15"}},
+			Channels: []declcfg.Channel{
+				{Name: "stable-4.14", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"}, {Name: "foo.v1.0.0"}}},
+				{Name: "stable-4.15", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.1.0"}}},
+				{Name: "fast-4.15", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.1.0"}}},
+			},
+			Bundles: []declcfg.Bundle{
+				{Name: "foo.v1.0.0", Package: "foo", Properties: propertiesForBundle("foo", "1.0.0")},
+				{Name: "foo.v1.1.0", Package: "foo", Properties: propertiesForBundle("foo", "1.1.0")},
+			},
+		}
+	}
+	tests := []struct {
+		name      string
+		config    FilterConfiguration
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, error)
+	}{
+		{
+			name: "WHEN channels are selected by glob THEN Returns all matching channels with the pattern's configuration",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", Channels: []Channel{
+				{Name: "stable-*", MinVersion: "1.0.0"},
+			}}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.Channel{
+					{Name: "stable-4.14", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"}, {Name: "foo.v1.0.0"}}},
+					{Name: "stable-4.15", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.1.0"}}},
+				}, out.Channels)
+			},
+		},
+		{
+			name: "WHEN a channel is selected by name and regular expression THEN Returns it with the configuration of its name",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", Channels: []Channel{
+				{Name: `re:^stable-4\.1[45]$`, MinVersion: "1.0.0"},
+				{Name: "stable-4.14"},
+			}}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.Channel{
+					{Name: "stable-4.14", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"}}},
+					{Name: "stable-4.15", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v1.1.0"}}},
+				}, out.Channels)
+			},
+		},
+		{
+			name: "WHEN a pattern matches no channel THEN Returns error",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", Channels: []Channel{
+				{Name: "stable-*"},
+				{Name: "candidate-*"},
+			}}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, err error) {
+				assert.Nil(t, out)
+				assert.EqualError(t, err, `package "foo" channel "candidate-*": pattern matches no channel of the catalog`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewMirrorFilter(tt.config)
+			out, err := f.FilterCatalog(context.Background(), catalog())
+			tt.assertion(t, out, err)
+		})
+	}
+}
+
+func TestFilter_FilterCatalog_DefaultChannelPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    FilterConfiguration
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, FilterReport, error)
+	}{
+		{
+			name: "WHEN the default channel is filtered out without policy THEN Returns error",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "foo", Channels: []Channel{{Name: "fast"}}},
+			}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				assert.Nil(t, out)
+				assert.ErrorContains(t, err, `the default channel "stable" was filtered out, a new default channel must be configured for this package`)
+			},
+		},
+		{
+			name: "WHEN the default channel is filtered out with a policy THEN Returns the selected default channel and reports it",
+			config: FilterConfiguration{
+				Packages:             []Package{{Name: "foo", ExcludeChannels: []string{"stable"}}},
+				DefaultChannelPolicy: filter_package.DefaultChannelPolicyHighestVersion,
+			},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.Package{{Name: "foo", DefaultChannel: "fast"}, {Name: "bar", DefaultChannel: "stable"}}, out.Packages)
+				foo, _ := report.Package("foo")
+				assert.Equal(t, "fast", foo.DefaultChannel)
+				bar, _ := report.Package("bar")
+				assert.Empty(t, bar.DefaultChannel)
+			},
+		},
+		{
+			name: "WHEN the package policy finds no channel THEN Returns error",
+			config: FilterConfiguration{
+				Packages: []Package{
+					{Name: "foo", Channels: []Channel{{Name: "fast"}}, DefaultChannelPolicy: filter_package.DefaultChannelPolicyStable},
+				},
+				DefaultChannelPolicy: filter_package.DefaultChannelPolicyHighestVersion,
+			},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				assert.Nil(t, out)
+				assert.ErrorContains(t, err, `no remaining channel matches the "stable" default channel policy`)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := FilterReport{}
+			f := NewMirrorFilter(tt.config, WithReport(&report))
+			out, err := f.FilterCatalog(context.Background(), excludeTestCatalog())
+			tt.assertion(t, out, report, err)
+		})
+	}
+}
+
+func multipleHeadsTestCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v2.0.0", Skips: []string{"foo.v1.0.0"}},
+				{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
+			}},
+		},
+		Bundles: []declcfg.Bundle{
+			{Name: "foo.v1.0.0", Package: "foo", Properties: propertiesForBundle("foo", "1.0.0")},
+			{Name: "foo.v1.1.0", Package: "foo", Properties: propertiesForBundle("foo", "1.1.0")},
+			{Name: "foo.v2.0.0", Package: "foo", Properties: propertiesForBundle("foo", "2.0.0")},
+			{Name: "foo.v2.1.0", Package: "foo", Properties: propertiesForBundle("foo", "2.1.0")},
+		},
+	}
+}
+
+func TestFilter_FilterCatalog_MultipleHeads(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    FilterConfiguration
+		options   []FilterOption
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, FilterReport, error)
+	}{
+		{
+			name:   "WHEN no multiple heads policy THEN Returns error",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo"}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				assert.Nil(t, out)
+				assert.ErrorContains(t, err, `multiple channel heads found: [foo.v1.1.0 foo.v2.1.0]`)
+			},
+		},
+		{
+			name:    "WHEN unknown multiple heads policy THEN Returns error",
+			config:  FilterConfiguration{Packages: []Package{{Name: "foo"}}},
+			options: []FilterOption{WithMultipleHeads("keepOne")},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				assert.Nil(t, out)
+				assert.ErrorContains(t, err, `unknown multiple heads policy "keepOne"`)
+			},
+		},
+		{
+			name:    "WHEN keeping all heads THEN Returns all the channel heads",
+			config:  FilterConfiguration{Packages: []Package{{Name: "foo"}}},
+			options: []FilterOption{WithMultipleHeads(filter_package.MultipleHeadsPolicyKeepAll)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.ChannelEntry{
+					{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+					{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
+				}, out.Channels[0].Entries)
+				assert.Equal(t, []string{"foo.v1.1.0", "foo.v2.1.0"}, bundleNamesOf(out))
+				foo, _ := report.Package("foo")
+				stable, _ := foo.Channel("stable")
+				assert.Contains(t, stable.Entries, BundleReport{Name: "foo.v1.1.0", Version: "1.1.0", Decision: Kept, Reason: ReasonChannelHead})
+				assert.Contains(t, stable.Entries, BundleReport{Name: "foo.v2.1.0", Version: "2.1.0", Decision: Kept, Reason: ReasonChannelHead})
+			},
+		},
+		{
+			name:    "WHEN keeping all heads with a versionRange THEN Returns the entries in range below each head",
+			config:  FilterConfiguration{Packages: []Package{{Name: "foo", VersionRange: ">=1.1.0 <2.1.0"}}},
+			options: []FilterOption{WithMultipleHeads(filter_package.MultipleHeadsPolicyKeepAll)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []string{"foo.v1.1.0", "foo.v2.0.0"}, bundleNamesOf(out))
+			},
+		},
+		{
+			name:    "WHEN keeping all heads with shortestPath THEN Returns the shortest upgrade path to each head",
+			config:  FilterConfiguration{Packages: []Package{{Name: "foo", ShortestPath: true}}},
+			options: []FilterOption{WithMultipleHeads(filter_package.MultipleHeadsPolicyKeepAll)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.ChannelEntry{
+					{Name: "foo.v1.0.0"},
+					{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+					{Name: "foo.v2.0.0", Skips: []string{"foo.v1.0.0"}},
+					{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
+				}, out.Channels[0].Entries)
+				foo, _ := report.Package("foo")
+				stable, _ := foo.Channel("stable")
+				assert.Contains(t, stable.Entries, BundleReport{Name: "foo.v1.1.0", Version: "1.1.0", Decision: Kept, Reason: ReasonShortestPath})
+				assert.Contains(t, stable.Entries, BundleReport{Name: "foo.v2.1.0", Version: "2.1.0", Decision: Kept, Reason: ReasonShortestPath})
+			},
+		},
+		{
+			name:    "WHEN keeping all heads with shortestPath and a versionRange THEN Returns the paths of the heads that reach the range",
+			config:  FilterConfiguration{Packages: []Package{{Name: "foo", ShortestPath: true, VersionRange: ">=2.0.0"}}},
+			options: []FilterOption{WithMultipleHeads(filter_package.MultipleHeadsPolicyKeepAll)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.ChannelEntry{
+					{Name: "foo.v2.0.0"},
+					{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
+				}, out.Channels[0].Entries)
+			},
+		},
+		{
+			name:   "WHEN shortestPath without multiple heads policy THEN Returns error",
+			config: FilterConfiguration{Packages: []Package{{Name: "foo", ShortestPath: true}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				assert.Nil(t, out)
+				assert.ErrorContains(t, err, `multiple channel heads found: [foo.v1.1.0 foo.v2.1.0]`)
+			},
+		},
+		{
+			name:    "WHEN keeping the highest head THEN Returns the entries the highest head upgrades from",
+			config:  FilterConfiguration{Packages: []Package{{Name: "foo"}}},
+			options: []FilterOption{WithMultipleHeads(filter_package.MultipleHeadsPolicyKeepHighest), InFull(true)},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.ChannelEntry{
+					{Name: "foo.v1.0.0"},
+					{Name: "foo.v2.0.0", Skips: []string{"foo.v1.0.0"}},
+					{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
+				}, out.Channels[0].Entries)
+				assert.Equal(t, []string{"foo.v1.0.0", "foo.v2.0.0", "foo.v2.1.0"}, bundleNamesOf(out))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := FilterReport{}
+			f := NewMirrorFilter(tt.config, append(tt.options, WithReport(&report))...)
+			out, err := f.FilterCatalog(context.Background(), multipleHeadsTestCatalog())
+			tt.assertion(t, out, report, err)
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package v1alpha1
+
+import (
+	"slices"
+	"strings"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+)
+
+// Decision is the outcome of the filtering for a package, a channel or a bundle.
+type Decision string
+
+const (
+	Kept    Decision = "kept"
+	Removed Decision = "removed"
+)
+
+// Reasons recorded in a FilterReport.
+const (
+	ReasonPackageSelected        = "selected package"
+	ReasonPackageMatchesSelector = "matches a package selector"
+	ReasonPackageExcluded        = "excluded package"
+	ReasonPackageNotSelected     = "package not selected"
+	ReasonAllPackages            = "no package filtering configured"
+
+	ReasonChannelSelected    = "selected channel"
+	ReasonChannelNotSelected = "channel not selected"
+	ReasonAllChannels        = "no channel filtering configured"
+	ReasonEmptyChannel       = "no entries left after filtering"
+	ReasonChannelExcluded    = "excluded channel"
+	ReasonChannelDeprecated  = "deprecated channel"
+
+	ReasonChannelHead        = "channel head"
+	ReasonNotChannelHead     = "not channel head"
+	ReasonInFull             = "full channel requested"
+	ReasonInVersionRange     = "within versionRange"
+	ReasonOutsideRange       = "outside versionRange"
+	ReasonSingleHead         = "required to preserve single head"
+	ReasonNotInUpgradeChain  = "not on the upgrade chain of the filtered channel head"
+	ReasonShortestPath       = "on the shortest upgrade path"
+	ReasonNotShortestPath    = "not on the shortest upgrade path"
+	ReasonSelectedBundle     = "selected bundle"
+	ReasonNotSelectedBundle  = "not a selected bundle"
+	ReasonKeptInChannel      = "kept in at least one channel"
+	ReasonNotKeptInChannel   = "not kept in any channel"
+	ReasonNoChannelFiltering = "package has no channels to filter"
+	ReasonDependency         = "required by a kept bundle"
+	ReasonBundleExcluded     = "excluded bundle"
+	ReasonBundleDeprecated   = "deprecated bundle"
+)
+
+// FilterReport explains why each package, channel, channel entry and bundle was kept or removed
+// by FilterCatalog. It is filled when the filter is created with the WithReport option.
+type FilterReport struct {
+	Packages []PackageReport `json:"packages"`
+
+	// Dependencies lists the bundles added by WithDependencies, and the requirement each of them satisfies.
+	Dependencies []DependencyReport `json:"dependencies,omitempty"`
+}
+
+type PackageReport struct {
+	Name     string   `json:"name"`
+	Decision Decision `json:"decision"`
+	Reason   string   `json:"reason"`
+
+	// DefaultChannel is the channel selected by the default channel policy, when the original default channel
+	// of the package was filtered out.
+	DefaultChannel string `json:"defaultChannel,omitempty"`
+
+	// Channels lists the channels of a kept package, with the decision for each of their entries.
+	Channels []ChannelReport `json:"channels,omitempty"`
+	// Bundles lists the bundles of a kept package.
+	Bundles []BundleReport `json:"bundles,omitempty"`
+}
+
+type ChannelReport struct {
+	Name     string   `json:"name"`
+	Decision Decision `json:"decision"`
+	Reason   string   `json:"reason"`
+
+	Entries []BundleReport `json:"entries,omitempty"`
+}
+
+type BundleReport struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version,omitempty"`
+	Decision Decision `json:"decision"`
+	Reason   string   `json:"reason"`
+}
+
+type DependencyReport struct {
+	// Package and Bundle identify the bundle that was added to the filtered catalog.
+	Package string `json:"package"`
+	Bundle  string `json:"bundle"`
+	// RequiredByPackage and RequiredByBundle identify the kept bundle declaring the requirement.
+	RequiredByPackage string `json:"requiredByPackage"`
+	RequiredByBundle  string `json:"requiredByBundle"`
+	// Requirement describes the olm.package.required or olm.gvk.required property that is satisfied.
+	Requirement string `json:"requirement"`
+}
+
+// Package returns the report of the package named name, if any.
+func (r *FilterReport) Package(name string) (PackageReport, bool) {
+	for _, p := range r.Packages {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return PackageReport{}, false
+}
+
+// Channel returns the report of the channel named name, if any.
+func (r PackageReport) Channel(name string) (ChannelReport, bool) {
+	for _, c := range r.Channels {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return ChannelReport{}, false
+}
+
+// reportBuilder collects decisions while FilterCatalog runs.
+// A nil reportBuilder discards them, so that filtering without report costs nothing.
+type reportBuilder struct {
+	packages     map[string]*PackageReport
+	channels     map[string]map[string]*ChannelReport
+	dependencies []DependencyReport
+}
+
+func newReportBuilder() *reportBuilder {
+	return &reportBuilder{
+		packages: make(map[string]*PackageReport),
+		channels: make(map[string]map[string]*ChannelReport),
+	}
+}
+
+func (b *reportBuilder) pkg(name string, decision Decision, reason string) {
+	if b == nil {
+		return
+	}
+	p, ok := b.packages[name]
+	if !ok {
+		p = &PackageReport{Name: name}
+		b.packages[name] = p
+	}
+	p.Decision = decision
+	p.Reason = reason
+}
+
+func (b *reportBuilder) defaultChannel(pkg, name string) {
+	if b == nil {
+		return
+	}
+	if p, ok := b.packages[pkg]; ok {
+		p.DefaultChannel = name
+	}
+}
+
+func (b *reportBuilder) channel(pkg, name string, decision Decision, reason string) {
+	if b == nil {
+		return
+	}
+	if _, ok := b.channels[pkg]; !ok {
+		b.channels[pkg] = make(map[string]*ChannelReport)
+	}
+	c, ok := b.channels[pkg][name]
+	if !ok {
+		c = &ChannelReport{Name: name}
+		b.channels[pkg][name] = c
+	}
+	c.Decision = decision
+	c.Reason = reason
+}
+
+func (b *reportBuilder) entry(pkg, channel, name string, version *mmsemver.Version, decision Decision, reason string) {
+	if b == nil {
+		return
+	}
+	c, ok := b.channels[pkg][channel]
+	if !ok {
+		return
+	}
+	c.Entries = upsertBundleReport(c.Entries, bundleReport(name, version, decision, reason))
+}
+
+func (b *reportBuilder) bundle(pkg, name string, version *mmsemver.Version, decision Decision, reason string) {
+	if b == nil {
+		return
+	}
+	p, ok := b.packages[pkg]
+	if !ok {
+		return
+	}
+	p.Bundles = upsertBundleReport(p.Bundles, bundleReport(name, version, decision, reason))
+}
+
+func (b *reportBuilder) dependency(dep DependencyReport) {
+	if b == nil {
+		return
+	}
+	b.dependencies = append(b.dependencies, dep)
+}
+
+func (b *reportBuilder) report() FilterReport {
+	report := FilterReport{Packages: []PackageReport{}}
+	for name, p := range b.packages {
+		for _, c := range b.channels[name] {
+			slices.SortFunc(c.Entries, compareBundleReports)
+			p.Channels = append(p.Channels, *c)
+		}
+		slices.SortFunc(p.Channels, func(a, b ChannelReport) int { return strings.Compare(a.Name, b.Name) })
+		slices.SortFunc(p.Bundles, compareBundleReports)
+		report.Packages = append(report.Packages, *p)
+	}
+	slices.SortFunc(report.Packages, func(a, b PackageReport) int { return strings.Compare(a.Name, b.Name) })
+	report.Dependencies = b.dependencies
+	return report
+}
+
+func bundleReport(name string, version *mmsemver.Version, decision Decision, reason string) BundleReport {
+	r := BundleReport{Name: name, Decision: decision, Reason: reason}
+	if version != nil {
+		r.Version = version.String()
+	}
+	return r
+}
+
+// upsertBundleReport replaces the report of the same bundle in reports, if any, so that the latest decision wins.
+func upsertBundleReport(reports []BundleReport, r BundleReport) []BundleReport {
+	i := slices.IndexFunc(reports, func(existing BundleReport) bool { return existing.Name == r.Name })
+	if i < 0 {
+		return append(reports, r)
+	}
+	reports[i] = r
+	return reports
+}
+
+func compareBundleReports(a, b BundleReport) int {
+	return strings.Compare(a.Name, b.Name)
+}
@@ -0,0 +1,211 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+
+	filter_package "github.com/sherine-k/catalog-filter/pkg/filter"
+)
+
+func reportTestCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}, {Name: "bar", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{
+			{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v3", Replaces: "foo.v2"},
+				{Name: "foo.v2", Replaces: "foo.v1"},
+				{Name: "foo.v1"},
+			}},
+			{Name: "beta", Package: "foo", Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v3"},
+			}},
+			{Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{
+				{Name: "bar.v1"},
+			}},
+		},
+		Bundles: []declcfg.Bundle{
+			{Name: "foo.v1", Package: "foo", Properties: propertiesForBundle("foo", "1.0.0")},
+			{Name: "foo.v2", Package: "foo", Properties: propertiesForBundle("foo", "2.0.0")},
+			{Name: "foo.v3", Package: "foo", Properties: propertiesForBundle("foo", "3.0.0")},
+			{Name: "bar.v1", Package: "bar", Properties: propertiesForBundle("bar", "1.0.0")},
+		},
+	}
+}
+
+func TestFilter_FilterCatalog_WithReport(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    FilterConfiguration
+		assertion func(*testing.T, FilterReport)
+	}{
+		{
+			name:   "WHEN empty config THEN reports channel heads",
+			config: FilterConfiguration{},
+			assertion: func(t *testing.T, report FilterReport) {
+				foo, ok := report.Package("foo")
+				require.True(t, ok)
+				assert.Equal(t, Kept, foo.Decision)
+				assert.Equal(t, ReasonAllPackages, foo.Reason)
+				stable, ok := foo.Channel("stable")
+				require.True(t, ok)
+				assert.Equal(t, []BundleReport{
+					{Name: "foo.v1", Version: "1.0.0", Decision: Removed, Reason: ReasonNotChannelHead},
+					{Name: "foo.v2", Version: "2.0.0", Decision: Removed, Reason: ReasonNotChannelHead},
+					{Name: "foo.v3", Version: "3.0.0", Decision: Kept, Reason: ReasonChannelHead},
+				}, stable.Entries)
+				assert.Equal(t, []BundleReport{
+					{Name: "foo.v1", Version: "1.0.0", Decision: Removed, Reason: ReasonNotKeptInChannel},
+					{Name: "foo.v2", Version: "2.0.0", Decision: Removed, Reason: ReasonNotKeptInChannel},
+					{Name: "foo.v3", Version: "3.0.0", Decision: Kept, Reason: ReasonKeptInChannel},
+				}, foo.Bundles)
+			},
+		},
+		{
+			name: "WHEN filter on 1 package and 1 channel with versionRange THEN reports removed package, channel and out of range bundles",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "foo", Channels: []Channel{{Name: "stable", VersionRange: ">=1.0.0 <2.0.0"}}},
+			}},
+			assertion: func(t *testing.T, report FilterReport) {
+				bar, ok := report.Package("bar")
+				require.True(t, ok)
+				assert.Equal(t, PackageReport{Name: "bar", Decision: Removed, Reason: ReasonPackageNotSelected}, bar)
+
+				foo, ok := report.Package("foo")
+				require.True(t, ok)
+				assert.Equal(t, ReasonPackageSelected, foo.Reason)
+				beta, ok := foo.Channel("beta")
+				require.True(t, ok)
+				assert.Equal(t, ChannelReport{Name: "beta", Decision: Removed, Reason: ReasonChannelNotSelected}, beta)
+				stable, ok := foo.Channel("stable")
+				require.True(t, ok)
+				assert.Equal(t, Kept, stable.Decision)
+				assert.Equal(t, []BundleReport{
+					{Name: "foo.v1", Version: "1.0.0", Decision: Kept, Reason: ReasonInVersionRange},
+					{Name: "foo.v2", Version: "2.0.0", Decision: Removed, Reason: ReasonOutsideRange},
+					{Name: "foo.v3", Version: "3.0.0", Decision: Removed, Reason: ReasonOutsideRange},
+				}, stable.Entries)
+			},
+		},
+		{
+			name: "WHEN filter by selected bundles THEN reports selected bundles and empty channels",
+			config: FilterConfiguration{Packages: []Package{
+				{Name: "foo", DefaultChannel: "stable", SelectedBundles: []SelectedBundle{{Name: "foo.v2"}}},
+			}},
+			assertion: func(t *testing.T, report FilterReport) {
+				foo, _ := report.Package("foo")
+				beta, ok := foo.Channel("beta")
+				require.True(t, ok)
+				assert.Equal(t, Removed, beta.Decision)
+				assert.Equal(t, ReasonEmptyChannel, beta.Reason)
+				stable, _ := foo.Channel("stable")
+				assert.Equal(t, []BundleReport{
+					{Name: "foo.v1", Version: "1.0.0", Decision: Removed, Reason: ReasonNotSelectedBundle},
+					{Name: "foo.v2", Version: "2.0.0", Decision: Kept, Reason: ReasonSelectedBundle},
+					{Name: "foo.v3", Version: "3.0.0", Decision: Removed, Reason: ReasonNotSelectedBundle},
+				}, stable.Entries)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := FilterReport{}
+			f := NewMirrorFilter(tt.config, WithReport(&report))
+			_, err := f.FilterCatalog(context.Background(), reportTestCatalog())
+			require.NoError(t, err)
+			tt.assertion(t, report)
+		})
+	}
+}
+
+func TestFilter_FilterCatalog_WithReport_SingleHead(t *testing.T) {
+	report := FilterReport{}
+	f := NewMirrorFilter(FilterConfiguration{Packages: []Package{
+		{Name: "pkg", Channels: []Channel{{Name: "ch", VersionRange: ">=1.0.0 <2.0.0"}}},
+	}}, WithReport(&report))
+	_, err := f.FilterCatalog(context.Background(), &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Name: "pkg", DefaultChannel: "ch"}},
+		Channels: []declcfg.Channel{{Name: "ch", Package: "pkg", Entries: []declcfg.ChannelEntry{
+			{Name: "b2", Skips: []string{"b1"}},
+			{Name: "b1"},
+		}}},
+		Bundles: []declcfg.Bundle{
+			{Name: "b1", Package: "pkg", Properties: propertiesForBundle("pkg", "1.0.0")},
+			{Name: "b2", Package: "pkg", Properties: propertiesForBundle("pkg", "2.0.0")},
+		},
+	})
+	require.NoError(t, err)
+	pkg, _ := report.Package("pkg")
+	ch, ok := pkg.Channel("ch")
+	require.True(t, ok)
+	assert.Equal(t, []BundleReport{
+		{Name: "b1", Version: "1.0.0", Decision: Kept, Reason: ReasonInVersionRange},
+		{Name: "b2", Version: "2.0.0", Decision: Kept, Reason: ReasonSingleHead},
+	}, ch.Entries)
+}
+
+func TestFilter_FilterCatalog_WithReport_OnError(t *testing.T) {
+	report := FilterReport{}
+	f := NewMirrorFilter(FilterConfiguration{Packages: []Package{
+		{Name: "foo", Channels: []Channel{{Name: "beta"}}},
+	}}, WithReport(&report))
+	_, err := f.FilterCatalog(context.Background(), reportTestCatalog())
+	require.Error(t, err)
+	foo, ok := report.Package("foo")
+	require.True(t, ok)
+	stable, ok := foo.Channel("stable")
+	require.True(t, ok)
+	assert.Equal(t, Removed, stable.Decision)
+}
+
+func TestFilterFS_WithReport(t *testing.T) {
+	report := FilterReport{}
+	f := NewMirrorFilter(FilterConfiguration{
+		Packages:        []Package{{Name: "foo"}},
+		ExcludePackages: []string{"baz"},
+	}, WithReport(&report))
+	fbc := reportTestCatalog()
+	fbc.Packages = append(fbc.Packages, declcfg.Package{Name: "baz"})
+	_, err := filter_package.FilterFS(context.Background(), catalogFS(t, fbc), f)
+	require.NoError(t, err)
+
+	bar, ok := report.Package("bar")
+	require.True(t, ok, "packages dropped while the catalog is streamed are reported")
+	assert.Equal(t, Removed, bar.Decision)
+	assert.Equal(t, ReasonPackageNotSelected, bar.Reason)
+	baz, ok := report.Package("baz")
+	require.True(t, ok)
+	assert.Equal(t, ReasonPackageExcluded, baz.Reason)
+	foo, ok := report.Package("foo")
+	require.True(t, ok)
+	assert.Equal(t, Kept, foo.Decision)
+
+	_, err = f.FilterCatalog(context.Background(), reportTestCatalog())
+	require.NoError(t, err)
+	_, ok = report.Package("baz")
+	assert.False(t, ok, "packages dropped while streaming are only reported once")
+}
+
+func TestFilter_FilterCatalog_WithReport_Reused(t *testing.T) {
+	report := FilterReport{}
+	f := NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo"}}}, WithReport(&report))
+	metaFilter, ok := f.(filter_package.MetaFilter)
+	require.True(t, ok)
+	// a stream that stops before its catalog is filtered
+	assert.False(t, metaFilter.KeepMeta(&declcfg.Meta{Schema: declcfg.SchemaPackage, Name: "bar"}))
+	_, err := f.FilterCatalog(context.Background(), nil)
+	require.NoError(t, err)
+
+	fbc := reportTestCatalog()
+	fbc.Packages = fbc.Packages[:1]
+	fbc.Channels = fbc.Channels[:2]
+	fbc.Bundles = fbc.Bundles[:3]
+	_, err = f.FilterCatalog(context.Background(), fbc)
+	require.NoError(t, err)
+	_, ok = report.Package("bar")
+	assert.False(t, ok, "packages dropped by an earlier run are not reported")
+}
@@ -0,0 +1,90 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/sherine-k/catalog-filter/pkg/filter"
+	"github.com/sherine-k/catalog-filter/pkg/schema"
+)
+
+// JSONSchema returns the JSON Schema of FilterConfiguration. It covers the rules of Validate that do not depend on
+// other packages of the configuration, such as the exclusive settings of a package.
+func JSONSchema() *schema.Schema {
+	return schema.Generate(FilterConfiguration{})
+}
+
+// ExtendJSONSchema sets the API version and kind of the configuration.
+func (FilterConfiguration) ExtendJSONSchema(s *schema.Schema) {
+	s.Schema = schema.Draft
+	s.Title = FilterKind + " " + FilterAPIVersion
+	s.Properties["apiVersion"].Const = FilterAPIVersion
+	s.Properties["kind"].Const = FilterKind
+	s.Required = append(s.Required, "apiVersion", "kind")
+	s.Properties["excludePackages"].Items.MinLength = schema.Ptr(1)
+	s.Properties["defaultChannelPolicy"].Enum = schema.Enum(filter.DefaultChannelPolicies()...)
+}
+
+// ExtendJSONSchema adds the version formats and the exclusive settings of a package.
+func (Package) ExtendJSONSchema(s *schema.Schema) {
+	s.Properties["name"].MinLength = schema.Ptr(1)
+	s.Properties["defaultChannelPolicy"].Enum = schema.Enum(filter.DefaultChannelPolicies()...)
+	schema.VersionRange(s.Properties["versionRange"])
+	schema.Semver(s.Properties["minVersion"])
+	schema.Semver(s.Properties["maxVersion"])
+	s.Properties["excludeChannels"].Items.MinLength = schema.Ptr(1)
+	s.Properties["excludeBundles"].Items.MinLength = schema.Ptr(1)
+
+	versionRange := schema.AnyOf(schema.Sets("versionRange"), schema.Sets("minVersion"), schema.Sets("maxVersion"))
+	s.AllOf = append(s.AllOf,
+		versionBoundsRule(),
+		schema.Exclusive("mixing both filtering by bundles and filtering by channels or versionRange is not allowed",
+			schema.SetsItems("bundles"), schema.AnyOf(schema.SetsItems("channels"), versionRange)),
+		schema.Exclusive("mixing both filtering by bundles and shortestPath is not allowed",
+			schema.SetsItems("bundles"), schema.SetsTrue("shortestPath")),
+		schema.Exclusive("package.VersionRange and channel.VersionRange are exclusive",
+			versionRange, &schema.Schema{Required: []string{"channels"}, Properties: map[string]*schema.Schema{"channels": {Contains: versionRange}}}),
+	)
+}
+
+// ExtendJSONSchema adds the version formats and the exclusive settings of a channel.
+func (Channel) ExtendJSONSchema(s *schema.Schema) {
+	s.Properties["name"].MinLength = schema.Ptr(1)
+	schema.VersionRange(s.Properties["versionRange"])
+	schema.Semver(s.Properties["minVersion"])
+	schema.Semver(s.Properties["maxVersion"])
+	s.AllOf = append(s.AllOf, versionBoundsRule())
+}
+
+// ExtendJSONSchema requires at least one criterion.
+func (Selector) ExtendJSONSchema(s *schema.Schema) {
+	s.MinProperties = schema.Ptr(1)
+}
+
+func versionBoundsRule() *schema.Schema {
+	return schema.Exclusive("versionRange and minVersion/maxVersion are exclusive",
+		schema.Sets("versionRange"), schema.AnyOf(schema.Sets("minVersion"), schema.Sets("maxVersion")))
+}
+
+// LoadFilterConfigurationStrict loads a FilterConfiguration like LoadFilterConfiguration, after validating it
+// against JSONSchema. Unknown fields, values of the wrong type and the other schema violations are reported
+// with their line and column.
+func LoadFilterConfigurationStrict(r io.Reader) (*FilterConfiguration, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.ValidateYAML(JSONSchema(), data); err != nil {
+		return nil, fmt.Errorf("invalid filter configuration: %w", err)
+	}
+	cfg := &FilterConfiguration{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
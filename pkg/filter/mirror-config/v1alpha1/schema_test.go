@@ -0,0 +1,93 @@
+package v1alpha1
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFilterConfigurationStrict(t *testing.T) {
+	t.Run("WHEN configurations are valid THEN Loads them as LoadFilterConfiguration does", func(t *testing.T) {
+		paths, err := filepath.Glob("testdata/configs/valid*.yaml")
+		require.NoError(t, err)
+		for _, path := range paths {
+			if strings.Contains(path, "configv1alpha1") {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			expected, err := LoadFilterConfiguration(strings.NewReader(string(data)))
+			require.NoError(t, err, path)
+			actual, err := LoadFilterConfigurationStrict(strings.NewReader(string(data)))
+			require.NoError(t, err, path)
+			assert.Equal(t, expected, actual, path)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		data     string
+		expected []string
+	}{
+		{
+			name: "WHEN fields are unknown or mistyped THEN Returns their position",
+			data: "apiVersion: olm.operatorframework.io/filter/mirror/v1alpha1\nkind: FilterConfiguration\npackages:\n- name: foo\n  channels:\n  - name: stable\n    versionRnage: '>=1.0.0'\n  shortestPath: \"true\"\n",
+			expected: []string{
+				`line 7, column 5: packages[0].channels[0]: unknown field "versionRnage"`,
+				`line 8, column 17: packages[0].shortestPath: must be of type boolean`,
+			},
+		},
+		{
+			name: "WHEN settings are exclusive THEN Returns the position of the package",
+			path: "testdata/configs/invalid_versionbounds.yaml",
+			expected: []string{
+				`line 4, column 5: packages[0]: versionRange and minVersion/maxVersion are exclusive`,
+				`line 13, column 17: packages[2].minVersion: "not semver" must be a semantic version, such as "1.2.3"`,
+				`line 14, column 5: packages[3]: package.VersionRange and channel.VersionRange are exclusive`,
+				`line 19, column 5: packages[4]: mixing both filtering by bundles and filtering by channels or versionRange is not allowed`,
+			},
+		},
+		{
+			name: "WHEN values are not allowed THEN Returns their position",
+			path: "testdata/configs/invalid_multipleerrors.yaml",
+			expected: []string{
+				`line 1, column 13: apiVersion: must be "olm.operatorframework.io/filter/mirror/v1alpha1"`,
+				`line 2, column 7: kind: must be "FilterConfiguration"`,
+				`line 11, column 11: packages[2].name: must have at least 1 characters`,
+				`line 31, column 19: packages[6].versionRange: "not semver" must be a semver range, such as ">=1.0.0 <2.0.0"`,
+				`line 44, column 5: packages[9]: mixing both filtering by bundles and shortestPath is not allowed`,
+				`line 54, column 5: selectors[1]: must have at least 1 fields`,
+			},
+		},
+		{
+			name:     "WHEN policy is unknown THEN Returns the known policies",
+			path:     "testdata/configs/invalid_defaultchannelpolicy.yaml",
+			expected: []string{`line 3, column 23: defaultChannelPolicy: must be one of "error", "highestVersion", "stable"`},
+		},
+		{
+			name:     "WHEN the schema is matched but Validate fails THEN Returns the validation error",
+			data:     "apiVersion: olm.operatorframework.io/filter/mirror/v1alpha1\nkind: FilterConfiguration\npackages:\n- name: foo\n  minVersion: 2.0.0\n  maxVersion: 1.0.0\n",
+			expected: []string{`package "foo" at index [0] is invalid: minVersion "2.0.0" is greater than maxVersion "1.0.0"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.data)
+			if tt.path != "" {
+				var err error
+				data, err = os.ReadFile(tt.path)
+				require.NoError(t, err)
+			}
+			cfg, err := LoadFilterConfigurationStrict(strings.NewReader(string(data)))
+			assert.Nil(t, cfg)
+			for _, expected := range tt.expected {
+				assert.ErrorContains(t, err, expected)
+			}
+		})
+	}
+}
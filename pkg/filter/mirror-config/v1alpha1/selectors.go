@@ -0,0 +1,123 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
+)
+
+const categoriesAnnotation = "categories"
+
+func (s Selector) isEmpty() bool {
+	return len(s.Properties) == 0 && len(s.Labels) == 0 && s.Provider == "" && len(s.Keywords) == 0 &&
+		s.Maturity == "" && len(s.Categories) == 0
+}
+
+func (s Selector) needsCSVMetadata() bool {
+	return len(s.Labels) > 0 || s.Provider != "" || len(s.Keywords) > 0 || s.Maturity != "" || len(s.Categories) > 0
+}
+
+// matches reports whether pkg, whose head bundle has the given CSV metadata, satisfies all the criteria of s.
+// csvMetadata is nil when the head bundle has no olm.csv.metadata property.
+func (s Selector) matches(pkg declcfg.Package, csvMetadata *property.CSVMetadata) bool {
+	for propertyType, value := range s.Properties {
+		if !slices.ContainsFunc(pkg.Properties, func(p property.Property) bool {
+			return p.Type == propertyType && propertyValueEquals(p.Value, value)
+		}) {
+			return false
+		}
+	}
+	if !s.needsCSVMetadata() {
+		return true
+	}
+	if csvMetadata == nil {
+		return false
+	}
+	for key, value := range s.Labels {
+		if v, ok := csvMetadata.Labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	if s.Provider != "" && !strings.EqualFold(s.Provider, csvMetadata.Provider.Name) {
+		return false
+	}
+	if s.Maturity != "" && !strings.EqualFold(s.Maturity, csvMetadata.Maturity) {
+		return false
+	}
+	if !containsAllFold(csvMetadata.Keywords, s.Keywords) {
+		return false
+	}
+	var categories []string
+	for _, c := range strings.Split(csvMetadata.Annotations[categoriesAnnotation], ",") {
+		categories = append(categories, strings.TrimSpace(c))
+	}
+	return containsAllFold(categories, s.Categories)
+}
+
+// propertyValueEquals compares the value of a property to a configured value, either as a JSON string
+// or, for other JSON values, as compact JSON.
+func propertyValueEquals(raw json.RawMessage, value string) bool {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s == value
+	}
+	compact := &bytes.Buffer{}
+	if err := json.Compact(compact, raw); err != nil {
+		return false
+	}
+	return compact.String() == value
+}
+
+func containsAllFold(values, wanted []string) bool {
+	for _, w := range wanted {
+		if !slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, w) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectPackages returns the names of the packages of index that match at least one of selectors.
+func selectPackages(selectors []Selector, index operatorIndex, log *logrus.Entry) sets.Set[string] {
+	selected := sets.New[string]()
+	needsCSVMetadata := slices.ContainsFunc(selectors, Selector.needsCSVMetadata)
+	for name, pkg := range index.Packages {
+		var csvMetadata *property.CSVMetadata
+		if needsCSVMetadata {
+			csvMetadata = headCSVMetadata(pkg, index, log)
+		}
+		if slices.ContainsFunc(selectors, func(s Selector) bool { return s.matches(pkg, csvMetadata) }) {
+			selected.Insert(name)
+		}
+	}
+	return selected
+}
+
+// headCSVMetadata returns the CSV metadata of the head bundle of the default channel of pkg, or nil if it
+// cannot be found.
+func headCSVMetadata(pkg declcfg.Package, index operatorIndex, log *logrus.Entry) *property.CSVMetadata {
+	for _, ch := range index.Channels[pkg.Name] {
+		if ch.Name != pkg.DefaultChannel {
+			continue
+		}
+		c, err := graph.NewChannel(ch)
+		if err != nil {
+			log.Warnf("package %q channel %q: unable to find the head bundle to match selectors: %v", pkg.Name, ch.Name, err)
+			return nil
+		}
+		props, err := property.Parse(index.BundlesByPkgAndName[pkg.Name][c.Head.Name].Properties)
+		if err != nil || len(props.CSVMetadatas) == 0 {
+			return nil
+		}
+		return &props.CSVMetadatas[0]
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+func csvMetadataProperty(t *testing.T, metadata property.CSVMetadata) property.Property {
+	value, err := json.Marshal(metadata)
+	require.NoError(t, err)
+	return property.Property{Type: property.TypeCSVMetadata, Value: value}
+}
+
+func selectorsTestCatalog(t *testing.T) *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{
+			{Name: "foo", DefaultChannel: "stable", Properties: []property.Property{{Type: "example.com/tier", Value: json.RawMessage(`"gold"`)}}},
+			{Name: "bar", DefaultChannel: "stable"},
+			{Name: "baz", DefaultChannel: "stable"},
+		},
+		Channels: []declcfg.Channel{
+			{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{{Name: "foo.v2", Replaces: "foo.v1"}, {Name: "foo.v1"}}},
+			{Name: "stable", Package: "bar", Entries: []declcfg.ChannelEntry{{Name: "bar.v1"}}},
+			{Name: "stable", Package: "baz", Entries: []declcfg.ChannelEntry{{Name: "baz.v1"}}},
+		},
+		Bundles: []declcfg.Bundle{
+			// only the metadata of the channel head counts
+			{Name: "foo.v1", Package: "foo", Properties: append(propertiesForBundle("foo", "1.0.0"), csvMetadataProperty(t, property.CSVMetadata{
+				Keywords: []string{"database"},
+			}))},
+			{Name: "foo.v2", Package: "foo", Properties: append(propertiesForBundle("foo", "2.0.0"), csvMetadataProperty(t, property.CSVMetadata{
+				Provider:    v1alpha1.AppLink{Name: "Red Hat"},
+				Keywords:    []string{"Storage", "ceph"},
+				Maturity:    "stable",
+				Labels:      map[string]string{"operatorframework.io/arch.amd64": "supported"},
+				Annotations: map[string]string{"categories": "Storage, Database"},
+			}))},
+			{Name: "bar.v1", Package: "bar", Properties: append(propertiesForBundle("bar", "1.0.0"), csvMetadataProperty(t, property.CSVMetadata{
+				Provider: v1alpha1.AppLink{Name: "Community"},
+				Keywords: []string{"storage"},
+				Maturity: "alpha",
+			}))},
+			{Name: "baz.v1", Package: "baz", Properties: propertiesForBundle("baz", "1.0.0")},
+		},
+	}
+}
+
+func TestFilter_FilterCatalog_WithSelectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    FilterConfiguration
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, FilterReport)
+	}{
+		{
+			name:   "WHEN selecting by provider THEN Returns the packages whose head bundle has that provider",
+			config: FilterConfiguration{Selectors: []Selector{{Provider: "red hat"}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport) {
+				assert.Equal(t, []string{"foo.v2"}, bundleNamesOf(out))
+				foo, ok := report.Package("foo")
+				require.True(t, ok)
+				assert.Equal(t, ReasonPackageMatchesSelector, foo.Reason)
+			},
+		},
+		{
+			name:   "WHEN selecting by keyword THEN Returns the packages whose head bundle has that keyword",
+			config: FilterConfiguration{Selectors: []Selector{{Keywords: []string{"storage"}}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport) {
+				assert.Equal(t, []string{"bar.v1", "foo.v2"}, bundleNamesOf(out))
+			},
+		},
+		{
+			name:   "WHEN selecting by keyword of a bundle that is not the head THEN Returns no package",
+			config: FilterConfiguration{Selectors: []Selector{{Keywords: []string{"database"}}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport) {
+				assert.Empty(t, out.Packages)
+				assert.Empty(t, out.Bundles)
+			},
+		},
+		{
+			name: "WHEN selecting by maturity, categories and labels THEN Returns the packages matching all criteria",
+			config: FilterConfiguration{Selectors: []Selector{{
+				Maturity:   "stable",
+				Categories: []string{"database"},
+				Labels:     map[string]string{"operatorframework.io/arch.amd64": "supported"},
+			}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport) {
+				assert.Equal(t, []string{"foo.v2"}, bundleNamesOf(out))
+			},
+		},
+		{
+			name:   "WHEN selecting by package property THEN Returns the packages with that property",
+			config: FilterConfiguration{Selectors: []Selector{{Properties: map[string]string{"example.com/tier": "gold"}}}},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, _ FilterReport) {
+				assert.Equal(t, []string{"foo.v2"}, bundleNamesOf(out))
+			},
+		},
+		{
+			name: "WHEN several selectors AND packages THEN Returns the union, configured packages keep their configuration",
+			config: FilterConfiguration{
+				Packages:  []Package{{Name: "foo", Channels: []Channel{{Name: "stable", MinVersion: "1.0.0"}}}, {Name: "baz"}},
+				Selectors: []Selector{{Maturity: "alpha"}, {Provider: "Red Hat"}},
+			},
+			assertion: func(t *testing.T, out *declcfg.DeclarativeConfig, report FilterReport) {
+				assert.Equal(t, []string{"bar.v1", "baz.v1", "foo.v1", "foo.v2"}, bundleNamesOf(out))
+				foo, _ := report.Package("foo")
+				assert.Equal(t, ReasonPackageSelected, foo.Reason)
+				bar, _ := report.Package("bar")
+				assert.Equal(t, ReasonPackageMatchesSelector, bar.Reason)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := FilterReport{}
+			f := NewMirrorFilter(tt.config, WithReport(&report))
+			out, err := f.FilterCatalog(context.Background(), selectorsTestCatalog(t))
+			require.NoError(t, err)
+			tt.assertion(t, out, report)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"github.com/sherine-k/catalog-filter/pkg/graph"
+)
+
+// validateFilteredCatalog verifies that the output of FilterCatalog can be installed by OLM, whatever
+// the filtering mode that produced it:
+// * each channel has a single head, unless multipleHeads is set, no cycle, and only entries whose bundle was kept
+// * each package's default channel is in the catalog
+// * each bundle is an entry of at least one channel
+// * each package required by a bundle through olm.package.required is in the catalog
+// * each deprecation refers to a package, channel or bundle that was kept
+// Entries whose bundle is not in the input catalog either, inputBundles, are not dangling: filtering did not
+// drop them. All problems found are returned together.
+func validateFilteredCatalog(fbc *declcfg.DeclarativeConfig, inputBundles map[string]map[string]declcfg.Bundle, multipleHeads bool) error {
+	var errs []error
+
+	packages := sets.New[string]()
+	for _, pkg := range fbc.Packages {
+		packages.Insert(pkg.Name)
+	}
+	bundles := map[string]sets.Set[string]{}
+	for _, b := range fbc.Bundles {
+		if !packages.Has(b.Package) {
+			errs = append(errs, fmt.Errorf("package %q bundle %q: package is not in the filtered catalog", b.Package, b.Name))
+		}
+		if _, ok := bundles[b.Package]; !ok {
+			bundles[b.Package] = sets.New[string]()
+		}
+		bundles[b.Package].Insert(b.Name)
+	}
+
+	channels := map[string]sets.Set[string]{}
+	bundlesInChannels := map[string]sets.Set[string]{}
+	for _, ch := range fbc.Channels {
+		if !packages.Has(ch.Package) {
+			errs = append(errs, fmt.Errorf("package %q channel %q: package is not in the filtered catalog", ch.Package, ch.Name))
+		}
+		if _, ok := channels[ch.Package]; !ok {
+			channels[ch.Package] = sets.New[string]()
+			bundlesInChannels[ch.Package] = sets.New[string]()
+		}
+		channels[ch.Package].Insert(ch.Name)
+		if _, err := graph.New(ch, graph.WithMultipleHeads(multipleHeads)); err != nil {
+			errs = append(errs, fmt.Errorf("package %q channel %q: %v", ch.Package, ch.Name, err))
+		}
+		for _, e := range ch.Entries {
+			bundlesInChannels[ch.Package].Insert(e.Name)
+			if _, inInput := inputBundles[ch.Package][e.Name]; inInput && !bundles[ch.Package].Has(e.Name) {
+				errs = append(errs, fmt.Errorf("package %q channel %q: dangling entry %q, its bundle is not in the filtered catalog", ch.Package, ch.Name, e.Name))
+			}
+		}
+	}
+
+	for _, pkg := range fbc.Packages {
+		if pkg.DefaultChannel != "" && !channels[pkg.Name].Has(pkg.DefaultChannel) {
+			errs = append(errs, fmt.Errorf("package %q channel %q: default channel is not in the filtered catalog", pkg.Name, pkg.DefaultChannel))
+		}
+	}
+
+	for _, b := range fbc.Bundles {
+		if !bundlesInChannels[b.Package].Has(b.Name) {
+			errs = append(errs, fmt.Errorf("package %q bundle %q: bundle is not an entry of any channel", b.Package, b.Name))
+		}
+		for _, required := range requiredPackages(b) {
+			if !packages.Has(required) {
+				errs = append(errs, fmt.Errorf("package %q bundle %q: required package %q is not in the filtered catalog", b.Package, b.Name, required))
+			}
+		}
+	}
+
+	for _, d := range fbc.Deprecations {
+		if !packages.Has(d.Package) {
+			errs = append(errs, fmt.Errorf("package %q: deprecation refers to a package that is not in the filtered catalog", d.Package))
+			continue
+		}
+		for _, e := range d.Entries {
+			switch e.Reference.Schema {
+			case declcfg.SchemaChannel:
+				if !channels[d.Package].Has(e.Reference.Name) {
+					errs = append(errs, fmt.Errorf("package %q channel %q: deprecation refers to a channel that is not in the filtered catalog", d.Package, e.Reference.Name))
+				}
+			case declcfg.SchemaBundle:
+				if !bundles[d.Package].Has(e.Reference.Name) {
+					errs = append(errs, fmt.Errorf("package %q bundle %q: deprecation refers to a bundle that is not in the filtered catalog", d.Package, e.Reference.Name))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// requiredPackages returns the names of the packages listed in the olm.package.required properties of b.
+func requiredPackages(b declcfg.Bundle) []string {
+	var required []string
+	for _, p := range b.Properties {
+		if p.Type != property.TypePackageRequired {
+			continue
+		}
+		var pkg property.PackageRequired
+		if err := json.Unmarshal(p.Value, &pkg); err != nil {
+			continue
+		}
+		required = append(required, pkg.PackageName)
+	}
+	return required
+}
@@ -0,0 +1,130 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+func validCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Name: "foo", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+			{Name: "foo.v2", Replaces: "foo.v1"},
+			{Name: "foo.v1"},
+		}}},
+		Bundles: []declcfg.Bundle{
+			{Name: "foo.v1", Package: "foo", Properties: propertiesForBundle("foo", "1.0.0")},
+			{Name: "foo.v2", Package: "foo", Properties: propertiesForBundle("foo", "2.0.0")},
+		},
+		Deprecations: []declcfg.Deprecation{{Package: "foo", Entries: []declcfg.DeprecationEntry{
+			{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaBundle, Name: "foo.v1"}},
+			{Reference: declcfg.PackageScopedReference{Schema: declcfg.SchemaChannel, Name: "stable"}},
+		}}},
+	}
+}
+
+func TestValidateFilteredCatalog(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(*declcfg.DeclarativeConfig)
+		expected []string
+	}{
+		{
+			name:   "WHEN catalog is valid THEN returns no error",
+			mutate: func(*declcfg.DeclarativeConfig) {},
+		},
+		{
+			name: "WHEN channel has multiple heads THEN returns error",
+			mutate: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Channels[0].Entries[0].Replaces = ""
+			},
+			expected: []string{`package "foo" channel "stable": multiple channel heads found: [foo.v1 foo.v2]`},
+		},
+		{
+			name: "WHEN channel entry has no bundle THEN returns error",
+			mutate: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Bundles = fbc.Bundles[1:]
+				fbc.Deprecations = nil
+			},
+			expected: []string{`package "foo" channel "stable": dangling entry "foo.v1", its bundle is not in the filtered catalog`},
+		},
+		{
+			name: "WHEN channel entry has no bundle in the input catalog either THEN returns no error",
+			mutate: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Channels[0].Entries[1].Replaces = "foo.v0"
+				fbc.Channels[0].Entries = append(fbc.Channels[0].Entries, declcfg.ChannelEntry{Name: "foo.v0"})
+			},
+		},
+		{
+			name: "WHEN default channel is missing THEN returns error",
+			mutate: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Packages[0].DefaultChannel = "beta"
+			},
+			expected: []string{`package "foo" channel "beta": default channel is not in the filtered catalog`},
+		},
+		{
+			name: "WHEN bundle is in no channel THEN returns error",
+			mutate: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{Name: "foo.v3", Package: "foo"})
+			},
+			expected: []string{`package "foo" bundle "foo.v3": bundle is not an entry of any channel`},
+		},
+		{
+			name: "WHEN required package is not in the catalog THEN returns error",
+			mutate: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Bundles[1].Properties = append(fbc.Bundles[1].Properties, property.MustBuildPackageRequired("bar", ">=1.0.0"))
+			},
+			expected: []string{`package "foo" bundle "foo.v2": required package "bar" is not in the filtered catalog`},
+		},
+		{
+			name: "WHEN deprecations refer to removed objects THEN returns all errors",
+			mutate: func(fbc *declcfg.DeclarativeConfig) {
+				fbc.Deprecations[0].Entries[0].Reference.Name = "foo.v0"
+				fbc.Deprecations[0].Entries[1].Reference.Name = "beta"
+				fbc.Deprecations = append(fbc.Deprecations, declcfg.Deprecation{Package: "bar"})
+			},
+			expected: []string{
+				`package "foo" bundle "foo.v0": deprecation refers to a bundle that is not in the filtered catalog`,
+				`package "foo" channel "beta": deprecation refers to a channel that is not in the filtered catalog`,
+				`package "bar": deprecation refers to a package that is not in the filtered catalog`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := indexFromDeclCfg(validCatalog())
+			require.NoError(t, err)
+			fbc := validCatalog()
+			tt.mutate(fbc)
+			err = validateFilteredCatalog(fbc, input.BundlesByPkgAndName, false)
+			if len(tt.expected) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, expected := range tt.expected {
+				assert.ErrorContains(t, err, expected)
+			}
+		})
+	}
+}
+
+func TestFilter_FilterCatalog_InvalidOutput(t *testing.T) {
+	f := NewMirrorFilter(FilterConfiguration{Packages: []Package{{Name: "foo"}}})
+	fbc := validCatalog()
+	// the head of the channel requires a package that is not selected
+	fbc.Bundles[1].Properties = append(fbc.Bundles[1].Properties, property.MustBuildPackageRequired("bar", ">=1.0.0"))
+	fbc.Packages = append(fbc.Packages, declcfg.Package{Name: "bar"})
+
+	out, err := f.FilterCatalog(context.Background(), fbc)
+	assert.Nil(t, out)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "filtered catalog is invalid")
+	assert.ErrorContains(t, err, `package "foo" bundle "foo.v2": required package "bar" is not in the filtered catalog`)
+}
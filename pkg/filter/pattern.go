@@ -0,0 +1,118 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// RegexpPrefix marks a name pattern as a regular expression, e.g. `re:^stable-4\.1[4-6]$`.
+const RegexpPrefix = "re:"
+
+// IsNamePattern reports whether name is a pattern, either a regular expression prefixed with RegexpPrefix or
+// a glob such as `stable-*`, rather than a literal name.
+func IsNamePattern(name string) bool {
+	return strings.HasPrefix(name, RegexpPrefix) || strings.ContainsAny(name, "*?[")
+}
+
+// CompileNamePattern returns a function reporting whether a name matches pattern. Regular expressions
+// use the syntax of the regexp package and are not anchored implicitly. Globs use the syntax of path.Match
+// and must match the whole name. A pattern that is not a glob nor a regular expression matches itself only.
+func CompileNamePattern(pattern string) (func(string) bool, error) {
+	if expr, ok := strings.CutPrefix(pattern, RegexpPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", expr, err)
+		}
+		return re.MatchString, nil
+	}
+	if !IsNamePattern(pattern) {
+		return func(name string) bool { return name == pattern }, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %v", pattern, err)
+	}
+	return func(name string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}, nil
+}
+
+// ChannelNames returns the names of the channels of each package of fbc.
+func ChannelNames(fbc *declcfg.DeclarativeConfig) map[string]sets.Set[string] {
+	channelNames := map[string]sets.Set[string]{}
+	for _, ch := range fbc.Channels {
+		if _, ok := channelNames[ch.Package]; !ok {
+			channelNames[ch.Package] = sets.New[string]()
+		}
+		channelNames[ch.Package].Insert(ch.Name)
+	}
+	return channelNames
+}
+
+// HasChannelPatterns reports whether one of the channel names of chConfigs, the channel configurations of each
+// package by name, is a pattern.
+func HasChannelPatterns[C any](chConfigs map[string]map[string]C) bool {
+	for _, pkgChannels := range chConfigs {
+		for name := range pkgChannels {
+			if IsNamePattern(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExpandChannelPatterns returns the configurations of channels, the channels configured for each package in
+// order, by name, with each channel name pattern replaced by the channels of channelNames it matches. name
+// returns the name of a configuration, and rename a copy of it for the channel it matched. Literal channel names
+// take precedence over patterns, and a channel matched by several patterns gets the configuration of the first of
+// them. A pattern that matches no channel of its package is an error, unless the package is not in the catalog
+// at all.
+func ExpandChannelPatterns[C any](channels map[string][]C, channelNames map[string]sets.Set[string], name func(C) string, rename func(C, string) C) (map[string]map[string]C, error) {
+	var errs []error
+	expanded := make(map[string]map[string]C, len(channels))
+	for pkgName, pkgChannels := range channels {
+		expanded[pkgName] = make(map[string]C, len(pkgChannels))
+		for _, ch := range pkgChannels {
+			if !IsNamePattern(name(ch)) {
+				expanded[pkgName][name(ch)] = ch
+			}
+		}
+		for _, ch := range pkgChannels {
+			pattern := name(ch)
+			if !IsNamePattern(pattern) {
+				continue
+			}
+			match, err := CompileNamePattern(pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("package %q channel %q: %v", pkgName, pattern, err))
+				continue
+			}
+			names, ok := channelNames[pkgName]
+			if !ok {
+				continue
+			}
+			matched := false
+			for _, channelName := range sets.List(names) {
+				if !match(channelName) {
+					continue
+				}
+				matched = true
+				if _, ok := expanded[pkgName][channelName]; !ok {
+					expanded[pkgName][channelName] = rename(ch, channelName)
+				}
+			}
+			if !matched {
+				errs = append(errs, fmt.Errorf("package %q channel %q: pattern matches no channel of the catalog", pkgName, pattern))
+			}
+		}
+	}
+	return expanded, errors.Join(errs...)
+}
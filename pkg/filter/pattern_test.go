@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func TestCompileNamePattern(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		matches     []string
+		mismatches  []string
+		expectedErr string
+	}{
+		{
+			name:       "Literal",
+			pattern:    "stable",
+			matches:    []string{"stable"},
+			mismatches: []string{"stable-4.14", "fast"},
+		},
+		{
+			name:       "Glob",
+			pattern:    "stable-*",
+			matches:    []string{"stable-4.14", "stable-5.0"},
+			mismatches: []string{"stable", "fast-4.14", "pre-stable-4.14"},
+		},
+		{
+			name:       "Regexp",
+			pattern:    `re:^stable-4\.1[4-6]$`,
+			matches:    []string{"stable-4.14", "stable-4.16"},
+			mismatches: []string{"stable-4.13", "stable-4.140"},
+		},
+		{
+			name:        "InvalidGlob",
+			pattern:     "stable-[",
+			expectedErr: `invalid glob "stable-["`,
+		},
+		{
+			name:        "InvalidRegexp",
+			pattern:     "re:stable-(",
+			expectedErr: `invalid regular expression "stable-("`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := CompileNamePattern(tt.pattern)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			for _, name := range tt.matches {
+				assert.True(t, match(name), name)
+			}
+			for _, name := range tt.mismatches {
+				assert.False(t, match(name), name)
+			}
+		})
+	}
+}
+
+func TestExpandChannelPatterns(t *testing.T) {
+	type channel struct{ name, versionRange string }
+	name := func(ch channel) string { return ch.name }
+	rename := func(ch channel, name string) channel { ch.name = name; return ch }
+	channelNames := ChannelNames(&declcfg.DeclarativeConfig{Channels: []declcfg.Channel{
+		{Package: "foo", Name: "stable-4.14"},
+		{Package: "foo", Name: "stable-4.15"},
+		{Package: "foo", Name: "fast"},
+	}})
+
+	expanded, err := ExpandChannelPatterns(map[string][]channel{
+		"foo": {{name: "stable-4.15", versionRange: ">=2.0.0"}, {name: "stable-*", versionRange: ">=1.0.0"}, {name: "re:^(stable|fast)"}},
+		"bar": {{name: "stable-*"}},
+	}, channelNames, name, rename)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]channel{
+		"foo": {
+			"stable-4.14": {name: "stable-4.14", versionRange: ">=1.0.0"},
+			"stable-4.15": {name: "stable-4.15", versionRange: ">=2.0.0"},
+			"fast":        {name: "fast"},
+		},
+		"bar": {},
+	}, expanded)
+	assert.True(t, HasChannelPatterns(map[string]map[string]channel{"foo": {"stable-*": {}}}))
+	assert.False(t, HasChannelPatterns(map[string]map[string]channel{"foo": {"stable": {}}}))
+
+	_, err = ExpandChannelPatterns(map[string][]channel{"foo": {{name: "beta-*"}, {name: "re:("}}}, channelNames, name, rename)
+	assert.ErrorContains(t, err, `package "foo" channel "beta-*": pattern matches no channel of the catalog`)
+	assert.ErrorContains(t, err, `package "foo" channel "re:(": invalid regular expression "("`)
+}
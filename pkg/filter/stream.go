@@ -0,0 +1,54 @@
+package filter
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// LoadFS walks the file based catalog rooted at root and builds a DeclarativeConfig from the meta
+// objects accepted by keep. Rejected meta objects are dropped as soon as they are read, so they are
+// never decoded into declcfg types nor held in memory.
+func LoadFS(ctx context.Context, root fs.FS, keep MetaFilter, opts ...declcfg.LoadOption) (*declcfg.DeclarativeConfig, error) {
+	var (
+		mu    sync.Mutex
+		metas []*declcfg.Meta
+	)
+	// WalkMetasFS invokes the walk function concurrently
+	if err := declcfg.WalkMetasFS(ctx, root, func(_ string, meta *declcfg.Meta, err error) error {
+		if err != nil {
+			return err
+		}
+		if !keep.KeepMeta(meta) {
+			return nil
+		}
+		mu.Lock()
+		metas = append(metas, meta)
+		mu.Unlock()
+		return nil
+	}, opts...); err != nil {
+		return nil, err
+	}
+	return declcfg.LoadSlice(metas)
+}
+
+// FilterFS loads the file based catalog rooted at root and filters it with f.
+// When f also implements MetaFilter, the catalog is pre-filtered while it is read (see LoadFS),
+// and FilterCatalog only runs on the meta objects that were kept.
+func FilterFS(ctx context.Context, root fs.FS, f CatalogFilter, opts ...declcfg.LoadOption) (*declcfg.DeclarativeConfig, error) {
+	var (
+		fbc *declcfg.DeclarativeConfig
+		err error
+	)
+	if mf, ok := f.(MetaFilter); ok {
+		fbc, err = LoadFS(ctx, root, mf, opts...)
+	} else {
+		fbc, err = declcfg.LoadFS(ctx, root, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f.FilterCatalog(ctx, fbc)
+}
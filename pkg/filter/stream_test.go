@@ -0,0 +1,126 @@
+package filter
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func testCatalogFS() fstest.MapFS {
+	return fstest.MapFS{
+		"foo/catalog.yaml": &fstest.MapFile{Data: []byte(`---
+schema: olm.package
+name: foo
+---
+schema: olm.channel
+package: foo
+name: ch1
+entries:
+  - name: bundle1
+---
+schema: olm.bundle
+package: foo
+name: bundle1
+image: quay.io/example/foo-bundle:v1
+`)},
+		"bar/catalog.yaml": &fstest.MapFile{Data: []byte(`---
+schema: olm.package
+name: bar
+---
+schema: olm.channel
+package: bar
+name: ch2
+entries:
+  - name: bundle2
+---
+schema: olm.bundle
+package: bar
+name: bundle2
+image: quay.io/example/bar-bundle:v2
+---
+schema: other
+package: bar
+`)},
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	tests := []struct {
+		name      string
+		keep      MetaFilter
+		assertion func(*testing.T, *declcfg.DeclarativeConfig, error)
+	}{
+		{
+			name: "KeepAll",
+			keep: MetaFilterFunc(func(*declcfg.Meta) bool { return true }),
+			assertion: func(t *testing.T, fbc *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Len(t, fbc.Packages, 2)
+				assert.Len(t, fbc.Channels, 2)
+				assert.Len(t, fbc.Bundles, 2)
+				assert.Len(t, fbc.Others, 1)
+			},
+		},
+		{
+			name: "KeepFoo",
+			keep: NewPackageFilter("foo").(MetaFilter),
+			assertion: func(t *testing.T, fbc *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo"}}, fbc.Packages)
+				require.Len(t, fbc.Channels, 1)
+				assert.Equal(t, "ch1", fbc.Channels[0].Name)
+				require.Len(t, fbc.Bundles, 1)
+				assert.Equal(t, "bundle1", fbc.Bundles[0].Name)
+				assert.Empty(t, fbc.Others)
+			},
+		},
+		{
+			name: "KeepNothing",
+			keep: NewPackageFilter().(MetaFilter),
+			assertion: func(t *testing.T, fbc *declcfg.DeclarativeConfig, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, &declcfg.DeclarativeConfig{}, fbc)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := LoadFS(context.Background(), testCatalogFS(), tt.keep)
+			tt.assertion(t, actual, err)
+		})
+	}
+}
+
+func TestLoadFS_InvalidCatalog(t *testing.T) {
+	root := fstest.MapFS{"catalog.yaml": &fstest.MapFile{Data: []byte("schema: olm.package\nname: [")}}
+	_, err := LoadFS(context.Background(), root, NewPackageFilter("foo").(MetaFilter))
+	require.Error(t, err)
+}
+
+type catalogOnlyFilter struct {
+	CatalogFilter
+}
+
+func TestFilterFS(t *testing.T) {
+	t.Run("MetaFilter", func(t *testing.T) {
+		actual, err := FilterFS(context.Background(), testCatalogFS(), NewPackageFilter("bar"))
+		require.NoError(t, err)
+		assert.Len(t, actual.Packages, 1)
+		assert.Len(t, actual.Others, 1)
+	})
+	t.Run("CatalogFilterOnly", func(t *testing.T) {
+		actual, err := FilterFS(context.Background(), testCatalogFS(), catalogOnlyFilter{NewPackageFilter("bar")})
+		require.NoError(t, err)
+		assert.True(t, slices.ContainsFunc(actual.Bundles, func(b declcfg.Bundle) bool {
+			return strings.HasPrefix(b.Image, "quay.io/example/bar-bundle")
+		}))
+		assert.Len(t, actual.Packages, 1)
+	})
+}
@@ -0,0 +1,185 @@
+package graph
+
+import (
+	"slices"
+	"strings"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// Channel is a head of the upgrade graph of a channel, along with the graph. The head and the entries it
+// upgrades from are the part of the channel that is filtered.
+type Channel struct {
+	Graph *Graph
+	Head  *Node
+}
+
+// NewChannel builds the upgrade graph of ch, which must have a single head, and returns the channel of its head.
+func NewChannel(ch declcfg.Channel) (*Channel, error) {
+	g, err := New(ch)
+	if err != nil {
+		return nil, err
+	}
+	return &Channel{Graph: g, Head: g.Heads()[0]}, nil
+}
+
+// NewChannelHeads builds the upgrade graph of ch like NewChannel does, but accepts channels with several heads.
+// It returns a channel for each head, made of the head and the entries it upgrades from, sorted by head name.
+// The entries that several heads upgrade from are shared by their channels.
+func NewChannelHeads(ch declcfg.Channel) ([]*Channel, error) {
+	g, err := New(ch, WithMultipleHeads(true))
+	if err != nil {
+		return nil, err
+	}
+	var channels []*Channel
+	for _, head := range g.Heads() {
+		channels = append(channels, &Channel{Graph: g, Head: head})
+	}
+	return channels, nil
+}
+
+// HighestHead returns the channel of heads whose head has the highest version in versionMap. Among heads with
+// the same version, or without version, the one with the highest name is returned.
+func HighestHead(heads []*Channel, versionMap map[string]*mmsemver.Version) *Channel {
+	return slices.MaxFunc(heads, func(a, b *Channel) int {
+		va, vb := versionMap[a.Head.Name], versionMap[b.Head.Name]
+		switch {
+		case va != nil && vb != nil && !va.Equal(vb):
+			return va.Compare(vb)
+		case va != nil && vb == nil:
+			return 1
+		case va == nil && vb != nil:
+			return -1
+		}
+		return strings.Compare(a.Head.Name, b.Head.Name)
+	})
+}
+
+// KeepHighestHead returns the entries of ch without, when it has several heads, the heads other than the one
+// with the highest version in versionMap, and the entries that only they upgrade from. The entries of channels
+// that are invalid for another reason are returned as they are, to fail when they are filtered. ch is left
+// untouched.
+func KeepHighestHead(ch declcfg.Channel, versionMap map[string]*mmsemver.Version, log *logrus.Entry) []declcfg.ChannelEntry {
+	heads, err := NewChannelHeads(ch)
+	if err != nil || len(heads) == 1 {
+		return ch.Entries
+	}
+	highest := HighestHead(heads, versionMap)
+	var dropped []string
+	for _, c := range heads {
+		if c != highest {
+			dropped = append(dropped, c.Head.Name)
+		}
+	}
+	log.Infof("package %q channel %q: keeping head %q, which has the highest version, and dropping heads %v", ch.Package, ch.Name, highest.Head.Name, dropped)
+	entries := highest.EntryNames()
+	return slices.DeleteFunc(slices.Clone(ch.Entries), func(e declcfg.ChannelEntry) bool {
+		return !entries.Has(e.Name)
+	})
+}
+
+// EntryNames returns the names of the head of c and of all the entries it upgrades from, through replaces
+// and skips.
+func (c *Channel) EntryNames() sets.Set[string] {
+	return c.Graph.ReachableFrom(c.Head.Name)
+}
+
+// FilterByVersionRange returns the entries of the channel that fall within the version range.
+//
+// This is a bit tricky because we don't want to create additional channel heads, which might mean including extra
+// bundles that fall outside the version range. If this happens, we will emit a warning for each bundle that falls
+// outside the range. See FilterEntries for how the new head and tail are found.
+func (c *Channel) FilterByVersionRange(versionRange *mmsemver.Constraints, versionMap map[string]*mmsemver.Version, log *logrus.Entry) sets.Set[string] {
+	inRange := func(e *Node) bool {
+		return versionMap[e.Name] != nil && versionRange.Check(versionMap[e.Name])
+	}
+	return c.FilterEntries(inRange, func(cur *Node) {
+		if version := versionMap[cur.Name]; version == nil {
+			log.Warnf("including bundle %q: it is unversioned but is required to ensure inclusion of all bundles in the range", cur.Name)
+		} else {
+			log.Warnf("including bundle %q with version %q: it falls outside the specified range of %q but is required to ensure inclusion of all bundles in the range", cur.Name, version, versionRange)
+		}
+	})
+}
+
+// FilterEntries returns the entries for which inRange is true, and calls keptOutOfRange for each entry of the
+// replaces chain that is kept in order to preserve a single channel head although inRange is false for it.
+//
+// For each existing channel head, we need to find a new head and new tail bundle. We will count the number of bundles in
+// range that are at or below each bundle in the replaces chain. In order to get the minimal set, we will
+// keep track of the specific bundles that we have seen and only count them once.
+//   - The new head will be the bundle with the most bundles at or below it. If multiple bundles have the same number
+//     of range matches at or below them, we will use the bundle lowest in the replaces chain.
+//   - The tail will be the first bundle in the replaces chain whose range match count is 0. The tail is not
+//     included in the new chain.
+func (c *Channel) FilterEntries(inRange func(*Node) bool, keptOutOfRange func(*Node)) sets.Set[string] {
+	keepEntries := sets.New[string]()
+
+	seen := sets.New[string]()
+	counts := map[string]int{}
+	countUniqueTailBundlesInRange(c.Head, inRange, seen, counts)
+	maxCount := -1
+
+	// Find:
+	// - head (lowest node on replaces chain that has the maximum
+	//   count of unvisited tail nodes in range)
+	// - tail (highest node on the replaces chain that has 0 unvisited tail
+	//   nodes in range)
+	var head, tail *Node
+	for cur := c.Head; cur != nil; cur = cur.Replaces {
+		count := counts[cur.Name]
+		if count >= maxCount {
+			head = cur
+			maxCount = count
+		}
+		if count == 0 {
+			tail = cur
+			break
+		}
+	}
+
+	// We how have head and tail, let's traverse head to tail and build a list of bundles to keep,
+	// notifying keptOutOfRange if anything in the replaces chain is not in range.
+	for cur := head; cur != tail; cur = cur.Replaces {
+		if !inRange(cur) {
+			keptOutOfRange(cur)
+		}
+		keepEntries.Insert(cur.Name)
+		for _, skip := range cur.Skips {
+			if inRange(skip) {
+				keepEntries.Insert(skip.Name)
+			}
+		}
+	}
+	return keepEntries
+}
+
+// countUniqueTailBundlesInRange counts the number of bundles in the replaces chain of b that are in range
+// that are unique to b, where "in the replaces chain" is defined as "b or any bundle that b skips, or any bundle in
+// the replaces chain of b's replaces bundle"
+func countUniqueTailBundlesInRange(entry *Node, inRange func(*Node) bool, seen sets.Set[string], counts map[string]int) {
+	replaces := entry.Replaces
+	count := 0
+	if replaces != nil {
+		countUniqueTailBundlesInRange(replaces, inRange, seen, counts)
+		count += counts[replaces.Name]
+	}
+
+	if !seen.Has(entry.Name) && inRange(entry) {
+		seen.Insert(entry.Name)
+		count++
+	}
+
+	for _, skip := range entry.Skips {
+		if !seen.Has(skip.Name) && inRange(skip) {
+			seen.Insert(skip.Name)
+			count++
+		}
+	}
+
+	counts[entry.Name] = count
+}
@@ -1,4 +1,4 @@
-package v1alpha1
+package graph
 
 import (
 	"bytes"
@@ -13,260 +13,72 @@ import (
 	"github.com/operator-framework/operator-registry/alpha/declcfg"
 )
 
-func TestChannel_NewChannel(t *testing.T) {
+func TestChannel_NewChannelHeads(t *testing.T) {
+	versionMap := map[string]*mmsemver.Version{
+		"foo.v1.0.0": mmsemver.MustParse("1.0.0"),
+		"foo.v1.1.0": mmsemver.MustParse("1.1.0"),
+		"foo.v2.0.0": mmsemver.MustParse("2.0.0"),
+		"foo.v2.1.0": mmsemver.MustParse("2.1.0"),
+	}
 	type testCase struct {
 		name      string
 		in        declcfg.Channel
-		assertion func(*testing.T, *channel, error)
+		assertion func(*testing.T, []*Channel, error)
 	}
 	testCases := []testCase{
 		{
-			name: "no entries",
-			in:   declcfg.Channel{},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Nil(t, actual)
-				assert.Error(t, err)
-				assert.ErrorContains(t, err, `channel has no entries`)
-			},
-		},
-		{
-			name: "single entry",
-			in:   declcfg.Channel{Entries: []declcfg.ChannelEntry{{Name: "foo.v1.0.0"}}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Equal(t, &channel{head: &channelEntry{Name: "foo.v1.0.0"}}, actual)
-				assert.NoError(t, err)
-			},
-		},
-		{
-			name: "multiple entries",
-			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0"},
-				{Name: "foo.v2.0.0", Replaces: "foo.v1.0.0"},
-			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Equal(t, &channel{head: &channelEntry{Name: "foo.v2.0.0", Replaces: &channelEntry{Name: "foo.v1.0.0"}}}, actual)
-				assert.NoError(t, err)
-			},
-		},
-		{
-			name: "multiple heads",
-			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0"},
-				{Name: "foo.v2.0.0"},
-			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Nil(t, actual)
-				assert.Error(t, err)
-				assert.ErrorContains(t, err, `multiple channel heads found: [foo.v1.0.0 foo.v2.0.0]`)
-			},
-		},
-		{
-			name: "multiple heads with replaces",
+			name: "single head",
 			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
 				{Name: "foo.v1.0.0"},
 				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
-				{Name: "foo.v2.0.0"},
-				{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
 			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Nil(t, actual)
-				assert.Error(t, err)
-				assert.ErrorContains(t, err, `multiple channel heads found: [foo.v1.1.0 foo.v2.1.0]`)
-			},
-		},
-		{
-			name: "replaces and skips",
-			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0"},
-				{Name: "foo.v1.1.0"},
-				{Name: "foo.v1.1.1"},
-				{Name: "foo.v1.1.2", Replaces: "foo.v1.0.0", Skips: []string{"foo.v1.1.1", "foo.v1.1.0"}},
-				{Name: "foo.v2.0.0"},
-				{Name: "foo.v2.0.1"},
-				{Name: "foo.v2.0.2", Replaces: "foo.v1.1.2", Skips: []string{"foo.v2.0.1", "foo.v2.0.0"}},
-			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				require.NotNil(t, actual.head)
-
-				// foo.v2.0.2
-				assert.Equal(t, "foo.v2.0.2", actual.head.Name)
-				assert.Equal(t, sets.New[string]("foo.v2.0.1", "foo.v2.0.0"), channelEntrySetToNames(actual.head.Skips))
-				require.NotNil(t, actual.head.Replaces)
-
-				// foo.v1.1.2
-				assert.Equal(t, "foo.v1.1.2", actual.head.Replaces.Name)
-				assert.Equal(t, sets.New[string]("foo.v1.1.1", "foo.v1.1.0"), channelEntrySetToNames(actual.head.Replaces.Skips))
-				require.NotNil(t, actual.head.Replaces.Replaces)
-
-				// foo.v1.0.0
-				assert.Equal(t, "foo.v1.0.0", actual.head.Replaces.Replaces.Name)
-				assert.Nil(t, actual.head.Replaces.Replaces.Skips)
-				assert.Nil(t, actual.head.Replaces.Replaces.Replaces)
-
-				assert.NoError(t, err)
-			},
-		},
-		{
-			name: "long replaces chain",
-			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0"},
-				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
-				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
-				{Name: "foo.v1.3.0", Replaces: "foo.v1.2.0"},
-				{Name: "foo.v1.4.0", Replaces: "foo.v1.3.0"},
-				{Name: "foo.v1.5.0", Replaces: "foo.v1.4.0"},
-				{Name: "foo.v1.6.0", Replaces: "foo.v1.5.0"},
-			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Equal(t, &channel{head: &channelEntry{
-					Name: "foo.v1.6.0",
-					Replaces: &channelEntry{
-						Name: "foo.v1.5.0",
-						Replaces: &channelEntry{
-							Name: "foo.v1.4.0",
-							Replaces: &channelEntry{
-								Name: "foo.v1.3.0",
-								Replaces: &channelEntry{
-									Name: "foo.v1.2.0",
-									Replaces: &channelEntry{
-										Name: "foo.v1.1.0",
-										Replaces: &channelEntry{
-											Name: "foo.v1.0.0",
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-				}, actual)
-				assert.NoError(t, err)
-			},
-		},
-		{
-			name: "multiple heads replace same bundle",
-			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0"},
-				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
-				{Name: "foo.v1.2.0", Replaces: "foo.v1.0.0"},
-			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Nil(t, actual)
-				assert.Error(t, err)
-				assert.ErrorContains(t, err, `multiple channel heads found: [foo.v1.1.0 foo.v1.2.0]`)
+			assertion: func(t *testing.T, actual []*Channel, err error) {
+				require.NoError(t, err)
+				require.Len(t, actual, 1)
+				assert.Equal(t, "foo.v1.1.0", actual[0].Head.Name)
 			},
 		},
 		{
-			name: "duplicate channel entries",
+			name: "multiple heads sharing entries",
 			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
 				{Name: "foo.v1.0.0"},
+				{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
 				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
-				{Name: "foo.v1.0.0"},
-				{Name: "foo.v1.1.0"},
-			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Nil(t, actual)
-				assert.Error(t, err)
-				assert.ErrorContains(t, err, `duplicate channel entry "foo.v1.0.0"`)
-				assert.ErrorContains(t, err, `duplicate channel entry "foo.v1.1.0"`)
-			},
-		},
-		{
-			name: "replace yourself",
-			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0", Replaces: "foo.v1.0.0"},
-				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
-			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Nil(t, actual)
-				assert.Error(t, err)
-				assert.ErrorContains(t, err, `replaces itself`)
-			},
-		},
-		{
-			name: "skip yourself",
-			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0", Skips: []string{"foo.v1.0.0"}},
-				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
-			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Nil(t, actual)
-				assert.Error(t, err)
-				assert.ErrorContains(t, err, `skips itself`)
-			},
-		},
-		{
-			name: "replaces cycle",
-			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0", Replaces: "foo.v1.2.0"},
-				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
-				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
-				{Name: "foo.v1.3.0", Replaces: "foo.v1.2.0"},
-			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Nil(t, actual)
-				assert.Error(t, err)
-				assert.ErrorContains(t, err, `detected a cycle in the upgrade graph of the channel`)
-			},
-		},
-		{
-			name: "replaces then skips cycle",
-			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0", Skips: []string{"foo.v1.2.0"}},
-				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
-				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
-				{Name: "foo.v2.0.0"},
+				{Name: "foo.v2.0.0", Skips: []string{"foo.v1.0.0"}},
 			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Nil(t, actual)
-				assert.Error(t, err)
-				assert.ErrorContains(t, err, `detected a cycle in the upgrade graph of the channel`)
+			assertion: func(t *testing.T, actual []*Channel, err error) {
+				require.NoError(t, err)
+				require.Len(t, actual, 2)
+				assert.Equal(t, "foo.v1.1.0", actual[0].Head.Name)
+				assert.Equal(t, sets.New("foo.v1.1.0", "foo.v1.0.0"), actual[0].EntryNames())
+				assert.Equal(t, "foo.v2.1.0", actual[1].Head.Name)
+				assert.Equal(t, sets.New("foo.v2.1.0", "foo.v2.0.0", "foo.v1.0.0"), actual[1].EntryNames())
+				assert.Equal(t, "foo.v2.1.0", HighestHead(actual, versionMap).Head.Name)
+				assert.Equal(t, "foo.v1.1.0", HighestHead(actual, map[string]*mmsemver.Version{"foo.v1.1.0": mmsemver.MustParse("1.1.0")}).Head.Name, "versioned heads are higher than unversioned ones")
 			},
 		},
 		{
-			name: "skips then replaces cycle",
+			name: "multiple heads with a cycle",
 			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0", Replaces: "foo.v1.2.0"},
+				{Name: "foo.v1.0.0", Replaces: "foo.v1.1.0"},
 				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
-				{Name: "foo.v1.2.0", Skips: []string{"foo.v1.1.0"}},
-				{Name: "foo.v2.0.0"},
-			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
-				assert.Nil(t, actual)
-				assert.Error(t, err)
-				assert.ErrorContains(t, err, `detected a cycle in the upgrade graph of the channel`)
-			},
-		},
-		{
-			name: "skip each other",
-			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
-				{Name: "foo.v1.0.0", Skips: []string{"foo.v1.2.0"}},
-				{Name: "foo.v1.2.0", Skips: []string{"foo.v1.0.0"}},
-				{Name: "foo.v2.0.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.1.0"},
+				{Name: "foo.v2.1.0"},
 			}},
-			assertion: func(t *testing.T, actual *channel, err error) {
+			assertion: func(t *testing.T, actual []*Channel, err error) {
 				assert.Nil(t, actual)
-				assert.Error(t, err)
 				assert.ErrorContains(t, err, `detected a cycle in the upgrade graph of the channel`)
 			},
 		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			out, err := newChannel(tc.in, nil)
-			tc.assertion(t, out, err)
+			actual, err := NewChannelHeads(tc.in)
+			tc.assertion(t, actual, err)
 		})
 	}
 }
 
-func channelEntrySetToNames(s sets.Set[*channelEntry]) sets.Set[string] {
-	names := sets.New[string]()
-	for e := range s {
-		names.Insert(e.Name)
-	}
-	return names
-}
-
 func TestChannel_FilterByVersionRange(t *testing.T) {
 	type testCase struct {
 		name             string
@@ -467,11 +279,11 @@ func TestChannel_FilterByVersionRange(t *testing.T) {
 			logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true, DisableQuote: true})
 			entry := logrus.NewEntry(logger)
 
-			out, err := newChannel(tc.in, entry)
+			out, err := NewChannel(tc.in)
 			require.NoError(t, err)
 			vr, err := mmsemver.NewConstraint(tc.versionRange)
 			require.NoError(t, err)
-			actual := out.filterByVersionRange(vr, tc.versionMap)
+			actual := out.FilterByVersionRange(vr, tc.versionMap, entry)
 			assert.Equal(t, tc.expected, sets.List(actual))
 			for _, expectedWarning := range tc.expectedWarnings {
 				assert.Contains(t, logOutput.String(), expectedWarning)
@@ -479,3 +291,31 @@ func TestChannel_FilterByVersionRange(t *testing.T) {
 		})
 	}
 }
+
+func TestKeepHighestHead(t *testing.T) {
+	versionMap := map[string]*mmsemver.Version{
+		"foo.v1.0.0": mmsemver.MustParse("1.0.0"),
+		"foo.v1.1.0": mmsemver.MustParse("1.1.0"),
+		"foo.v2.0.0": mmsemver.MustParse("2.0.0"),
+	}
+	logOutput := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(logOutput)
+	entry := logrus.NewEntry(logger)
+
+	ch := declcfg.Channel{Name: "stable", Package: "foo", Entries: []declcfg.ChannelEntry{
+		{Name: "foo.v1.0.0"},
+		{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+		{Name: "foo.v2.0.0", Skips: []string{"foo.v1.0.0"}},
+	}}
+	actual := KeepHighestHead(ch, versionMap, entry)
+	assert.Equal(t, []declcfg.ChannelEntry{
+		{Name: "foo.v1.0.0"},
+		{Name: "foo.v2.0.0", Skips: []string{"foo.v1.0.0"}},
+	}, actual)
+	assert.Len(t, ch.Entries, 3, "the entries of the channel are left untouched")
+	assert.Contains(t, logOutput.String(), `dropping heads [foo.v1.1.0]`)
+
+	single := declcfg.Channel{Entries: ch.Entries[:2]}
+	assert.Equal(t, single.Entries, KeepHighestHead(single, versionMap, entry))
+}
@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// RemoveEntries returns entries without the excluded ones. An entry that replaced an excluded entry replaces,
+// instead, the first entry of its replaces chain that is not excluded, and skips the entries the excluded ones
+// skipped. An entry that skipped an excluded entry skips, instead, the entries the excluded one replaced and
+// skipped. This way, no upgrade edge between the remaining entries is lost.
+func RemoveEntries(entries []declcfg.ChannelEntry, excluded sets.Set[string]) []declcfg.ChannelEntry {
+	byName := make(map[string]declcfg.ChannelEntry, len(entries))
+	for _, e := range entries {
+		byName[e.Name] = e
+	}
+
+	var kept []declcfg.ChannelEntry
+	for _, e := range entries {
+		if excluded.Has(e.Name) {
+			continue
+		}
+		var skips []string
+		pending := slices.Clone(e.Skips)
+		seen := sets.New(e.Name)
+
+		for e.Replaces != "" && excluded.Has(e.Replaces) && !seen.Has(e.Replaces) {
+			seen.Insert(e.Replaces)
+			replaced := byName[e.Replaces]
+			pending = append(pending, replaced.Skips...)
+			e.Replaces = replaced.Replaces
+		}
+		if excluded.Has(e.Replaces) {
+			e.Replaces = ""
+		}
+
+		for len(pending) > 0 {
+			name := pending[0]
+			pending = pending[1:]
+			if seen.Has(name) {
+				continue
+			}
+			seen.Insert(name)
+			if !excluded.Has(name) {
+				skips = append(skips, name)
+				continue
+			}
+			skipped := byName[name]
+			if skipped.Replaces != "" {
+				pending = append(pending, skipped.Replaces)
+			}
+			pending = append(pending, skipped.Skips...)
+		}
+		e.Skips = skips
+		kept = append(kept, e)
+	}
+	return kept
+}
@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func TestRemoveEntries(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []declcfg.ChannelEntry
+		excluded []string
+		expected []declcfg.ChannelEntry
+	}{
+		{
+			name: "WHEN excluding an entry of the replaces chain THEN its successor replaces its predecessor",
+			entries: []declcfg.ChannelEntry{
+				{Name: "foo.v3", Replaces: "foo.v2"},
+				{Name: "foo.v2", Replaces: "foo.v1"},
+				{Name: "foo.v1"},
+			},
+			excluded: []string{"foo.v2"},
+			expected: []declcfg.ChannelEntry{
+				{Name: "foo.v3", Replaces: "foo.v1"},
+				{Name: "foo.v1"},
+			},
+		},
+		{
+			name: "WHEN excluding consecutive entries THEN their skips are kept",
+			entries: []declcfg.ChannelEntry{
+				{Name: "foo.v4", Replaces: "foo.v3"},
+				{Name: "foo.v3", Replaces: "foo.v2", Skips: []string{"foo.v2.1"}},
+				{Name: "foo.v2.1"},
+				{Name: "foo.v2", Replaces: "foo.v1"},
+				{Name: "foo.v1"},
+			},
+			excluded: []string{"foo.v3", "foo.v2"},
+			expected: []declcfg.ChannelEntry{
+				{Name: "foo.v4", Replaces: "foo.v1", Skips: []string{"foo.v2.1"}},
+				{Name: "foo.v2.1"},
+				{Name: "foo.v1"},
+			},
+		},
+		{
+			name: "WHEN excluding a skipped entry THEN the entries it replaced and skipped are skipped instead",
+			entries: []declcfg.ChannelEntry{
+				{Name: "foo.v3", Replaces: "foo.v2.1", Skips: []string{"foo.v2"}},
+				{Name: "foo.v2.1"},
+				{Name: "foo.v2", Replaces: "foo.v1", Skips: []string{"foo.v1.1"}},
+				{Name: "foo.v1.1"},
+				{Name: "foo.v1"},
+			},
+			excluded: []string{"foo.v2"},
+			expected: []declcfg.ChannelEntry{
+				{Name: "foo.v3", Replaces: "foo.v2.1", Skips: []string{"foo.v1", "foo.v1.1"}},
+				{Name: "foo.v2.1"},
+				{Name: "foo.v1.1"},
+				{Name: "foo.v1"},
+			},
+		},
+		{
+			name: "WHEN excluding the tail THEN its successor has no replaces",
+			entries: []declcfg.ChannelEntry{
+				{Name: "foo.v2", Replaces: "foo.v1"},
+				{Name: "foo.v1"},
+			},
+			excluded: []string{"foo.v1"},
+			expected: []declcfg.ChannelEntry{
+				{Name: "foo.v2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := RemoveEntries(tt.entries, sets.New(tt.excluded...))
+			assert.Equal(t, tt.expected, actual)
+			_, err := New(declcfg.Channel{Entries: actual})
+			assert.NoError(t, err)
+		})
+	}
+}
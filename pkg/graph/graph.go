@@ -0,0 +1,279 @@
+// Package graph builds the upgrade graph of a file based catalog channel from the replaces and skips of its
+// entries, and answers queries on it: heads and tails, the entries an entry upgrades from or to, the upgrade
+// paths between two entries and a topological order of the channel. Channel filters the entries a head
+// upgrades from, by version range, without creating new heads.
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// Node is a bundle of the upgrade graph, along with the nodes it upgrades from. Skips are sorted by name.
+// A bundle that is replaced or skipped without being an entry of the channel is a node that upgrades from nothing.
+type Node struct {
+	Name     string
+	Replaces *Node
+	Skips    []*Node
+}
+
+// upgradesFrom returns the nodes n replaces and skips, without duplicates.
+func (n *Node) upgradesFrom() []*Node {
+	from := slices.Clone(n.Skips)
+	if n.Replaces != nil && !slices.Contains(from, n.Replaces) {
+		from = append(from, n.Replaces)
+	}
+	return from
+}
+
+// Graph is the upgrade graph of a channel. It is acyclic, and it has a single head unless built
+// WithMultipleHeads.
+type Graph struct {
+	nodes        map[string]*Node
+	entries      sets.Set[string]
+	heads        []*Node
+	predecessors map[string][]*Node
+	order        []string
+}
+
+type options struct {
+	multipleHeads bool
+}
+
+type Option func(*options)
+
+// WithMultipleHeads accepts channels with several heads when allow is true.
+func WithMultipleHeads(allow bool) Option {
+	return func(o *options) {
+		o.multipleHeads = allow
+	}
+}
+
+// New builds the upgrade graph of ch. It fails when ch has no entries, duplicate entries, entries that
+// replace or skip themselves, no head, several heads unless WithMultipleHeads is set, or a cycle.
+func New(ch declcfg.Channel, opts ...Option) (*Graph, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(ch.Entries) == 0 {
+		return nil, errors.New("channel has no entries")
+	}
+
+	g := &Graph{
+		nodes:        make(map[string]*Node, len(ch.Entries)),
+		entries:      sets.New[string](),
+		predecessors: make(map[string][]*Node, len(ch.Entries)),
+	}
+	node := func(name string) *Node {
+		n, ok := g.nodes[name]
+		if !ok {
+			n = &Node{Name: name}
+			g.nodes[name] = n
+		}
+		return n
+	}
+
+	var errs []error
+	for _, e := range ch.Entries {
+		// Check for duplicates, and error if a dup is found.
+		if g.entries.Has(e.Name) {
+			errs = append(errs, fmt.Errorf("duplicate channel entry %q", e.Name))
+			continue
+		}
+		g.entries.Insert(e.Name)
+		n := node(e.Name)
+
+		if e.Replaces != "" {
+			if e.Name == e.Replaces {
+				errs = append(errs, fmt.Errorf("invalid channel entry %q: replaces itself", e.Name))
+			}
+			n.Replaces = node(e.Replaces)
+		}
+		for _, skipName := range e.Skips {
+			if e.Name == skipName {
+				errs = append(errs, fmt.Errorf("invalid channel entry %q: skips itself", e.Name))
+			}
+			if skip := node(skipName); !slices.Contains(n.Skips, skip) {
+				n.Skips = append(n.Skips, skip)
+			}
+		}
+		slices.SortFunc(n.Skips, compareNames)
+		for _, from := range n.upgradesFrom() {
+			g.predecessors[from.Name] = append(g.predecessors[from.Name], n)
+		}
+	}
+
+	// Find all of the channel heads (the bundles that have no incoming edges)
+	for _, e := range ch.Entries {
+		if len(g.predecessors[e.Name]) == 0 && !slices.Contains(g.heads, g.nodes[e.Name]) {
+			g.heads = append(g.heads, g.nodes[e.Name])
+		}
+	}
+	slices.SortFunc(g.heads, compareNames)
+	if len(g.heads) == 0 {
+		errs = append(errs, errors.New("no channel heads found"))
+	} else if len(g.heads) > 1 && !o.multipleHeads {
+		errs = append(errs, fmt.Errorf("multiple channel heads found: %v", names(g.heads)))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, p := range g.predecessors {
+		slices.SortFunc(p, compareNames)
+	}
+
+	// Topological sort the channel. If we can successfully perform a topological
+	// sort, then we know there are no cycles. The ready nodes are kept sorted by
+	// name so that the order does not depend on the order of the entries.
+	incoming := make(map[string]int, len(g.predecessors))
+	for name, p := range g.predecessors {
+		incoming[name] = len(p)
+	}
+	ready := slices.Clone(g.heads)
+	for len(ready) > 0 {
+		cur := ready[0]
+		ready = ready[1:]
+		if g.entries.Has(cur.Name) {
+			g.order = append(g.order, cur.Name)
+		}
+		for _, from := range cur.upgradesFrom() {
+			incoming[from.Name]--
+			if incoming[from.Name] == 0 {
+				delete(incoming, from.Name)
+				i, _ := slices.BinarySearchFunc(ready, from, compareNames)
+				ready = slices.Insert(ready, i, from)
+			}
+		}
+	}
+
+	// If we exhaust our queue and there are still incoming edges left
+	// untraversed, it means we have a cycle.
+	if len(incoming) > 0 {
+		return nil, errors.New("detected a cycle in the upgrade graph of the channel")
+	}
+	return g, nil
+}
+
+// Heads returns the entries that no other entry replaces or skips, sorted by name.
+func (g *Graph) Heads() []*Node {
+	return slices.Clone(g.heads)
+}
+
+// Tails returns the entries of the channel that upgrade from no other entry of the channel, sorted by name.
+// A tail may still replace or skip bundles that are not entries of the channel.
+func (g *Graph) Tails() []*Node {
+	var tails []*Node
+	for _, name := range sets.List(g.entries) {
+		n := g.nodes[name]
+		if !slices.ContainsFunc(n.upgradesFrom(), func(from *Node) bool { return g.entries.Has(from.Name) }) {
+			tails = append(tails, n)
+		}
+	}
+	return tails
+}
+
+// Node returns the node named name, or nil if no entry of the channel is or references name.
+func (g *Graph) Node(name string) *Node {
+	return g.nodes[name]
+}
+
+// Has reports whether name is an entry of the channel.
+func (g *Graph) Has(name string) bool {
+	return g.entries.Has(name)
+}
+
+// ReachableFrom returns the names of name and of all the nodes it upgrades from, directly or through other
+// nodes. It returns an empty set when name is not in the graph.
+func (g *Graph) ReachableFrom(name string) sets.Set[string] {
+	reachable := sets.New[string]()
+	start, ok := g.nodes[name]
+	if !ok {
+		return reachable
+	}
+	reachable.Insert(name)
+	queue := []*Node{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, from := range cur.upgradesFrom() {
+			if !reachable.Has(from.Name) {
+				reachable.Insert(from.Name)
+				queue = append(queue, from)
+			}
+		}
+	}
+	return reachable
+}
+
+// Predecessors returns the entries that replace or skip name, sorted by name.
+func (g *Graph) Predecessors(name string) []*Node {
+	return slices.Clone(g.predecessors[name])
+}
+
+// UpgradePaths returns all the upgrade paths from the node named from to the node named to. Each path starts
+// with from and ends with to, and each of its nodes is replaced or skipped by the next one. The paths are
+// sorted, and there is none when to does not upgrade from from, directly or not. The number of paths can grow
+// quickly with the number of skips, it is meant to be used on small parts of a channel.
+func (g *Graph) UpgradePaths(from, to string) [][]string {
+	if _, ok := g.nodes[to]; !ok || !g.ReachableFrom(to).Has(from) {
+		return nil
+	}
+	// only walk through the nodes that upgrade from the node named from, directly or not
+	upgradesTo := sets.New(from)
+	queue := []string{from}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, p := range g.predecessors[cur] {
+			if !upgradesTo.Has(p.Name) {
+				upgradesTo.Insert(p.Name)
+				queue = append(queue, p.Name)
+			}
+		}
+	}
+
+	var paths [][]string
+	var walk func(cur *Node, path []string)
+	walk = func(cur *Node, path []string) {
+		path = append(path, cur.Name)
+		if cur.Name == from {
+			p := slices.Clone(path)
+			slices.Reverse(p)
+			paths = append(paths, p)
+			return
+		}
+		for _, n := range cur.upgradesFrom() {
+			if upgradesTo.Has(n.Name) {
+				walk(n, path)
+			}
+		}
+	}
+	walk(g.nodes[to], nil)
+	slices.SortFunc(paths, slices.Compare)
+	return paths
+}
+
+// TopologicalOrder returns the names of the entries of the channel, each one before the entries it upgrades
+// from, so the heads come first. Entries that can come in any order are sorted by name.
+func (g *Graph) TopologicalOrder() []string {
+	return slices.Clone(g.order)
+}
+
+func compareNames(a, b *Node) int {
+	return strings.Compare(a.Name, b.Name)
+}
+
+func names(nodes []*Node) []string {
+	n := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		n = append(n, node.Name)
+	}
+	return n
+}
@@ -0,0 +1,333 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func TestNew(t *testing.T) {
+	type testCase struct {
+		name      string
+		in        declcfg.Channel
+		assertion func(*testing.T, *Graph, error)
+	}
+	testCases := []testCase{
+		{
+			name: "no entries",
+			in:   declcfg.Channel{},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `channel has no entries`)
+			},
+		},
+		{
+			name: "single entry",
+			in:   declcfg.Channel{Entries: []declcfg.ChannelEntry{{Name: "foo.v1.0.0"}}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Equal(t, []*Node{{Name: "foo.v1.0.0"}}, actual.Heads())
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "multiple entries",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v2.0.0", Replaces: "foo.v1.0.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Equal(t, []*Node{{Name: "foo.v2.0.0", Replaces: &Node{Name: "foo.v1.0.0"}}}, actual.Heads())
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "multiple heads",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v2.0.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `multiple channel heads found: [foo.v1.0.0 foo.v2.0.0]`)
+			},
+		},
+		{
+			name: "multiple heads with replaces",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v2.0.0"},
+				{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `multiple channel heads found: [foo.v1.1.0 foo.v2.1.0]`)
+			},
+		},
+		{
+			name: "replaces and skips",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0"},
+				{Name: "foo.v1.1.1"},
+				{Name: "foo.v1.1.2", Replaces: "foo.v1.0.0", Skips: []string{"foo.v1.1.1", "foo.v1.1.0"}},
+				{Name: "foo.v2.0.0"},
+				{Name: "foo.v2.0.1"},
+				{Name: "foo.v2.0.2", Replaces: "foo.v1.1.2", Skips: []string{"foo.v2.0.1", "foo.v2.0.0"}},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				require.NoError(t, err)
+				require.Len(t, actual.Heads(), 1)
+				head := actual.Heads()[0]
+
+				// foo.v2.0.2
+				assert.Equal(t, "foo.v2.0.2", head.Name)
+				assert.Equal(t, []string{"foo.v2.0.0", "foo.v2.0.1"}, names(head.Skips))
+				require.NotNil(t, head.Replaces)
+
+				// foo.v1.1.2
+				assert.Equal(t, "foo.v1.1.2", head.Replaces.Name)
+				assert.Equal(t, []string{"foo.v1.1.0", "foo.v1.1.1"}, names(head.Replaces.Skips))
+				require.NotNil(t, head.Replaces.Replaces)
+
+				// foo.v1.0.0
+				assert.Equal(t, "foo.v1.0.0", head.Replaces.Replaces.Name)
+				assert.Nil(t, head.Replaces.Replaces.Skips)
+				assert.Nil(t, head.Replaces.Replaces.Replaces)
+
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "long replaces chain",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+				{Name: "foo.v1.3.0", Replaces: "foo.v1.2.0"},
+				{Name: "foo.v1.4.0", Replaces: "foo.v1.3.0"},
+				{Name: "foo.v1.5.0", Replaces: "foo.v1.4.0"},
+				{Name: "foo.v1.6.0", Replaces: "foo.v1.5.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Equal(t, []*Node{{
+					Name: "foo.v1.6.0",
+					Replaces: &Node{
+						Name: "foo.v1.5.0",
+						Replaces: &Node{
+							Name: "foo.v1.4.0",
+							Replaces: &Node{
+								Name: "foo.v1.3.0",
+								Replaces: &Node{
+									Name: "foo.v1.2.0",
+									Replaces: &Node{
+										Name: "foo.v1.1.0",
+										Replaces: &Node{
+											Name: "foo.v1.0.0",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				}, actual.Heads())
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "multiple heads replace same bundle",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.0.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `multiple channel heads found: [foo.v1.1.0 foo.v1.2.0]`)
+			},
+		},
+		{
+			name: "duplicate channel entries",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `duplicate channel entry "foo.v1.0.0"`)
+				assert.ErrorContains(t, err, `duplicate channel entry "foo.v1.1.0"`)
+			},
+		},
+		{
+			name: "replace yourself",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `replaces itself`)
+			},
+		},
+		{
+			name: "skip yourself",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0", Skips: []string{"foo.v1.0.0"}},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `skips itself`)
+			},
+		},
+		{
+			name: "replaces cycle",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0", Replaces: "foo.v1.2.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+				{Name: "foo.v1.3.0", Replaces: "foo.v1.2.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `detected a cycle in the upgrade graph of the channel`)
+			},
+		},
+		{
+			name: "replaces then skips cycle",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0", Skips: []string{"foo.v1.2.0"}},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0"},
+				{Name: "foo.v2.0.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `detected a cycle in the upgrade graph of the channel`)
+			},
+		},
+		{
+			name: "skips then replaces cycle",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0", Replaces: "foo.v1.2.0"},
+				{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+				{Name: "foo.v1.2.0", Skips: []string{"foo.v1.1.0"}},
+				{Name: "foo.v2.0.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `detected a cycle in the upgrade graph of the channel`)
+			},
+		},
+		{
+			name: "skip each other",
+			in: declcfg.Channel{Entries: []declcfg.ChannelEntry{
+				{Name: "foo.v1.0.0", Skips: []string{"foo.v1.2.0"}},
+				{Name: "foo.v1.2.0", Skips: []string{"foo.v1.0.0"}},
+				{Name: "foo.v2.0.0"},
+			}},
+			assertion: func(t *testing.T, actual *Graph, err error) {
+				assert.Nil(t, actual)
+				assert.Error(t, err)
+				assert.ErrorContains(t, err, `detected a cycle in the upgrade graph of the channel`)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := New(tc.in)
+			tc.assertion(t, out, err)
+		})
+	}
+}
+
+func TestNew_WithMultipleHeads(t *testing.T) {
+	in := declcfg.Channel{Entries: []declcfg.ChannelEntry{
+		{Name: "foo.v2.1.0", Replaces: "foo.v2.0.0"},
+		{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+		{Name: "foo.v2.0.0", Skips: []string{"foo.v1.0.0"}},
+		{Name: "foo.v1.0.0"},
+	}}
+	_, err := New(in, WithMultipleHeads(false))
+	assert.ErrorContains(t, err, `multiple channel heads found: [foo.v1.1.0 foo.v2.1.0]`)
+
+	actual, err := New(in, WithMultipleHeads(true))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo.v1.1.0", "foo.v2.1.0"}, names(actual.Heads()))
+	assert.Equal(t, []string{"foo.v1.0.0"}, names(actual.Tails()))
+	assert.Equal(t, []string{"foo.v1.1.0", "foo.v2.1.0", "foo.v2.0.0", "foo.v1.0.0"}, actual.TopologicalOrder())
+
+	_, err = New(declcfg.Channel{Entries: []declcfg.ChannelEntry{
+		{Name: "foo.v1.0.0", Replaces: "foo.v1.1.0"},
+		{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+		{Name: "foo.v2.0.0"},
+	}}, WithMultipleHeads(true))
+	assert.ErrorContains(t, err, `detected a cycle in the upgrade graph of the channel`)
+}
+
+func TestGraph_Queries(t *testing.T) {
+	// foo.v1.3.0 replaces foo.v1.2.0 and skips foo.v1.1.0, foo.v1.2.0 replaces foo.v1.1.0 and skips
+	// foo.v1.0.0, foo.v1.1.0 replaces foo.v1.0.0, which replaces foo.v0.1.0 that is not in the channel.
+	g, err := New(declcfg.Channel{Entries: []declcfg.ChannelEntry{
+		{Name: "foo.v1.0.0", Replaces: "foo.v0.1.0"},
+		{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+		{Name: "foo.v1.2.0", Replaces: "foo.v1.1.0", Skips: []string{"foo.v1.0.0"}},
+		{Name: "foo.v1.3.0", Replaces: "foo.v1.2.0", Skips: []string{"foo.v1.1.0"}},
+	}})
+	require.NoError(t, err)
+
+	t.Run("Heads", func(t *testing.T) {
+		assert.Equal(t, []string{"foo.v1.3.0"}, names(g.Heads()))
+	})
+	t.Run("Tails", func(t *testing.T) {
+		assert.Equal(t, []string{"foo.v1.0.0"}, names(g.Tails()))
+	})
+	t.Run("Node", func(t *testing.T) {
+		assert.Equal(t, "foo.v1.2.0", g.Node("foo.v1.3.0").Replaces.Name)
+		assert.Equal(t, []string{"foo.v1.1.0"}, names(g.Node("foo.v1.3.0").Skips))
+		assert.NotNil(t, g.Node("foo.v0.1.0"))
+		assert.False(t, g.Has("foo.v0.1.0"))
+		assert.True(t, g.Has("foo.v1.0.0"))
+		assert.Nil(t, g.Node("foo.v2.0.0"))
+	})
+	t.Run("ReachableFrom", func(t *testing.T) {
+		assert.Equal(t, sets.New("foo.v1.2.0", "foo.v1.1.0", "foo.v1.0.0", "foo.v0.1.0"), g.ReachableFrom("foo.v1.2.0"))
+		assert.Equal(t, sets.New("foo.v0.1.0"), g.ReachableFrom("foo.v0.1.0"))
+		assert.Empty(t, g.ReachableFrom("foo.v2.0.0"))
+	})
+	t.Run("Predecessors", func(t *testing.T) {
+		assert.Equal(t, []string{"foo.v1.2.0", "foo.v1.3.0"}, names(g.Predecessors("foo.v1.1.0")))
+		assert.Equal(t, []string{"foo.v1.0.0"}, names(g.Predecessors("foo.v0.1.0")))
+		assert.Empty(t, g.Predecessors("foo.v1.3.0"))
+	})
+	t.Run("UpgradePaths", func(t *testing.T) {
+		assert.Equal(t, [][]string{
+			{"foo.v1.0.0", "foo.v1.1.0", "foo.v1.2.0", "foo.v1.3.0"},
+			{"foo.v1.0.0", "foo.v1.1.0", "foo.v1.3.0"},
+			{"foo.v1.0.0", "foo.v1.2.0", "foo.v1.3.0"},
+		}, g.UpgradePaths("foo.v1.0.0", "foo.v1.3.0"))
+		assert.Equal(t, [][]string{{"foo.v1.2.0"}}, g.UpgradePaths("foo.v1.2.0", "foo.v1.2.0"))
+		assert.Nil(t, g.UpgradePaths("foo.v1.3.0", "foo.v1.0.0"))
+		assert.Nil(t, g.UpgradePaths("foo.v1.0.0", "foo.v2.0.0"))
+	})
+	t.Run("TopologicalOrder", func(t *testing.T) {
+		assert.Equal(t, []string{"foo.v1.3.0", "foo.v1.2.0", "foo.v1.1.0", "foo.v1.0.0"}, g.TopologicalOrder())
+	})
+}
@@ -0,0 +1,166 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Mark highlights an entry of a rendered graph with the outcome of the filtering.
+type Mark string
+
+const (
+	// MarkKept is an entry kept by the filtering.
+	MarkKept Mark = "kept"
+	// MarkKeptOutOfRange is an entry outside of the version range, kept to preserve a single channel head.
+	MarkKeptOutOfRange Mark = "keptOutOfRange"
+	// MarkDropped is an entry removed by the filtering.
+	MarkDropped Mark = "dropped"
+)
+
+// RenderOptions decide how WriteDOT and WriteMermaid draw the graph.
+type RenderOptions struct {
+	// Title names the graph, typically after its package and channel.
+	Title string
+	// Versions adds the version of each bundle found in it to the label of its node.
+	Versions map[string]*mmsemver.Version
+	// Marks highlights the entries by their mark. Entries without a mark are drawn plainly.
+	Marks map[string]Mark
+}
+
+// MarkEntries returns marks for all the entries of g: MarkKept for the entries in kept, and MarkDropped for
+// the others, except for the entries in keptOutOfRange which are marked MarkKeptOutOfRange.
+func (g *Graph) MarkEntries(kept, keptOutOfRange sets.Set[string]) map[string]Mark {
+	marks := make(map[string]Mark, g.entries.Len())
+	for name := range g.entries {
+		switch {
+		case keptOutOfRange.Has(name):
+			marks[name] = MarkKeptOutOfRange
+		case kept.Has(name):
+			marks[name] = MarkKept
+		default:
+			marks[name] = MarkDropped
+		}
+	}
+	return marks
+}
+
+var dotStyles = map[Mark]string{
+	MarkKept:           `style=filled, fillcolor="palegreen"`,
+	MarkKeptOutOfRange: `style=filled, fillcolor="orange"`,
+	MarkDropped:        `style="filled,dashed", fillcolor="lightgrey", fontcolor="grey40"`,
+}
+
+var mermaidStyles = map[Mark]string{
+	MarkKept:           "fill:#98fb98",
+	MarkKeptOutOfRange: "fill:#ffa500",
+	MarkDropped:        "fill:#d3d3d3,color:#666666,stroke-dasharray:5 5",
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Each entry points to the entry it replaces with
+// a solid edge and to the entries it skips with dashed edges. Bundles that are referenced without being
+// entries of the channel are drawn dotted.
+func (g *Graph) WriteDOT(w io.Writer, opts RenderOptions) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(opts.Title))
+	b.WriteString("\tnode [shape=box];\n")
+	for _, n := range g.renderOrder() {
+		attrs := []string{"label=" + dotQuote(label(n, opts.Versions, "\n"))}
+		if style, ok := dotStyles[opts.Marks[n.Name]]; ok {
+			attrs = append(attrs, style)
+		} else if !g.Has(n.Name) {
+			attrs = append(attrs, "style=dotted")
+		}
+		fmt.Fprintf(&b, "\t%s [%s];\n", dotQuote(n.Name), strings.Join(attrs, ", "))
+	}
+	for _, n := range g.renderOrder() {
+		if n.Replaces != nil {
+			fmt.Fprintf(&b, "\t%s -> %s [label=\"replaces\"];\n", dotQuote(n.Name), dotQuote(n.Replaces.Name))
+		}
+		for _, skip := range n.Skips {
+			fmt.Fprintf(&b, "\t%s -> %s [label=\"skips\", style=dashed];\n", dotQuote(n.Name), dotQuote(skip.Name))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, drawn the same way WriteDOT draws it.
+func (g *Graph) WriteMermaid(w io.Writer, opts RenderOptions) error {
+	var b strings.Builder
+	if opts.Title != "" {
+		fmt.Fprintf(&b, "---\ntitle: %s\n---\n", opts.Title)
+	}
+	b.WriteString("flowchart TD\n")
+	// bundle names are not valid Mermaid node ids, nodes are numbered in the order they are drawn
+	ids := map[string]string{}
+	byMark := map[Mark][]string{}
+	var referenced []string
+	for i, n := range g.renderOrder() {
+		ids[n.Name] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "\t%s[\"%s\"]\n", ids[n.Name], mermaidEscape(label(n, opts.Versions, "<br/>")))
+		if mark, ok := opts.Marks[n.Name]; ok {
+			byMark[mark] = append(byMark[mark], ids[n.Name])
+		} else if !g.Has(n.Name) {
+			referenced = append(referenced, ids[n.Name])
+		}
+	}
+	for _, n := range g.renderOrder() {
+		if n.Replaces != nil {
+			fmt.Fprintf(&b, "\t%s -->|replaces| %s\n", ids[n.Name], ids[n.Replaces.Name])
+		}
+		for _, skip := range n.Skips {
+			fmt.Fprintf(&b, "\t%s -.->|skips| %s\n", ids[n.Name], ids[skip.Name])
+		}
+	}
+	for _, mark := range []Mark{MarkKept, MarkKeptOutOfRange, MarkDropped} {
+		if len(byMark[mark]) > 0 {
+			fmt.Fprintf(&b, "\tclassDef %s %s\n\tclass %s %s\n", mark, mermaidStyles[mark], strings.Join(byMark[mark], ","), mark)
+		}
+	}
+	if len(referenced) > 0 {
+		fmt.Fprintf(&b, "\tclassDef referenced stroke-dasharray:2 2\n\tclass %s referenced\n", strings.Join(referenced, ","))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderOrder returns the entries in topological order, followed by the bundles that are referenced without
+// being entries of the channel, sorted by name.
+func (g *Graph) renderOrder() []*Node {
+	var nodes []*Node
+	for _, name := range g.order {
+		nodes = append(nodes, g.nodes[name])
+	}
+	var referenced []string
+	for name := range g.nodes {
+		if !g.Has(name) {
+			referenced = append(referenced, name)
+		}
+	}
+	slices.Sort(referenced)
+	for _, name := range referenced {
+		nodes = append(nodes, g.nodes[name])
+	}
+	return nodes
+}
+
+func label(n *Node, versions map[string]*mmsemver.Version, separator string) string {
+	if v, ok := versions[n.Name]; ok && v != nil {
+		return n.Name + separator + v.String()
+	}
+	return n.Name
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
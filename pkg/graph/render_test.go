@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"bytes"
+	"testing"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func renderTestGraph(t *testing.T) (*Graph, RenderOptions) {
+	g, err := New(declcfg.Channel{Entries: []declcfg.ChannelEntry{
+		{Name: "foo.v1.0.0", Replaces: "foo.v0.1.0"},
+		{Name: "foo.v1.1.0", Replaces: "foo.v1.0.0"},
+		{Name: "foo.v2.0.0", Replaces: "foo.v1.1.0", Skips: []string{"foo.v1.0.0"}},
+	}})
+	require.NoError(t, err)
+	return g, RenderOptions{
+		Title: "foo/stable",
+		Versions: map[string]*mmsemver.Version{
+			"foo.v1.0.0": mmsemver.MustParse("1.0.0"),
+			"foo.v1.1.0": mmsemver.MustParse("1.1.0"),
+			"foo.v2.0.0": mmsemver.MustParse("2.0.0"),
+		},
+		Marks: g.MarkEntries(sets.New("foo.v2.0.0", "foo.v1.1.0"), sets.New("foo.v2.0.0")),
+	}
+}
+
+func TestGraph_MarkEntries(t *testing.T) {
+	g, opts := renderTestGraph(t)
+	assert.Equal(t, map[string]Mark{
+		"foo.v2.0.0": MarkKeptOutOfRange,
+		"foo.v1.1.0": MarkKept,
+		"foo.v1.0.0": MarkDropped,
+	}, opts.Marks)
+	assert.Equal(t, map[string]Mark{
+		"foo.v2.0.0": MarkDropped,
+		"foo.v1.1.0": MarkDropped,
+		"foo.v1.0.0": MarkDropped,
+	}, g.MarkEntries(nil, nil))
+}
+
+func TestGraph_WriteDOT(t *testing.T) {
+	g, opts := renderTestGraph(t)
+	out := &bytes.Buffer{}
+	require.NoError(t, g.WriteDOT(out, opts))
+	assert.Equal(t, `digraph "foo/stable" {
+	node [shape=box];
+	"foo.v2.0.0" [label="foo.v2.0.0\n2.0.0", style=filled, fillcolor="orange"];
+	"foo.v1.1.0" [label="foo.v1.1.0\n1.1.0", style=filled, fillcolor="palegreen"];
+	"foo.v1.0.0" [label="foo.v1.0.0\n1.0.0", style="filled,dashed", fillcolor="lightgrey", fontcolor="grey40"];
+	"foo.v0.1.0" [label="foo.v0.1.0", style=dotted];
+	"foo.v2.0.0" -> "foo.v1.1.0" [label="replaces"];
+	"foo.v2.0.0" -> "foo.v1.0.0" [label="skips", style=dashed];
+	"foo.v1.1.0" -> "foo.v1.0.0" [label="replaces"];
+	"foo.v1.0.0" -> "foo.v0.1.0" [label="replaces"];
+}
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, g.WriteDOT(out, RenderOptions{Title: `foo "stable"`}))
+	assert.Contains(t, out.String(), `digraph "foo \"stable\"" {`)
+	assert.Contains(t, out.String(), `"foo.v2.0.0" [label="foo.v2.0.0"];`)
+}
+
+func TestGraph_WriteMermaid(t *testing.T) {
+	g, opts := renderTestGraph(t)
+	out := &bytes.Buffer{}
+	require.NoError(t, g.WriteMermaid(out, opts))
+	assert.Equal(t, `---
+title: foo/stable
+---
+flowchart TD
+	n0["foo.v2.0.0<br/>2.0.0"]
+	n1["foo.v1.1.0<br/>1.1.0"]
+	n2["foo.v1.0.0<br/>1.0.0"]
+	n3["foo.v0.1.0"]
+	n0 -->|replaces| n1
+	n0 -.->|skips| n2
+	n1 -->|replaces| n2
+	n2 -->|replaces| n3
+	classDef kept fill:#98fb98
+	class n1 kept
+	classDef keptOutOfRange fill:#ffa500
+	class n0 keptOutOfRange
+	classDef dropped fill:#d3d3d3,color:#666666,stroke-dasharray:5 5
+	class n2 dropped
+	classDef referenced stroke-dasharray:2 2
+	class n3 referenced
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, g.WriteMermaid(out, RenderOptions{}))
+	assert.Equal(t, "flowchart TD\n", out.String()[:len("flowchart TD\n")])
+}
@@ -0,0 +1,52 @@
+package images
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+// Usage is a bundle referring to an image, as its bundle image or as one of its related images.
+type Usage struct {
+	Package string `json:"package"`
+	Bundle  string `json:"bundle"`
+	// Name is the name of the related image, empty for the bundle image and for unnamed related images.
+	Name string `json:"name,omitempty"`
+}
+
+// Image is an image of a catalog and the bundles referring to it.
+type Image struct {
+	Reference string  `json:"image"`
+	Usages    []Usage `json:"usedBy"`
+}
+
+// List returns the images of the bundles of fbc: their bundle images and related images. Each image is listed
+// once, with all the bundles referring to it. Images are sorted by reference and their usages by package, bundle
+// and name. Bundles without a bundle image, such as the ones of catalogs rendered from bundle directories, only
+// contribute their related images.
+func List(fbc *declcfg.DeclarativeConfig) []Image {
+	usages := map[string][]Usage{}
+	add := func(reference string, usage Usage) {
+		if reference == "" || slices.Contains(usages[reference], usage) {
+			return
+		}
+		usages[reference] = append(usages[reference], usage)
+	}
+	for _, b := range fbc.Bundles {
+		add(b.Image, Usage{Package: b.Package, Bundle: b.Name})
+		for _, related := range b.RelatedImages {
+			add(related.Image, Usage{Package: b.Package, Bundle: b.Name, Name: related.Name})
+		}
+	}
+
+	images := make([]Image, 0, len(usages))
+	for reference, imageUsages := range usages {
+		slices.SortFunc(imageUsages, func(a, b Usage) int {
+			return cmp.Or(cmp.Compare(a.Package, b.Package), cmp.Compare(a.Bundle, b.Bundle), cmp.Compare(a.Name, b.Name))
+		})
+		images = append(images, Image{Reference: reference, Usages: imageUsages})
+	}
+	slices.SortFunc(images, func(a, b Image) int { return cmp.Compare(a.Reference, b.Reference) })
+	return images
+}
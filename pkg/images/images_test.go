@@ -0,0 +1,163 @@
+package images
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+func testCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Bundles: []declcfg.Bundle{
+			{
+				Package: "foo",
+				Name:    "foo.v2",
+				Image:   "quay.io/example/foo-bundle:v2",
+				RelatedImages: []declcfg.RelatedImage{
+					{Image: "quay.io/example/foo-bundle:v2"},
+					{Name: "operator", Image: "registry.redhat.io/example/foo-operator@" + testDigest},
+					{Name: "proxy", Image: "registry.redhat.io/openshift4/ose-kube-rbac-proxy:v4.16"},
+				},
+			},
+			{
+				Package: "foo",
+				Name:    "foo.v1",
+				Image:   "quay.io/example/foo-bundle:v1",
+				RelatedImages: []declcfg.RelatedImage{
+					{Name: "proxy", Image: "registry.redhat.io/openshift4/ose-kube-rbac-proxy:v4.16"},
+				},
+			},
+			{
+				Package: "bar",
+				Name:    "bar.v1",
+				RelatedImages: []declcfg.RelatedImage{
+					{Name: "kube-rbac-proxy", Image: "registry.redhat.io/openshift4/ose-kube-rbac-proxy:v4.16"},
+				},
+			},
+		},
+	}
+}
+
+func TestList(t *testing.T) {
+	expected := []Image{
+		{Reference: "quay.io/example/foo-bundle:v1", Usages: []Usage{{Package: "foo", Bundle: "foo.v1"}}},
+		{Reference: "quay.io/example/foo-bundle:v2", Usages: []Usage{{Package: "foo", Bundle: "foo.v2"}}},
+		{Reference: "registry.redhat.io/example/foo-operator@" + testDigest, Usages: []Usage{{Package: "foo", Bundle: "foo.v2", Name: "operator"}}},
+		{Reference: "registry.redhat.io/openshift4/ose-kube-rbac-proxy:v4.16", Usages: []Usage{
+			{Package: "bar", Bundle: "bar.v1", Name: "kube-rbac-proxy"},
+			{Package: "foo", Bundle: "foo.v1", Name: "proxy"},
+			{Package: "foo", Bundle: "foo.v2", Name: "proxy"},
+		}},
+	}
+	assert.Equal(t, expected, List(testCatalog()))
+	assert.Empty(t, List(&declcfg.DeclarativeConfig{}))
+}
+
+func TestWriteText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteText(buf, List(testCatalog())))
+	assert.Equal(t, `quay.io/example/foo-bundle:v1
+quay.io/example/foo-bundle:v2
+registry.redhat.io/example/foo-operator@`+testDigest+`
+registry.redhat.io/openshift4/ose-kube-rbac-proxy:v4.16
+`, buf.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteJSON(buf, List(testCatalog())[2:3]))
+	assert.JSONEq(t, `[{"image": "registry.redhat.io/example/foo-operator@`+testDigest+`", "usedBy": [{"package": "foo", "bundle": "foo.v2", "name": "operator"}]}]`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteJSON(buf, nil))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestWriteMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		images   []Image
+		opts     MappingOptions
+		expected string
+		err      string
+	}{
+		{
+			name:   "WHEN destination is set THEN Images are pushed under their repository path",
+			images: List(testCatalog()),
+			opts:   MappingOptions{Destination: "mirror.example.com/ns"},
+			expected: `quay.io/example/foo-bundle:v1=mirror.example.com/ns/example/foo-bundle:v1
+quay.io/example/foo-bundle:v2=mirror.example.com/ns/example/foo-bundle:v2
+registry.redhat.io/example/foo-operator@` + testDigest + `=mirror.example.com/ns/example/foo-operator:` + testDigestTag + `
+registry.redhat.io/openshift4/ose-kube-rbac-proxy:v4.16=mirror.example.com/ns/openshift4/ose-kube-rbac-proxy:v4.16
+`,
+		},
+		{
+			name:   "WHEN rewrites are set THEN Sources and destinations are rewritten",
+			images: List(testCatalog()),
+			opts: MappingOptions{
+				SourceRewrites:      Rewrites{{From: "registry.redhat.io", To: "registry-proxy.example.com/redhat"}},
+				DestinationRewrites: Rewrites{{From: "registry.redhat.io/openshift4", To: "mirror.example.com/ocp"}},
+				Destination:         "mirror.example.com",
+			},
+			expected: `quay.io/example/foo-bundle:v1=mirror.example.com/example/foo-bundle:v1
+quay.io/example/foo-bundle:v2=mirror.example.com/example/foo-bundle:v2
+registry-proxy.example.com/redhat/example/foo-operator@` + testDigest + `=mirror.example.com/example/foo-operator:` + testDigestTag + `
+registry-proxy.example.com/redhat/openshift4/ose-kube-rbac-proxy:v4.16=mirror.example.com/ocp/ose-kube-rbac-proxy:v4.16
+`,
+		},
+		{
+			name:   "WHEN reference has a tag and a digest THEN Pulls by digest and pushes the tag",
+			images: []Image{{Reference: "quay.io/example/foo:v1@" + testDigest}},
+			opts:   MappingOptions{Destination: "mirror.example.com"},
+			expected: `quay.io/example/foo@` + testDigest + `=mirror.example.com/example/foo:v1
+`,
+		},
+		{
+			name: "WHEN images of a repository are referenced by digest only THEN Pushes each one with a tag of its digest",
+			images: []Image{
+				{Reference: "quay.io/example/foo@" + testDigest},
+				{Reference: "quay.io/example/foo@" + otherDigest},
+			},
+			opts: MappingOptions{Destination: "mirror.example.com"},
+			expected: `quay.io/example/foo@` + testDigest + `=mirror.example.com/example/foo:` + testDigestTag + `
+quay.io/example/foo@` + otherDigest + `=mirror.example.com/example/foo:sha256-fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210
+`,
+		},
+		{
+			name:   "WHEN no rule maps an image THEN Returns error",
+			images: List(testCatalog()),
+			opts:   MappingOptions{DestinationRewrites: Rewrites{{From: "quay.io", To: "mirror.example.com"}}},
+			err: `no destination for image "registry.redhat.io/example/foo-operator@` + testDigest + `"
+no destination for image "registry.redhat.io/openshift4/ose-kube-rbac-proxy:v4.16"`,
+		},
+		{
+			name:   "WHEN images are mirrored to the same destination THEN Returns error",
+			images: []Image{{Reference: "quay.io/example/foo:v1"}, {Reference: "registry.example.com/example/foo:v1"}},
+			opts:   MappingOptions{Destination: "mirror.example.com"},
+			err:    `images "quay.io/example/foo:v1" and "registry.example.com/example/foo:v1" are both mirrored to "mirror.example.com/example/foo:v1"`,
+		},
+		{
+			name:   "WHEN a rewrite has no target THEN Returns error",
+			images: List(testCatalog()),
+			opts:   MappingOptions{SourceRewrites: Rewrites{{From: "quay.io"}}, Destination: "mirror.example.com"},
+			err:    "rewrite at index [0] is invalid: from and to must be specified",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := WriteMapping(buf, tt.images, tt.opts)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				assert.Empty(t, buf.String())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
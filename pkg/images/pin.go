@@ -0,0 +1,224 @@
+package images
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/sherine-k/catalog-filter/pkg/filter"
+)
+
+// ErrDigestNotFound is returned by a Resolver that does not know the digest of a reference.
+var ErrDigestNotFound = errors.New("digest not found")
+
+// Resolver resolves the digest of the image a reference points to.
+type Resolver interface {
+	// ResolveDigest returns the digest of the image ref points to, such as sha256:0123..., or ErrDigestNotFound.
+	ResolveDigest(ctx context.Context, ref Reference) (string, error)
+}
+
+// DigestMap is a Resolver looking digests up by reference, to pin images offline. It is keyed by fully
+// qualified references, as returned by Reference.String, with an explicit tag.
+type DigestMap map[string]string
+
+// ResolveDigest returns the digest of ref, a reference without a tag standing for its latest tag.
+func (m DigestMap) ResolveDigest(_ context.Context, ref Reference) (string, error) {
+	if ref.Tag == "" {
+		ref.Tag = "latest"
+	}
+	digest, ok := m[ref.String()]
+	if !ok {
+		return "", ErrDigestNotFound
+	}
+	return digest, nil
+}
+
+// LoadDigestMap loads a DigestMap from a JSON object mapping tag references to digests:
+//
+//	{"registry.redhat.io/ubi9/ubi:9.4": "sha256:0123..."}
+//
+// References are normalized, so that docker.io images can be listed by their short names.
+func LoadDigestMap(r io.Reader) (DigestMap, error) {
+	in := map[string]string{}
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return nil, err
+	}
+	m := make(DigestMap, len(in))
+	var errs []error
+	for reference, digest := range in {
+		ref, err := ParseReference(reference)
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+			continue
+		case ref.Digest != "":
+			errs = append(errs, fmt.Errorf("reference %q is already pinned to a digest", reference))
+			continue
+		case !digestPattern.MatchString(digest):
+			errs = append(errs, fmt.Errorf("reference %q has an invalid digest %q", reference, digest))
+			continue
+		}
+		if ref.Tag == "" {
+			ref.Tag = "latest"
+		}
+		m[ref.String()] = digest
+	}
+	if len(errs) > 0 {
+		// the references are reported in a stable order
+		slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+		return nil, errors.Join(errs...)
+	}
+	return m, nil
+}
+
+// LoadDigestMapFile loads a DigestMap from the JSON file at path, see LoadDigestMap.
+func LoadDigestMapFile(path string) (DigestMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := LoadDigestMap(f)
+	if err != nil {
+		return nil, fmt.Errorf("%q is invalid: %v", path, err)
+	}
+	return m, nil
+}
+
+// PinReport lists, per bundle, the image references a pinning filter could not pin to a digest.
+type PinReport struct {
+	Bundles []BundlePinReport `json:"bundles"`
+}
+
+// BundlePinReport lists the image references of a bundle that were not pinned.
+type BundlePinReport struct {
+	Package    string                `json:"package"`
+	Bundle     string                `json:"bundle"`
+	Unresolved []UnresolvedReference `json:"unresolved"`
+}
+
+// UnresolvedReference is an image reference whose digest could not be resolved.
+type UnresolvedReference struct {
+	// Field is the path of the reference in the bundle: image or relatedImages[1].image.
+	Field     string `json:"field"`
+	Reference string `json:"image"`
+	// Reason is the error returned by the Resolver, or why the reference cannot be parsed.
+	Reason string `json:"reason"`
+}
+
+type pinOptions struct {
+	Report *PinReport
+}
+
+type PinOption func(*pinOptions)
+
+// WithPinReport makes FilterCatalog fill report with the references it did not pin. The report is overwritten on
+// each call to FilterCatalog.
+func WithPinReport(report *PinReport) PinOption {
+	return func(opts *pinOptions) {
+		opts.Report = report
+	}
+}
+
+type pinner struct {
+	resolver Resolver
+	opts     pinOptions
+}
+
+// NewPinner returns a filter pinning the bundle images and related images of a catalog to the digests resolver
+// resolves, so that mirroring the catalog is reproducible: the tag of a reference is replaced by its digest, such
+// as quay.io/example/foo@sha256:0123... for quay.io/example/foo:v1. References that already have a digest are
+// kept as they are. References that cannot be resolved are kept with their tag, and reported by WithPinReport;
+// only the cancellation of ctx makes FilterCatalog fail. The catalog is not modified, a pinned copy is returned.
+func NewPinner(resolver Resolver, pinOpts ...PinOption) filter.CatalogFilter {
+	opts := pinOptions{}
+	for _, opt := range pinOpts {
+		opt(&opts)
+	}
+	return &pinner{resolver: resolver, opts: opts}
+}
+
+type resolution struct {
+	pinned string
+	err    error
+}
+
+func (p *pinner) FilterCatalog(ctx context.Context, fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	if fbc == nil {
+		return nil, nil
+	}
+	report := &PinReport{}
+	if p.opts.Report != nil {
+		defer func() { *p.opts.Report = *report }()
+	}
+
+	// images are shared by many bundles, each reference is resolved once
+	resolved := map[string]resolution{}
+	pin := func(reference string) (string, error) {
+		if r, ok := resolved[reference]; ok {
+			return r.pinned, r.err
+		}
+		pinned, err := p.pin(ctx, reference)
+		resolved[reference] = resolution{pinned: pinned, err: err}
+		return pinned, err
+	}
+
+	out := *fbc
+	out.Bundles = slices.Clone(fbc.Bundles)
+	for i := range out.Bundles {
+		b := &out.Bundles[i]
+		var unresolved []UnresolvedReference
+		pinField := func(field, reference string) (string, error) {
+			pinned, err := pin(reference)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return "", ctxErr
+				}
+				unresolved = append(unresolved, UnresolvedReference{Field: field, Reference: reference, Reason: err.Error()})
+				return reference, nil
+			}
+			return pinned, nil
+		}
+		var err error
+		if b.Image != "" {
+			if b.Image, err = pinField("image", b.Image); err != nil {
+				return nil, err
+			}
+		}
+		b.RelatedImages = slices.Clone(b.RelatedImages)
+		for j := range b.RelatedImages {
+			if b.RelatedImages[j].Image, err = pinField(fmt.Sprintf("relatedImages[%d].image", j), b.RelatedImages[j].Image); err != nil {
+				return nil, err
+			}
+		}
+		if len(unresolved) > 0 {
+			report.Bundles = append(report.Bundles, BundlePinReport{Package: b.Package, Bundle: b.Name, Unresolved: unresolved})
+		}
+	}
+	return &out, nil
+}
+
+// pin returns reference with its tag replaced by its digest, keeping the rest of the reference as it is written.
+func (p *pinner) pin(ctx context.Context, reference string) (string, error) {
+	ref, err := ParseReference(reference)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return reference, nil
+	}
+	digest, err := p.resolver.ResolveDigest(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if !digestPattern.MatchString(digest) {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return strings.TrimSuffix(reference, ":"+ref.Tag) + "@" + digest, nil
+}
@@ -0,0 +1,159 @@
+package images
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+const otherDigest = "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+
+func TestLoadDigestMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected DigestMap
+		err      string
+	}{
+		{
+			name: "WHEN references are short names THEN Keys are normalized",
+			json: `{"busybox": "` + testDigest + `", "quay.io/example/foo:v1": "` + otherDigest + `"}`,
+			expected: DigestMap{
+				"docker.io/library/busybox:latest": testDigest,
+				"quay.io/example/foo:v1":           otherDigest,
+			},
+		},
+		{
+			name: "WHEN references or digests are invalid THEN Returns all errors",
+			json: `{"quay.io/example/foo:v1": "v1", "quay.io/example/bar@` + testDigest + `": "` + testDigest + `", "quay.io/Example/baz:v1": "` + testDigest + `"}`,
+			err: `invalid image reference "quay.io/Example/baz:v1": invalid repository "Example/baz"
+reference "quay.io/example/bar@` + testDigest + `" is already pinned to a digest
+reference "quay.io/example/foo:v1" has an invalid digest "v1"`,
+		},
+		{
+			name: "WHEN file is not a JSON object THEN Returns error",
+			json: `["quay.io/example/foo:v1"]`,
+			err:  "json: cannot unmarshal array into Go value of type map[string]string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := LoadDigestMap(strings.NewReader(tt.json))
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, m)
+		})
+	}
+}
+
+func TestLoadDigestMapFile(t *testing.T) {
+	m, err := LoadDigestMapFile("testdata/digests.json")
+	require.NoError(t, err)
+	digest, err := m.ResolveDigest(context.Background(), Reference{Registry: "registry.redhat.io", Repository: "openshift4/ose-kube-rbac-proxy", Tag: "v4.16"})
+	require.NoError(t, err)
+	assert.Equal(t, otherDigest, digest)
+	_, err = m.ResolveDigest(context.Background(), Reference{Registry: "quay.io", Repository: "example/foo-bundle"})
+	assert.ErrorIs(t, err, ErrDigestNotFound)
+
+	_, err = LoadDigestMapFile("testdata/missing.json")
+	assert.EqualError(t, err, "open testdata/missing.json: no such file or directory")
+}
+
+type countingResolver struct {
+	DigestMap
+	calls int
+}
+
+func (r *countingResolver) ResolveDigest(ctx context.Context, ref Reference) (string, error) {
+	r.calls++
+	return r.DigestMap.ResolveDigest(ctx, ref)
+}
+
+func TestPinner_FilterCatalog(t *testing.T) {
+	fbc := testCatalog()
+	fbc.Bundles = append(fbc.Bundles, declcfg.Bundle{Package: "baz", Name: "baz.v1", Image: "quay.io/Example/baz-bundle:v1"})
+	resolver := &countingResolver{DigestMap: DigestMap{
+		"quay.io/example/foo-bundle:v2":                           testDigest,
+		"registry.redhat.io/openshift4/ose-kube-rbac-proxy:v4.16": otherDigest,
+	}}
+	report := &PinReport{}
+	out, err := NewPinner(resolver, WithPinReport(report)).FilterCatalog(context.Background(), fbc)
+	require.NoError(t, err)
+
+	assert.Equal(t, []declcfg.Bundle{
+		{
+			Package: "foo",
+			Name:    "foo.v2",
+			Image:   "quay.io/example/foo-bundle@" + testDigest,
+			RelatedImages: []declcfg.RelatedImage{
+				{Image: "quay.io/example/foo-bundle@" + testDigest},
+				{Name: "operator", Image: "registry.redhat.io/example/foo-operator@" + testDigest},
+				{Name: "proxy", Image: "registry.redhat.io/openshift4/ose-kube-rbac-proxy@" + otherDigest},
+			},
+		},
+		{
+			Package: "foo",
+			Name:    "foo.v1",
+			Image:   "quay.io/example/foo-bundle:v1",
+			RelatedImages: []declcfg.RelatedImage{
+				{Name: "proxy", Image: "registry.redhat.io/openshift4/ose-kube-rbac-proxy@" + otherDigest},
+			},
+		},
+		{
+			Package: "bar",
+			Name:    "bar.v1",
+			RelatedImages: []declcfg.RelatedImage{
+				{Name: "kube-rbac-proxy", Image: "registry.redhat.io/openshift4/ose-kube-rbac-proxy@" + otherDigest},
+			},
+		},
+		{Package: "baz", Name: "baz.v1", Image: "quay.io/Example/baz-bundle:v1"},
+	}, out.Bundles)
+	assert.Equal(t, []BundlePinReport{
+		{Package: "foo", Bundle: "foo.v1", Unresolved: []UnresolvedReference{
+			{Field: "image", Reference: "quay.io/example/foo-bundle:v1", Reason: "digest not found"},
+		}},
+		{Package: "baz", Bundle: "baz.v1", Unresolved: []UnresolvedReference{
+			{Field: "image", Reference: "quay.io/Example/baz-bundle:v1", Reason: `invalid image reference "quay.io/Example/baz-bundle:v1": invalid repository "Example/baz-bundle"`},
+		}},
+	}, report.Bundles)
+	// foo-bundle:v2, foo-bundle:v1 and ose-kube-rbac-proxy:v4.16 are resolved once each
+	assert.Equal(t, 3, resolver.calls)
+	// the input catalog is left untouched
+	assert.Equal(t, "quay.io/example/foo-bundle:v2", fbc.Bundles[0].Image)
+}
+
+type resolverFunc func(ctx context.Context, ref Reference) (string, error)
+
+func (f resolverFunc) ResolveDigest(ctx context.Context, ref Reference) (string, error) {
+	return f(ctx, ref)
+}
+
+func TestPinner_FilterCatalog_Errors(t *testing.T) {
+	t.Run("WHEN resolver returns an invalid digest THEN Reference is reported", func(t *testing.T) {
+		report := &PinReport{}
+		resolver := resolverFunc(func(context.Context, Reference) (string, error) { return "v1", nil })
+		out, err := NewPinner(resolver, WithPinReport(report)).FilterCatalog(context.Background(), &declcfg.DeclarativeConfig{
+			Bundles: []declcfg.Bundle{{Package: "foo", Name: "foo.v1", Image: "quay.io/example/foo-bundle:v1"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "quay.io/example/foo-bundle:v1", out.Bundles[0].Image)
+		assert.Equal(t, []BundlePinReport{{Package: "foo", Bundle: "foo.v1", Unresolved: []UnresolvedReference{
+			{Field: "image", Reference: "quay.io/example/foo-bundle:v1", Reason: `invalid digest "v1"`},
+		}}}, report.Bundles)
+	})
+	t.Run("WHEN context is canceled THEN Returns error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		resolver := resolverFunc(func(ctx context.Context, _ Reference) (string, error) { return "", ctx.Err() })
+		_, err := NewPinner(resolver).FilterCatalog(ctx, testCatalog())
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,134 @@
+// Package images lists the container images a file based catalog refers to, and rewrites their references
+// for mirroring.
+package images
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultRegistry is the registry of the references that do not name one.
+	DefaultRegistry = "docker.io"
+
+	officialRepositoryNamespace = "library"
+)
+
+var (
+	repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagPattern        = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestPattern     = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+)
+
+// Reference is a parsed image reference: registry/repository[:tag][@digest].
+type Reference struct {
+	// Registry is the host, and optional port, of the registry, DefaultRegistry when the reference names none.
+	Registry string
+	// Repository is the path of the image in the registry. Official images of DefaultRegistry are in the
+	// library namespace.
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an image reference. The first component of its path is its registry when it has a
+// dot or a port, or is localhost, like the container tools decide it.
+func ParseReference(s string) (Reference, error) {
+	ref := Reference{}
+	name := s
+	if before, digest, ok := strings.Cut(name, "@"); ok {
+		if !digestPattern.MatchString(digest) {
+			return Reference{}, fmt.Errorf("invalid image reference %q: invalid digest %q", s, digest)
+		}
+		name, ref.Digest = before, digest
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		if !tagPattern.MatchString(name[i+1:]) {
+			return Reference{}, fmt.Errorf("invalid image reference %q: invalid tag %q", s, name[i+1:])
+		}
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	first, rest, found := strings.Cut(name, "/")
+	switch {
+	case found && (strings.ContainsAny(first, ".:") || first == "localhost"):
+		ref.Registry, ref.Repository = first, rest
+	case found:
+		ref.Registry, ref.Repository = DefaultRegistry, name
+	default:
+		ref.Registry, ref.Repository = DefaultRegistry, officialRepositoryNamespace+"/"+name
+	}
+	if !repositoryPattern.MatchString(ref.Repository) {
+		return Reference{}, fmt.Errorf("invalid image reference %q: invalid repository %q", s, ref.Repository)
+	}
+	return ref, nil
+}
+
+// Name returns the registry and repository of r, without its tag or digest.
+func (r Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the fully qualified reference.
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Rewrite replaces the prefix From of the name of the references it matches with To. From is a registry, or a
+// registry followed by a repository prefix, matched on whole path components, such as registry.redhat.io or
+// registry.redhat.io/openshift4. To is a registry, optionally followed by a namespace.
+type Rewrite struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Matches reports whether rw applies to ref.
+func (rw Rewrite) Matches(ref Reference) bool {
+	name := ref.Name()
+	return name == rw.From || strings.HasPrefix(name, rw.From+"/")
+}
+
+// Apply returns ref with the prefix From of its name replaced by To, keeping its tag and digest. It reports
+// false, returning ref unchanged, when rw does not match ref.
+func (rw Rewrite) Apply(ref Reference) (Reference, bool, error) {
+	if !rw.Matches(ref) {
+		return ref, false, nil
+	}
+	name, err := ParseReference(rw.To + strings.TrimPrefix(ref.Name(), rw.From))
+	if err != nil {
+		return ref, false, fmt.Errorf("unable to rewrite %q from %q to %q: %v", ref, rw.From, rw.To, err)
+	}
+	rewritten := ref
+	rewritten.Registry, rewritten.Repository = name.Registry, name.Repository
+	return rewritten, true, nil
+}
+
+// Rewrites are rewrite rules, the first one matching a reference applies to it.
+type Rewrites []Rewrite
+
+// Apply returns ref rewritten by the first rule matching it, and reports whether any did.
+func (rws Rewrites) Apply(ref Reference) (Reference, bool, error) {
+	for _, rw := range rws {
+		if rw.Matches(ref) {
+			return rw.Apply(ref)
+		}
+	}
+	return ref, false, nil
+}
+
+// Validate checks that the rules name a registry to rewrite from and to.
+func (rws Rewrites) Validate() error {
+	for i, rw := range rws {
+		if rw.From == "" || rw.To == "" {
+			return fmt.Errorf("rewrite at index [%d] is invalid: from and to must be specified", i)
+		}
+	}
+	return nil
+}
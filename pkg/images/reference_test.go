@@ -0,0 +1,115 @@
+package images
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDigest    = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	testDigestTag = "sha256-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference string
+		expected  Reference
+		err       string
+	}{
+		{
+			name:      "WHEN reference has registry, tag and digest THEN Parses all of them",
+			reference: "registry.redhat.io/ubi9/ubi:9.4@" + testDigest,
+			expected:  Reference{Registry: "registry.redhat.io", Repository: "ubi9/ubi", Tag: "9.4", Digest: testDigest},
+		},
+		{
+			name:      "WHEN registry has a port THEN Port is part of the registry",
+			reference: "localhost:5000/foo/bar:v1",
+			expected:  Reference{Registry: "localhost:5000", Repository: "foo/bar", Tag: "v1"},
+		},
+		{
+			name:      "WHEN reference is localhost THEN Registry is localhost",
+			reference: "localhost/foo@" + testDigest,
+			expected:  Reference{Registry: "localhost", Repository: "foo", Digest: testDigest},
+		},
+		{
+			name:      "WHEN first component is not a host THEN Registry is the default registry",
+			reference: "foo/bar:latest",
+			expected:  Reference{Registry: DefaultRegistry, Repository: "foo/bar", Tag: "latest"},
+		},
+		{
+			name:      "WHEN reference is a single name THEN Repository is in the library namespace",
+			reference: "busybox",
+			expected:  Reference{Registry: DefaultRegistry, Repository: "library/busybox"},
+		},
+		{
+			name:      "WHEN repository has uppercase letters THEN Returns error",
+			reference: "quay.io/Foo/bar",
+			err:       `invalid image reference "quay.io/Foo/bar": invalid repository "Foo/bar"`,
+		},
+		{
+			name:      "WHEN digest is invalid THEN Returns error",
+			reference: "quay.io/foo/bar@sha256:abc",
+			err:       `invalid image reference "quay.io/foo/bar@sha256:abc": invalid digest "sha256:abc"`,
+		},
+		{
+			name:      "WHEN tag is invalid THEN Returns error",
+			reference: "quay.io/foo/bar:-v1",
+			err:       `invalid image reference "quay.io/foo/bar:-v1": invalid tag "-v1"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseReference(tt.reference)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ref)
+		})
+	}
+}
+
+func TestRewrites_Apply(t *testing.T) {
+	rewrites := Rewrites{
+		{From: "registry.redhat.io/openshift4", To: "mirror.example.com/ocp"},
+		{From: "registry.redhat.io", To: "mirror.example.com/redhat"},
+	}
+	tests := []struct {
+		name      string
+		reference string
+		expected  string
+		rewritten bool
+	}{
+		{
+			name:      "WHEN a repository prefix matches THEN First matching rule applies",
+			reference: "registry.redhat.io/openshift4/ose-kube-rbac-proxy:v4.16",
+			expected:  "mirror.example.com/ocp/ose-kube-rbac-proxy:v4.16",
+			rewritten: true,
+		},
+		{
+			name:      "WHEN only the registry matches THEN Registry rule applies and digest is kept",
+			reference: "registry.redhat.io/ubi9/ubi@" + testDigest,
+			expected:  "mirror.example.com/redhat/ubi9/ubi@" + testDigest,
+			rewritten: true,
+		},
+		{
+			name:      "WHEN prefix matches a partial path component THEN Rule does not apply",
+			reference: "registry.redhat.io.example.com/foo:v1",
+			expected:  "registry.redhat.io.example.com/foo:v1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseReference(tt.reference)
+			require.NoError(t, err)
+			rewritten, ok, err := rewrites.Apply(ref)
+			require.NoError(t, err)
+			assert.Equal(t, tt.rewritten, ok)
+			assert.Equal(t, tt.expected, rewritten.String())
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package images
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+	"github.com/sherine-k/catalog-filter/pkg/filter"
+)
+
+// ReasonNoRewriteMatches is recorded for the references no rewrite rule matches.
+const ReasonNoRewriteMatches = "no rewrite rule matches"
+
+// RewriteReport lists the image references a rewriting filter left unchanged.
+type RewriteReport struct {
+	Unrewritten []UnrewrittenReference `json:"unrewritten"`
+}
+
+// UnrewrittenReference is an image reference of a bundle that was not rewritten.
+type UnrewrittenReference struct {
+	Package string `json:"package"`
+	Bundle  string `json:"bundle"`
+	// Field is the path of the reference in the bundle, such as relatedImages[1].image or, for the objects and
+	// CSV metadata of its properties, properties[2].spec.relatedImages[0].image.
+	Field     string `json:"field"`
+	Reference string `json:"image"`
+	// Reason is ReasonNoRewriteMatches, or why the reference cannot be parsed or rewritten.
+	Reason string `json:"reason"`
+}
+
+type rewriteOptions struct {
+	Report *RewriteReport
+}
+
+type RewriteOption func(*rewriteOptions)
+
+// WithRewriteReport makes FilterCatalog fill report with the references it did not rewrite. The report is
+// overwritten on each call to FilterCatalog.
+func WithRewriteReport(report *RewriteReport) RewriteOption {
+	return func(opts *rewriteOptions) {
+		opts.Report = report
+	}
+}
+
+type rewriter struct {
+	rules Rewrites
+	opts  rewriteOptions
+}
+
+// NewRewriter returns a filter rewriting the image references of the bundles of a catalog with rules, typically
+// run on the output of another filter to point the filtered catalog at a mirror registry. It rewrites the bundle
+// images, the related images and, in the properties of the bundles, the images of their ClusterServiceVersion
+// objects (its related images, the containers of its deployments and their RELATED_IMAGE_ environment variables,
+// and its containerImage annotation) and the containerImage annotation of their olm.csv.metadata. Tags and
+// digests are kept exactly as they are. The catalog is not modified, a rewritten copy is returned.
+func NewRewriter(rules Rewrites, rewriteOpts ...RewriteOption) filter.CatalogFilter {
+	opts := rewriteOptions{}
+	for _, opt := range rewriteOpts {
+		opt(&opts)
+	}
+	return &rewriter{rules: rules, opts: opts}
+}
+
+func (r *rewriter) FilterCatalog(_ context.Context, fbc *declcfg.DeclarativeConfig) (*declcfg.DeclarativeConfig, error) {
+	if fbc == nil {
+		return nil, nil
+	}
+	if err := r.rules.Validate(); err != nil {
+		return nil, err
+	}
+	report := &RewriteReport{}
+	if r.opts.Report != nil {
+		defer func() { *r.opts.Report = *report }()
+	}
+
+	out := *fbc
+	out.Bundles = slices.Clone(fbc.Bundles)
+	for i := range out.Bundles {
+		b := &out.Bundles[i]
+		rewrite := func(field, reference string) string {
+			rewritten, reason := r.rewrite(reference)
+			if reason != "" {
+				report.Unrewritten = append(report.Unrewritten, UnrewrittenReference{Package: b.Package, Bundle: b.Name, Field: field, Reference: reference, Reason: reason})
+			}
+			return rewritten
+		}
+		if b.Image != "" {
+			b.Image = rewrite("image", b.Image)
+		}
+		b.RelatedImages = slices.Clone(b.RelatedImages)
+		for j := range b.RelatedImages {
+			b.RelatedImages[j].Image = rewrite(fmt.Sprintf("relatedImages[%d].image", j), b.RelatedImages[j].Image)
+		}
+		if err := rewriteProperties(b, rewrite); err != nil {
+			return nil, fmt.Errorf("package %q, bundle %q: %v", b.Package, b.Name, err)
+		}
+	}
+	return &out, nil
+}
+
+// rewrite returns reference rewritten by the first rule matching it, or reference and the reason it was not
+// rewritten.
+func (r *rewriter) rewrite(reference string) (string, string) {
+	ref, err := ParseReference(reference)
+	if err != nil {
+		return reference, err.Error()
+	}
+	rewritten, ok, err := r.rules.Apply(ref)
+	if err != nil {
+		return reference, err.Error()
+	}
+	if !ok {
+		return reference, ReasonNoRewriteMatches
+	}
+	return rewritten.String(), ""
+}
+
+// rewriteProperties rewrites the images of the olm.bundle.object and olm.csv.metadata properties of b, along with
+// its objects when they were read from them.
+func rewriteProperties(b *declcfg.Bundle, rewrite func(field, reference string) string) error {
+	b.Properties = slices.Clone(b.Properties)
+	objectsChanged := false
+	for i, p := range b.Properties {
+		field := fmt.Sprintf("properties[%d]", i)
+		switch p.Type {
+		case property.TypeBundleObject:
+			obj := property.BundleObject{}
+			if err := json.Unmarshal(p.Value, &obj); err != nil {
+				return fmt.Errorf("parse property at index %d as bundle object: %v", i, err)
+			}
+			manifest := map[string]any{}
+			if err := yaml.Unmarshal(obj.Data, &manifest); err != nil {
+				return fmt.Errorf("parse bundle object at index %d: %v", i, err)
+			}
+			if manifest["kind"] != "ClusterServiceVersion" || !rewriteCSV(manifest, field, rewrite) {
+				continue
+			}
+			data, err := json.Marshal(manifest)
+			if err != nil {
+				return err
+			}
+			b.Properties[i] = property.MustBuildBundleObject(data)
+			objectsChanged = true
+		case property.TypeCSVMetadata:
+			metadata := map[string]any{}
+			if err := json.Unmarshal(p.Value, &metadata); err != nil {
+				return fmt.Errorf("parse property at index %d as CSV metadata: %v", i, err)
+			}
+			if !rewriteString(metadata, []string{"annotations", "containerImage"}, field, rewrite) {
+				continue
+			}
+			value, err := json.Marshal(metadata)
+			if err != nil {
+				return err
+			}
+			b.Properties[i] = property.Property{Type: property.TypeCSVMetadata, Value: value}
+		}
+	}
+	if objectsChanged && len(b.Objects) > 0 {
+		return readObjects(b)
+	}
+	return nil
+}
+
+// readObjects sets the objects of b and its CSV from its olm.bundle.object properties, as declcfg loads them.
+func readObjects(b *declcfg.Bundle) error {
+	b.Objects, b.CsvJSON = nil, ""
+	for _, p := range b.Properties {
+		if p.Type != property.TypeBundleObject {
+			continue
+		}
+		obj := property.BundleObject{}
+		if err := json.Unmarshal(p.Value, &obj); err != nil {
+			return err
+		}
+		data, err := yaml.YAMLToJSON(obj.Data)
+		if err != nil {
+			return err
+		}
+		b.Objects = append(b.Objects, string(data))
+		manifest := map[string]any{}
+		if err := json.Unmarshal(data, &manifest); err == nil && manifest["kind"] == "ClusterServiceVersion" && b.CsvJSON == "" {
+			b.CsvJSON = string(data)
+		}
+	}
+	return nil
+}
+
+// rewriteCSV rewrites the images of a ClusterServiceVersion manifest located at field, and reports whether any
+// was rewritten.
+func rewriteCSV(csv map[string]any, field string, rewrite func(field, reference string) string) bool {
+	changed := rewriteString(csv, []string{"metadata", "annotations", "containerImage"}, field, rewrite)
+	for i, related := range objects(csv, "spec", "relatedImages") {
+		changed = rewriteString(related, []string{"image"}, fmt.Sprintf("%s.spec.relatedImages[%d]", field, i), rewrite) || changed
+	}
+	for i, deployment := range objects(csv, "spec", "install", "spec", "deployments") {
+		for _, containersField := range []string{"initContainers", "containers"} {
+			for j, container := range objects(deployment, "spec", "template", "spec", containersField) {
+				containerField := fmt.Sprintf("%s.spec.install.spec.deployments[%d].spec.template.spec.%s[%d]", field, i, containersField, j)
+				changed = rewriteString(container, []string{"image"}, containerField, rewrite) || changed
+				for k, env := range objects(container, "env") {
+					if name, _ := env["name"].(string); strings.HasPrefix(name, "RELATED_IMAGE_") {
+						changed = rewriteString(env, []string{"value"}, fmt.Sprintf("%s.env[%d]", containerField, k), rewrite) || changed
+					}
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// objects returns the objects of the array at path in m.
+func objects(m map[string]any, path ...string) []map[string]any {
+	for _, key := range path[:len(path)-1] {
+		m, _ = m[key].(map[string]any)
+	}
+	items, _ := m[path[len(path)-1]].([]any)
+	objs := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		obj, _ := item.(map[string]any)
+		objs = append(objs, obj)
+	}
+	return objs
+}
+
+// rewriteString rewrites the non empty string at path in m, and reports whether it changed.
+func rewriteString(m map[string]any, path []string, field string, rewrite func(field, reference string) string) bool {
+	for _, key := range path[:len(path)-1] {
+		m, _ = m[key].(map[string]any)
+	}
+	key := path[len(path)-1]
+	reference, _ := m[key].(string)
+	if reference == "" {
+		return false
+	}
+	rewritten := rewrite(field+"."+strings.Join(path, "."), reference)
+	m[key] = rewritten
+	return rewritten != reference
+}
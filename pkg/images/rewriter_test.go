@@ -0,0 +1,133 @@
+package images
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+	"github.com/operator-framework/operator-registry/alpha/property"
+)
+
+const testCSV = `{
+	"apiVersion": "operators.coreos.com/v1alpha1",
+	"kind": "ClusterServiceVersion",
+	"metadata": {"name": "foo.v1", "annotations": {"containerImage": "registry.redhat.io/example/foo-operator:v1"}},
+	"spec": {
+		"relatedImages": [{"name": "operator", "image": "registry.redhat.io/example/foo-operator:v1"}],
+		"install": {"strategy": "deployment", "spec": {"deployments": [{"name": "foo", "spec": {"template": {"spec": {
+			"initContainers": [{"name": "init", "image": "quay.io/example/init:v1"}],
+			"containers": [{
+				"name": "manager",
+				"image": "registry.redhat.io/example/foo-operator:v1",
+				"env": [
+					{"name": "RELATED_IMAGE_PROXY", "value": "registry.redhat.io/openshift4/ose-kube-rbac-proxy@` + testDigest + `"},
+					{"name": "LOG_LEVEL", "value": "registry.redhat.io/not/an/image"}
+				]
+			}]
+		}}}}]}}
+	}
+}`
+
+func rewriterTestCatalog() *declcfg.DeclarativeConfig {
+	return &declcfg.DeclarativeConfig{
+		Bundles: []declcfg.Bundle{
+			{
+				Package: "foo",
+				Name:    "foo.v1",
+				Image:   "registry.redhat.io/example/foo-bundle@" + testDigest,
+				RelatedImages: []declcfg.RelatedImage{
+					{Name: "operator", Image: "registry.redhat.io/example/foo-operator:v1"},
+					{Name: "init", Image: "quay.io/example/init:v1"},
+				},
+				Properties: []property.Property{
+					property.MustBuildPackage("foo", "1.0.0"),
+					property.MustBuildBundleObject([]byte(testCSV)),
+					{Type: property.TypeCSVMetadata, Value: json.RawMessage(`{"annotations":{"containerImage":"registry.redhat.io/example/foo-operator:v1"},"displayName":"Foo"}`)},
+				},
+				Objects: []string{testCSV},
+				CsvJSON: testCSV,
+			},
+		},
+	}
+}
+
+func TestRewriter_FilterCatalog(t *testing.T) {
+	fbc := rewriterTestCatalog()
+	report := &RewriteReport{}
+	f := NewRewriter(Rewrites{
+		{From: "registry.redhat.io/openshift4", To: "mirror.example.com/ocp"},
+		{From: "registry.redhat.io", To: "mirror.example.com/redhat"},
+	}, WithRewriteReport(report))
+	out, err := f.FilterCatalog(context.Background(), fbc)
+	require.NoError(t, err)
+
+	require.Len(t, out.Bundles, 1)
+	b := out.Bundles[0]
+	assert.Equal(t, "mirror.example.com/redhat/example/foo-bundle@"+testDigest, b.Image)
+	assert.Equal(t, []declcfg.RelatedImage{
+		{Name: "operator", Image: "mirror.example.com/redhat/example/foo-operator:v1"},
+		{Name: "init", Image: "quay.io/example/init:v1"},
+	}, b.RelatedImages)
+	assert.Equal(t, property.MustBuildPackage("foo", "1.0.0"), b.Properties[0])
+
+	props, err := property.Parse(b.Properties)
+	require.NoError(t, err)
+	require.Len(t, props.BundleObjects, 1)
+	csv := map[string]any{}
+	require.NoError(t, json.Unmarshal(props.BundleObjects[0].Data, &csv))
+	assert.Equal(t, "mirror.example.com/redhat/example/foo-operator:v1", csv["metadata"].(map[string]any)["annotations"].(map[string]any)["containerImage"])
+	assert.JSONEq(t, `[{"name": "operator", "image": "mirror.example.com/redhat/example/foo-operator:v1"}]`, mustMarshal(t, csv["spec"].(map[string]any)["relatedImages"]))
+	podSpec := csv["spec"].(map[string]any)["install"].(map[string]any)["spec"].(map[string]any)["deployments"].([]any)[0].(map[string]any)["spec"].(map[string]any)["template"].(map[string]any)["spec"]
+	assert.JSONEq(t, `{
+		"initContainers": [{"name": "init", "image": "quay.io/example/init:v1"}],
+		"containers": [{
+			"name": "manager",
+			"image": "mirror.example.com/redhat/example/foo-operator:v1",
+			"env": [
+				{"name": "RELATED_IMAGE_PROXY", "value": "mirror.example.com/ocp/ose-kube-rbac-proxy@`+testDigest+`"},
+				{"name": "LOG_LEVEL", "value": "registry.redhat.io/not/an/image"}
+			]
+		}]
+	}`, mustMarshal(t, podSpec))
+	require.Len(t, props.CSVMetadatas, 1)
+	assert.Equal(t, "mirror.example.com/redhat/example/foo-operator:v1", props.CSVMetadatas[0].Annotations["containerImage"])
+	assert.Equal(t, "Foo", props.CSVMetadatas[0].DisplayName)
+	require.Len(t, b.Objects, 1)
+	assert.JSONEq(t, string(props.BundleObjects[0].Data), b.Objects[0])
+	assert.Equal(t, b.Objects[0], b.CsvJSON)
+
+	assert.Equal(t, []UnrewrittenReference{
+		{Package: "foo", Bundle: "foo.v1", Field: "relatedImages[1].image", Reference: "quay.io/example/init:v1", Reason: ReasonNoRewriteMatches},
+		{Package: "foo", Bundle: "foo.v1", Field: "properties[1].spec.install.spec.deployments[0].spec.template.spec.initContainers[0].image", Reference: "quay.io/example/init:v1", Reason: ReasonNoRewriteMatches},
+	}, report.Unrewritten)
+
+	// the input catalog is left untouched
+	assert.Equal(t, rewriterTestCatalog(), fbc)
+}
+
+func TestRewriter_FilterCatalog_InvalidReference(t *testing.T) {
+	fbc := &declcfg.DeclarativeConfig{Bundles: []declcfg.Bundle{{Package: "foo", Name: "foo.v1", Image: "quay.io/Example/foo:v1"}}}
+	report := &RewriteReport{}
+	out, err := NewRewriter(Rewrites{{From: "quay.io", To: "mirror.example.com"}}, WithRewriteReport(report)).FilterCatalog(context.Background(), fbc)
+	require.NoError(t, err)
+	assert.Equal(t, "quay.io/Example/foo:v1", out.Bundles[0].Image)
+	assert.Equal(t, []UnrewrittenReference{{
+		Package: "foo", Bundle: "foo.v1", Field: "image", Reference: "quay.io/Example/foo:v1",
+		Reason: `invalid image reference "quay.io/Example/foo:v1": invalid repository "Example/foo"`,
+	}}, report.Unrewritten)
+}
+
+func TestRewriter_FilterCatalog_InvalidRules(t *testing.T) {
+	_, err := NewRewriter(Rewrites{{From: "quay.io"}}).FilterCatalog(context.Background(), rewriterTestCatalog())
+	assert.EqualError(t, err, "rewrite at index [0] is invalid: from and to must be specified")
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
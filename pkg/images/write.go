@@ -0,0 +1,117 @@
+package images
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteText writes the references of images, one per line.
+func WriteText(w io.Writer, images []Image) error {
+	var b strings.Builder
+	for _, image := range images {
+		b.WriteString(image.Reference + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes images as an indented JSON array, with the bundles referring to each image.
+func WriteJSON(w io.Writer, images []Image) error {
+	if images == nil {
+		images = []Image{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(images)
+}
+
+// MappingOptions decide where WriteMapping pulls the images from and pushes them to.
+type MappingOptions struct {
+	// SourceRewrites rewrite the references the images are pulled from, for instance to pull them from a
+	// mirror of their registry. Images no rule matches are pulled from their registry.
+	SourceRewrites Rewrites
+	// DestinationRewrites rewrite the references of the images into the references they are pushed to. They
+	// match the references of the catalog, before SourceRewrites apply.
+	DestinationRewrites Rewrites
+	// Destination is the registry, optionally followed by a namespace, that the images no DestinationRewrites
+	// match are pushed to, under their repository path: registry.redhat.io/ubi9/ubi is pushed to
+	// mirror.example.com/ns/ubi9/ubi when Destination is mirror.example.com/ns.
+	Destination string
+}
+
+// WriteMapping writes a mapping file for `oc image mirror`: one source=destination line per image. Images are
+// pulled by digest when their reference has one, and pushed with their tag when it has one. Images referenced
+// by digest only are pushed with a tag derived from their digest, such as sha256-<hex>, so that the images of a
+// repository do not overwrite each other and catalogs pinned to digests still resolve from the mirror.
+// Images that no rule maps to a destination, and destinations shared by several images, are reported in the
+// returned error and nothing is written.
+func WriteMapping(w io.Writer, images []Image, opts MappingOptions) error {
+	if err := errors.Join(opts.SourceRewrites.Validate(), opts.DestinationRewrites.Validate()); err != nil {
+		return err
+	}
+	var b strings.Builder
+	var errs []error
+	mirroredFrom := map[string]string{}
+	for _, image := range images {
+		source, destination, err := mapping(image.Reference, opts)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if other, ok := mirroredFrom[destination]; ok {
+			errs = append(errs, fmt.Errorf("images %q and %q are both mirrored to %q", other, image.Reference, destination))
+			continue
+		}
+		mirroredFrom[destination] = image.Reference
+		fmt.Fprintf(&b, "%s=%s\n", source, destination)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mapping returns the references an image is pulled from and pushed to.
+func mapping(reference string, opts MappingOptions) (string, string, error) {
+	ref, err := ParseReference(reference)
+	if err != nil {
+		return "", "", err
+	}
+	source, _, err := opts.SourceRewrites.Apply(ref)
+	if err != nil {
+		return "", "", err
+	}
+	destination, ok, err := opts.DestinationRewrites.Apply(ref)
+	if err != nil {
+		return "", "", err
+	}
+	if !ok {
+		if opts.Destination == "" {
+			return "", "", fmt.Errorf("no destination for image %q", reference)
+		}
+		destination, _, err = Rewrite{From: ref.Registry, To: opts.Destination}.Apply(ref)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if source.Digest != "" {
+		source.Tag = ""
+	}
+	// the pushed image keeps the digest of the pulled image, the destination only names its tag
+	if destination.Tag == "" && destination.Digest != "" {
+		destination.Tag = digestTag(destination.Digest)
+	}
+	destination.Digest = ""
+	return source.String(), destination.String(), nil
+}
+
+// digestTag returns the tag an image referenced by digest only is pushed with: the digest, with its algorithm
+// separated by a dash, such as sha256-<hex>.
+func digestTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1)
+}
@@ -0,0 +1,387 @@
+// Package ocilayout reads the file based catalog of a catalog image stored as an OCI image layout, such as the ones
+// `skopeo copy docker://... oci:dir` produces, and writes filtered catalogs back as OCI image layouts.
+package ocilayout
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ConfigsLabel is the label of catalog images naming the directory of their file based catalog.
+	ConfigsLabel = "operators.operatorframework.io.index.configs.v1"
+	// DefaultConfigsDir is the directory of the file based catalog of the images without ConfigsLabel.
+	DefaultConfigsDir = "/configs"
+	// RefNameAnnotation names the images of an OCI image layout, such as the tag given to skopeo.
+	RefNameAnnotation = "org.opencontainers.image.ref.name"
+
+	layoutFile    = "oci-layout"
+	layoutVersion = "1.0.0"
+	indexFile     = "index.json"
+
+	mediaTypeImageIndex    = "application/vnd.oci.image.index.v1+json"
+	mediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeImageConfig   = "application/vnd.oci.image.config.v1+json"
+	mediaTypeLayer         = "application/vnd.oci.image.layer.v1.tar"
+	mediaTypeLayerGzip     = "application/vnd.oci.image.layer.v1.tar+gzip"
+
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerLayerGzip    = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+)
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *platform         `json:"platform,omitempty"`
+}
+
+type platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (p *platform) String() string {
+	if p.Variant != "" {
+		return p.OS + "/" + p.Architecture + "/" + p.Variant
+	}
+	return p.OS + "/" + p.Architecture
+}
+
+type index struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	Manifests     []descriptor      `json:"manifests"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	Config        descriptor        `json:"config"`
+	Layers        []descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// imageConfig holds the fields of an image configuration this package reads, the others are kept as they are.
+type imageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Config       struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+type options struct {
+	reference string
+	platform  string
+}
+
+// Option selects the image of an OCI image layout.
+type Option func(*options)
+
+// WithReference selects the image named reference by its org.opencontainers.image.ref.name annotation, which
+// is required when the layout holds several images.
+func WithReference(reference string) Option {
+	return func(opts *options) {
+		opts.reference = reference
+	}
+}
+
+// WithPlatform selects the image of a platform, such as linux/amd64 or linux/arm64/v8, in a multi-platform
+// image. By default, the first image of the index is read, catalog images holding the same catalog for all
+// platforms.
+func WithPlatform(platform string) Option {
+	return func(opts *options) {
+		opts.platform = platform
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// image is the manifest and configuration of the image selected in a layout.
+type image struct {
+	manifest  manifest
+	config    imageConfig
+	rawConfig []byte
+}
+
+// configsDir returns the directory of the catalog in the image, without its leading slash.
+func (img *image) configsDir() string {
+	dir := img.config.Config.Labels[ConfigsLabel]
+	if dir == "" {
+		dir = DefaultConfigsDir
+	}
+	return strings.TrimPrefix(path.Clean("/"+dir), "/")
+}
+
+// readImage reads the manifest and configuration of the image selected by opts in the layout at dir.
+func readImage(dir string, opts options) (*image, error) {
+	candidates, err := candidateManifests(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	desc := candidates[0]
+	if len(candidates) > 1 {
+		// the images of a layout are either named, or the platforms of a single image
+		if candidates[0].Platform == nil {
+			return nil, fmt.Errorf("%q holds %d images, select one by its reference name", dir, len(candidates))
+		}
+		if desc, err = selectManifest(candidates, opts.platform); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		data, err := readBlob(dir, desc)
+		if err != nil {
+			return nil, err
+		}
+		switch desc.MediaType {
+		case mediaTypeImageIndex, mediaTypeDockerManifestList:
+			nested := index{}
+			if err := json.Unmarshal(data, &nested); err != nil {
+				return nil, fmt.Errorf("parse image index %s: %v", desc.Digest, err)
+			}
+			if desc, err = selectManifest(nested.Manifests, opts.platform); err != nil {
+				return nil, err
+			}
+		case mediaTypeImageManifest, mediaTypeDockerManifest:
+			img := &image{}
+			if err := json.Unmarshal(data, &img.manifest); err != nil {
+				return nil, fmt.Errorf("parse image manifest %s: %v", desc.Digest, err)
+			}
+			if img.rawConfig, err = readBlob(dir, img.manifest.Config); err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(img.rawConfig, &img.config); err != nil {
+				return nil, fmt.Errorf("parse image config %s: %v", img.manifest.Config.Digest, err)
+			}
+			if opts.platform != "" && img.config.OS+"/"+img.config.Architecture != platformOSArch(opts.platform) {
+				return nil, fmt.Errorf("no image for platform %q, found %s/%s", opts.platform, img.config.OS, img.config.Architecture)
+			}
+			return img, nil
+		default:
+			return nil, fmt.Errorf("unsupported media type %q of %s", desc.MediaType, desc.Digest)
+		}
+	}
+}
+
+// candidateManifests returns the descriptors of the index of the layout at dir that are named by the reference
+// of opts, or all of them when opts has no reference.
+func candidateManifests(dir string, opts options) ([]descriptor, error) {
+	data, err := os.ReadFile(filepath.Join(dir, layoutFile))
+	if err != nil {
+		return nil, fmt.Errorf("%q is not an OCI image layout: %v", dir, err)
+	}
+	layout := struct {
+		ImageLayoutVersion string `json:"imageLayoutVersion"`
+	}{}
+	if err := json.Unmarshal(data, &layout); err != nil {
+		return nil, fmt.Errorf("%q is not an OCI image layout: %v", dir, err)
+	}
+	if layout.ImageLayoutVersion != layoutVersion {
+		return nil, fmt.Errorf("unsupported OCI image layout version %q", layout.ImageLayoutVersion)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil {
+		return nil, err
+	}
+	idx := index{}
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", indexFile, err)
+	}
+
+	candidates := idx.Manifests
+	if opts.reference != "" {
+		candidates = nil
+		for _, desc := range idx.Manifests {
+			if desc.Annotations[RefNameAnnotation] == opts.reference {
+				candidates = append(candidates, desc)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no image named %q in %q", opts.reference, dir)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no image in %q", dir)
+	}
+	return candidates, nil
+}
+
+// imagePlatforms returns the platforms of the multi-platform image selected by opts in the layout at dir, or
+// nil when the image has a single platform or opts selects one. Descriptors of an unknown platform, such as
+// the attestations of buildx, are not images.
+func imagePlatforms(dir string, opts options) ([]string, error) {
+	if opts.platform != "" {
+		return nil, nil
+	}
+	descs, err := candidateManifests(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(descs) == 1 {
+		switch descs[0].MediaType {
+		case mediaTypeImageIndex, mediaTypeDockerManifestList:
+			data, err := readBlob(dir, descs[0])
+			if err != nil {
+				return nil, err
+			}
+			nested := index{}
+			if err := json.Unmarshal(data, &nested); err != nil {
+				return nil, fmt.Errorf("parse image index %s: %v", descs[0].Digest, err)
+			}
+			descs = nested.Manifests
+		default:
+			return nil, nil
+		}
+	}
+	var platforms []string
+	for _, desc := range descs {
+		if desc.Platform == nil || desc.Platform.OS == "unknown" {
+			continue
+		}
+		platforms = append(platforms, desc.Platform.String())
+	}
+	if len(platforms) < 2 {
+		return nil, nil
+	}
+	return platforms, nil
+}
+
+// selectManifest returns the descriptor of descs for platform, or the first one when platform is not set.
+func selectManifest(descs []descriptor, platform string) (descriptor, error) {
+	if len(descs) == 0 {
+		return descriptor{}, errors.New("empty image index")
+	}
+	if platform == "" {
+		return descs[0], nil
+	}
+	var platforms []string
+	for _, desc := range descs {
+		if desc.Platform == nil {
+			continue
+		}
+		if desc.Platform.String() == platform || desc.Platform.OS+"/"+desc.Platform.Architecture == platform {
+			return desc, nil
+		}
+		platforms = append(platforms, desc.Platform.String())
+	}
+	return descriptor{}, fmt.Errorf("no image for platform %q, found %s", platform, strings.Join(platforms, ", "))
+}
+
+// platformOSArch returns the os/arch part of platform, without its variant.
+func platformOSArch(platform string) string {
+	parts := strings.SplitN(platform, "/", 3)
+	return strings.Join(parts[:min(len(parts), 2)], "/")
+}
+
+// blobPath returns the path of the blob of desc in the layout at dir.
+func blobPath(dir string, desc descriptor) (string, error) {
+	algorithm, encoded, ok := strings.Cut(desc.Digest, ":")
+	if !ok || algorithm != "sha256" || len(encoded) != sha256.Size*2 || strings.ContainsAny(encoded, `/\.`) {
+		return "", fmt.Errorf("unsupported digest %q", desc.Digest)
+	}
+	return filepath.Join(dir, "blobs", algorithm, encoded), nil
+}
+
+// readBlob reads the blob of desc, and checks its size and digest.
+func readBlob(dir string, desc descriptor) ([]byte, error) {
+	r, err := openBlob(dir, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// openBlob opens the blob of desc. Reading it fails if its size or digest do not match desc.
+func openBlob(dir string, desc descriptor) (io.ReadCloser, error) {
+	path, err := blobPath(dir, desc)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &verifyingReader{file: f, desc: desc, hash: sha256.New()}, nil
+}
+
+type verifyingReader struct {
+	file *os.File
+	desc descriptor
+	hash hash.Hash
+	read int64
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.file.Read(p)
+	r.hash.Write(p[:n])
+	r.read += int64(n)
+	if err == io.EOF {
+		if r.read != r.desc.Size {
+			return n, fmt.Errorf("blob %s has %d bytes, expected %d", r.desc.Digest, r.read, r.desc.Size)
+		}
+		if digest := "sha256:" + hex.EncodeToString(r.hash.Sum(nil)); digest != r.desc.Digest {
+			return n, fmt.Errorf("blob %s has digest %s", r.desc.Digest, digest)
+		}
+	}
+	return n, err
+}
+
+func (r *verifyingReader) Close() error {
+	return r.file.Close()
+}
+
+// writeBlob writes data to the layout at dir, and returns its descriptor.
+func writeBlob(dir, mediaType string, data []byte) (descriptor, error) {
+	sum := sha256.Sum256(data)
+	desc := descriptor{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(data))}
+	path, err := blobPath(dir, desc)
+	if err != nil {
+		return desc, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return desc, err
+	}
+	return desc, os.WriteFile(path, data, 0666)
+}
+
+// writeJSON writes v to the layout at dir as a blob of mediaType.
+func writeJSON(dir, mediaType string, v any) (descriptor, error) {
+	data, err := marshal(v)
+	if err != nil {
+		return descriptor{}, err
+	}
+	return writeBlob(dir, mediaType, data)
+}
+
+func marshal(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
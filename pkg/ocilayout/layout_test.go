@@ -0,0 +1,343 @@
+package ocilayout
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	data     string
+}
+
+func file(name, data string) tarEntry { return tarEntry{name: name, typeflag: tar.TypeReg, data: data} }
+
+func packageCatalog(name string) string {
+	return `{"schema": "olm.package", "name": "` + name + `", "defaultChannel": "stable"}`
+}
+
+// writeLayer writes a tar layer of entries to the layout at dir, gzipped or not, and returns its descriptor and
+// diff ID.
+func writeLayer(t *testing.T, dir string, gzipped bool, entries ...tarEntry) (descriptor, string) {
+	tarball := &bytes.Buffer{}
+	tw := tar.NewWriter(tarball)
+	for _, e := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: e.typeflag, Name: e.name, Mode: 0644, Size: int64(len(e.data))}))
+		_, err := tw.Write([]byte(e.data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	sum := sha256.Sum256(tarball.Bytes())
+	diffID := "sha256:" + hex.EncodeToString(sum[:])
+	if !gzipped {
+		desc, err := writeBlob(dir, mediaTypeLayer, tarball.Bytes())
+		require.NoError(t, err)
+		return desc, diffID
+	}
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	_, err := gz.Write(tarball.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	desc, err := writeBlob(dir, mediaTypeLayerGzip, compressed.Bytes())
+	require.NoError(t, err)
+	return desc, diffID
+}
+
+// writeImage writes an image of layers to the layout at dir, and returns its manifest descriptor.
+func writeImage(t *testing.T, dir, arch string, labels map[string]string, layers ...[]tarEntry) descriptor {
+	m := manifest{SchemaVersion: 2, MediaType: mediaTypeImageManifest}
+	diffIDs := []string{}
+	for i, entries := range layers {
+		desc, diffID := writeLayer(t, dir, i%2 == 0, entries...)
+		m.Layers = append(m.Layers, desc)
+		diffIDs = append(diffIDs, diffID)
+	}
+	config := map[string]any{
+		"architecture": arch,
+		"os":           "linux",
+		"config":       map[string]any{"Labels": labels, "Entrypoint": []string{"/bin/opm"}, "Cmd": []string{"serve", "/configs", "--cache-dir=/tmp/cache"}},
+		"rootfs":       map[string]any{"type": "layers", "diff_ids": diffIDs},
+		"history":      []any{map[string]any{"created_by": "base"}},
+	}
+	var err error
+	m.Config, err = writeJSON(dir, mediaTypeImageConfig, config)
+	require.NoError(t, err)
+	desc, err := writeJSON(dir, mediaTypeImageManifest, m)
+	require.NoError(t, err)
+	desc.Platform = &platform{OS: "linux", Architecture: arch}
+	return desc
+}
+
+func baseLayers() [][]tarEntry {
+	return [][]tarEntry{
+		{
+			file("bin/opm", "#!/bin/sh"),
+			{name: "configs/", typeflag: tar.TypeDir},
+			file("configs/foo/catalog.json", packageCatalog("foo")),
+			file("configs/bar/catalog.json", packageCatalog("bar")),
+			file("configs/baz/catalog.json", packageCatalog("baz")),
+		},
+		{
+			file("configs/.wh.bar", ""),
+			file("configs/baz/.wh..wh..opq", ""),
+			file("configs/baz/catalog.yaml", "schema: olm.package\nname: baz\ndefaultChannel: fast\n"),
+			file("tmp/cache/cache.json", "{}"),
+		},
+	}
+}
+
+// testLayout writes a layout holding a single image named v1.
+func testLayout(t *testing.T, labels map[string]string, layers ...[]tarEntry) string {
+	dir := t.TempDir()
+	desc := writeImage(t, dir, "amd64", labels, layers...)
+	desc.Platform = nil
+	desc.Annotations = map[string]string{RefNameAnnotation: "v1"}
+	require.NoError(t, writeLayout(dir, index{SchemaVersion: 2, Manifests: []descriptor{desc}}))
+	return dir
+}
+
+func packages(fbc *declcfg.DeclarativeConfig) map[string]string {
+	defaultChannels := map[string]string{}
+	for _, p := range fbc.Packages {
+		defaultChannels[p.Name] = p.DefaultChannel
+	}
+	return defaultChannels
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("WHEN layers have whiteouts THEN Loads the catalog of the top layer", func(t *testing.T) {
+		dir := testLayout(t, nil, baseLayers()...)
+		fbc, err := Load(context.Background(), dir)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"foo": "stable", "baz": "fast"}, packages(fbc))
+	})
+	t.Run("WHEN image has the configs label THEN Loads the catalog of the labelled directory", func(t *testing.T) {
+		dir := testLayout(t, map[string]string{ConfigsLabel: "/catalog/"}, []tarEntry{
+			file("configs/foo/catalog.json", packageCatalog("foo")),
+			file("catalog/bar/catalog.json", packageCatalog("bar")),
+		})
+		fbc, err := Load(context.Background(), dir)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"bar": "stable"}, packages(fbc))
+	})
+	t.Run("WHEN image is multi-platform THEN Loads the image of the platform", func(t *testing.T) {
+		dir := t.TempDir()
+		amd64 := writeImage(t, dir, "amd64", nil, []tarEntry{file("configs/foo/catalog.json", packageCatalog("foo"))})
+		arm64 := writeImage(t, dir, "arm64", nil, []tarEntry{file("configs/bar/catalog.json", packageCatalog("bar"))})
+		idx, err := writeJSON(dir, mediaTypeImageIndex, index{SchemaVersion: 2, MediaType: mediaTypeImageIndex, Manifests: []descriptor{amd64, arm64}})
+		require.NoError(t, err)
+		require.NoError(t, writeLayout(dir, index{SchemaVersion: 2, Manifests: []descriptor{idx}}))
+
+		fbc, err := Load(context.Background(), dir)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"foo": "stable"}, packages(fbc))
+		fbc, err = Load(context.Background(), dir, WithPlatform("linux/arm64"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"bar": "stable"}, packages(fbc))
+		_, err = Load(context.Background(), dir, WithPlatform("linux/s390x"))
+		assert.EqualError(t, err, `no image for platform "linux/s390x", found linux/amd64, linux/arm64`)
+	})
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout func(t *testing.T) string
+		opts   []Option
+		err    string
+	}{
+		{
+			name:   "WHEN directory is not a layout THEN Returns error",
+			layout: func(t *testing.T) string { return t.TempDir() },
+			err:    "is not an OCI image layout",
+		},
+		{
+			name: "WHEN layout holds several images THEN Returns error",
+			layout: func(t *testing.T) string {
+				dir := t.TempDir()
+				v1 := writeImage(t, dir, "amd64", nil, baseLayers()...)
+				v2 := writeImage(t, dir, "amd64", nil, baseLayers()[:1]...)
+				v1.Platform, v2.Platform = nil, nil
+				require.NoError(t, writeLayout(dir, index{SchemaVersion: 2, Manifests: []descriptor{v1, v2}}))
+				return dir
+			},
+			err: "holds 2 images, select one by its reference name",
+		},
+		{
+			name:   "WHEN no image has the reference name THEN Returns error",
+			layout: func(t *testing.T) string { return testLayout(t, nil, baseLayers()...) },
+			opts:   []Option{WithReference("v2")},
+			err:    `no image named "v2"`,
+		},
+		{
+			name:   "WHEN image has no catalog THEN Returns error",
+			layout: func(t *testing.T) string { return testLayout(t, nil, []tarEntry{file("bin/opm", "#!/bin/sh")}) },
+			err:    "no file based catalog found in /configs of the image",
+		},
+		{
+			name: "WHEN a layer does not match its digest THEN Returns error",
+			layout: func(t *testing.T) string {
+				dir := testLayout(t, nil, baseLayers()...)
+				img, err := readImage(dir, options{})
+				require.NoError(t, err)
+				path, err := blobPath(dir, img.manifest.Layers[1])
+				require.NoError(t, err)
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+				data = bytes.Replace(data, []byte("fast"), []byte("slow"), 1)
+				require.NoError(t, os.WriteFile(path, data, 0666))
+				return dir
+			},
+			err: "has digest sha256:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(context.Background(), tt.layout(t), tt.opts...)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func writeTestCatalog() declcfg.DeclarativeConfig {
+	return declcfg.DeclarativeConfig{
+		Packages: []declcfg.Package{{Schema: declcfg.SchemaPackage, Name: "foo", DefaultChannel: "candidate"}, {Schema: declcfg.SchemaPackage, Name: "qux", DefaultChannel: "stable"}},
+		Channels: []declcfg.Channel{{Schema: declcfg.SchemaChannel, Package: "foo", Name: "candidate", Entries: []declcfg.ChannelEntry{{Name: "foo.v1"}}}},
+		Bundles:  []declcfg.Bundle{{Schema: declcfg.SchemaBundle, Package: "foo", Name: "foo.v1", Image: "quay.io/example/foo-bundle:v1"}},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "filtered")
+	require.NoError(t, Write(dir, writeTestCatalog(), WithReferenceName("filtered")))
+
+	fbc, err := Load(context.Background(), dir, WithReference("filtered"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "candidate", "qux": "stable"}, packages(fbc))
+	require.Len(t, fbc.Bundles, 1)
+	assert.Equal(t, "quay.io/example/foo-bundle:v1", fbc.Bundles[0].Image)
+
+	img, err := readImage(dir, options{})
+	require.NoError(t, err)
+	assert.Len(t, img.manifest.Layers, 1)
+	assert.Equal(t, "/configs", img.config.Config.Labels[ConfigsLabel])
+	assert.Equal(t, "linux/amd64", img.config.OS+"/"+img.config.Architecture)
+
+	// the same catalog produces the same image
+	other := filepath.Join(t.TempDir(), "filtered")
+	require.NoError(t, Write(other, writeTestCatalog(), WithReferenceName("filtered")))
+	index, err := os.ReadFile(filepath.Join(dir, indexFile))
+	require.NoError(t, err)
+	otherIndex, err := os.ReadFile(filepath.Join(other, indexFile))
+	require.NoError(t, err)
+	assert.Equal(t, string(index), string(otherIndex))
+
+	assert.ErrorContains(t, Write(dir, writeTestCatalog()), "already holds an OCI image layout")
+}
+
+func TestWrite_BaseImage(t *testing.T) {
+	base := testLayout(t, map[string]string{ConfigsLabel: "/configs", "vendor": "example"}, baseLayers()...)
+	dir := t.TempDir()
+	require.NoError(t, Write(dir, writeTestCatalog(), WithBaseImage(base, WithReference("v1"))))
+
+	fbc, err := Load(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "candidate", "qux": "stable"}, packages(fbc))
+
+	baseImg, err := readImage(base, options{})
+	require.NoError(t, err)
+	img, err := readImage(dir, options{})
+	require.NoError(t, err)
+	require.Len(t, img.manifest.Layers, 3)
+	assert.Equal(t, baseImg.manifest.Layers, img.manifest.Layers[:2])
+	assert.Equal(t, map[string]string{ConfigsLabel: "/configs", "vendor": "example"}, img.config.Config.Labels)
+
+	config := struct {
+		Config struct {
+			Entrypoint []string `json:"Entrypoint"`
+			Cmd        []string `json:"Cmd"`
+		} `json:"config"`
+		RootFS struct {
+			DiffIDs []string `json:"diff_ids"`
+		} `json:"rootfs"`
+		History []map[string]string `json:"history"`
+	}{}
+	require.NoError(t, json.Unmarshal(img.rawConfig, &config))
+	assert.Equal(t, []string{"/bin/opm"}, config.Config.Entrypoint)
+	// opm builds the cache of the filtered catalog when it starts
+	assert.Equal(t, []string{"serve", "/configs"}, config.Config.Cmd)
+	assert.Len(t, config.RootFS.DiffIDs, 3)
+	assert.Equal(t, []map[string]string{{"created_by": "base"}, {"created_by": "catalog-filter", "comment": "filtered file based catalog"}}, config.History)
+
+	// the files of the base image outside of the catalog are kept
+	files := map[string]bool{}
+	for _, layer := range img.manifest.Layers {
+		blob, err := openBlob(dir, layer)
+		require.NoError(t, err)
+		var r io.Reader = blob
+		if layer.MediaType == mediaTypeLayerGzip {
+			gz, err := gzip.NewReader(blob)
+			require.NoError(t, err)
+			r = gz
+		}
+		tr := tar.NewReader(r)
+		for hdr, err := tr.Next(); err == nil; hdr, err = tr.Next() {
+			files[hdr.Name] = true
+		}
+		blob.Close()
+	}
+	assert.True(t, files["bin/opm"])
+	assert.True(t, files["configs/.wh..wh..opq"])
+	assert.True(t, files["configs/foo/catalog.json"])
+	assert.True(t, files["tmp/.wh.cache"])
+}
+
+func TestWrite_MultiPlatformBaseImage(t *testing.T) {
+	base := t.TempDir()
+	amd64 := writeImage(t, base, "amd64", nil, baseLayers()...)
+	arm64 := writeImage(t, base, "arm64", nil, baseLayers()...)
+	idx, err := writeJSON(base, mediaTypeImageIndex, index{SchemaVersion: 2, MediaType: mediaTypeImageIndex, Manifests: []descriptor{amd64, arm64}})
+	require.NoError(t, err)
+	idx.Annotations = map[string]string{RefNameAnnotation: "v1"}
+	require.NoError(t, writeLayout(base, index{SchemaVersion: 2, Manifests: []descriptor{idx}}))
+
+	dir := t.TempDir()
+	require.NoError(t, Write(dir, writeTestCatalog(), WithBaseImage(base, WithReference("v1")), WithReferenceName("filtered")))
+	platforms, err := imagePlatforms(dir, options{reference: "filtered"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64"}, platforms)
+	for _, p := range platforms {
+		fbc, err := Load(context.Background(), dir, WithReference("filtered"), WithPlatform(p))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"foo": "candidate", "qux": "stable"}, packages(fbc))
+		img, err := readImage(dir, options{reference: "filtered", platform: p})
+		require.NoError(t, err)
+		assert.Len(t, img.manifest.Layers, 3)
+	}
+
+	// selecting a platform of the base image writes the image of this platform only
+	dir = t.TempDir()
+	require.NoError(t, Write(dir, writeTestCatalog(), WithBaseImage(base, WithReference("v1"), WithPlatform("linux/arm64"))))
+	platforms, err = imagePlatforms(dir, options{})
+	require.NoError(t, err)
+	assert.Nil(t, platforms)
+	img, err := readImage(dir, options{})
+	require.NoError(t, err)
+	assert.Equal(t, "linux/arm64", img.config.OS+"/"+img.config.Architecture)
+}
@@ -0,0 +1,185 @@
+package ocilayout
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"testing/fstest"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+	maxSymlinks    = 10
+)
+
+// Load reads the file based catalog of the image of the OCI image layout at dir, see ConfigsFS.
+func Load(ctx context.Context, dir string, opts ...Option) (*declcfg.DeclarativeConfig, error) {
+	fsys, err := ConfigsFS(dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return declcfg.LoadFS(ctx, fsys)
+}
+
+// ConfigsFS returns the file based catalog of the image of the OCI image layout at dir: the directory named by
+// its operators.operatorframework.io.index.configs.v1 label, /configs by default. The layers of the image are
+// applied in order, honouring their whiteouts, and only the files of the catalog are kept in memory. The returned
+// filesystem can be streamed through a filter with filter.FilterFS.
+func ConfigsFS(dir string, opts ...Option) (fs.FS, error) {
+	img, err := readImage(dir, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	configsDir := img.configsDir()
+	files := map[string]*tar.Header{}
+	contents := map[string][]byte{}
+	for _, layer := range img.manifest.Layers {
+		if err := applyLayer(dir, layer, configsDir, files, contents); err != nil {
+			return nil, fmt.Errorf("layer %s: %v", layer.Digest, err)
+		}
+	}
+
+	fsys := fstest.MapFS{}
+	for name, hdr := range files {
+		rel, ok := relativeTo(name, configsDir)
+		if !ok || rel == "." {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			fsys[rel] = &fstest.MapFile{Mode: fs.ModeDir | hdr.FileInfo().Mode().Perm()}
+		case tar.TypeReg, tar.TypeSymlink, tar.TypeLink:
+			target, err := resolve(name, files)
+			if err != nil {
+				return nil, err
+			}
+			if files[target].Typeflag == tar.TypeReg {
+				fsys[rel] = &fstest.MapFile{Data: contents[target], Mode: files[target].FileInfo().Mode().Perm()}
+			}
+		}
+	}
+	if len(fsys) == 0 {
+		return nil, fmt.Errorf("no file based catalog found in /%s of the image", configsDir)
+	}
+	return fsys, nil
+}
+
+// applyLayer applies the changes of a layer to the files under configsDir.
+func applyLayer(dir string, layer descriptor, configsDir string, files map[string]*tar.Header, contents map[string][]byte) error {
+	blob, err := openBlob(dir, layer)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	var r io.Reader = blob
+	switch layer.MediaType {
+	case mediaTypeLayer:
+	case mediaTypeLayerGzip, mediaTypeDockerLayerGzip:
+		gz, err := gzip.NewReader(blob)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	default:
+		return fmt.Errorf("unsupported layer media type %q", layer.MediaType)
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := cleanPath(hdr.Name)
+		base := path.Base(name)
+		switch {
+		case base == whiteoutOpaque:
+			removeUnder(path.Dir(name), files, contents, false)
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			removeUnder(path.Join(path.Dir(name), strings.TrimPrefix(base, whiteoutPrefix)), files, contents, true)
+			continue
+		}
+		if _, ok := relativeTo(name, configsDir); !ok {
+			continue
+		}
+		removeUnder(name, files, contents, hdr.Typeflag != tar.TypeDir)
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			contents[name] = data
+		case tar.TypeLink:
+			hdr.Linkname = cleanPath(hdr.Linkname)
+		case tar.TypeDir, tar.TypeSymlink:
+		default:
+			continue
+		}
+		files[name] = hdr
+	}
+	// the whole layer is read for its digest to be checked
+	_, err = io.Copy(io.Discard, blob)
+	return err
+}
+
+// removeUnder removes the files under name and, when self is set, name itself.
+func removeUnder(name string, files map[string]*tar.Header, contents map[string][]byte, self bool) {
+	for file := range files {
+		if (self && file == name) || strings.HasPrefix(file, name+"/") || name == "." {
+			delete(files, file)
+			delete(contents, file)
+		}
+	}
+}
+
+// resolve follows the links from name to the file they point to.
+func resolve(name string, files map[string]*tar.Header) (string, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		hdr, ok := files[name]
+		if !ok {
+			return "", fmt.Errorf("link to %q, which is not in the catalog", name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			if path.IsAbs(hdr.Linkname) {
+				name = cleanPath(hdr.Linkname)
+			} else {
+				name = cleanPath(path.Join(path.Dir(name), hdr.Linkname))
+			}
+		case tar.TypeLink:
+			name = hdr.Linkname
+		default:
+			return name, nil
+		}
+	}
+	return "", errors.New("too many levels of symbolic links")
+}
+
+// cleanPath returns name relative to the root of the image.
+func cleanPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// relativeTo returns name relative to dir, if it is dir or under dir.
+func relativeTo(name, dir string) (string, bool) {
+	if name == dir {
+		return ".", true
+	}
+	rel, ok := strings.CutPrefix(name, dir+"/")
+	return rel, ok
+}
@@ -0,0 +1,382 @@
+package ocilayout
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/operator-framework/operator-registry/alpha/declcfg"
+)
+
+type writeOptions struct {
+	base     string
+	baseOpts []Option
+	refName  string
+}
+
+// WriteOption configures the image Write produces.
+type WriteOption func(*writeOptions)
+
+// WithBaseImage builds the image on top of the image of the OCI image layout at dir, selected by opts, such as
+// the catalog image the catalog was filtered from. The filtered catalog replaces the catalog of the base image,
+// in the directory named by its operators.operatorframework.io.index.configs.v1 label. The cache of the catalog
+// built in the base image by `opm serve --cache-dir` is removed, along with the --cache-dir argument of its
+// command, for opm to build the cache of the filtered catalog when it starts.
+func WithBaseImage(dir string, opts ...Option) WriteOption {
+	return func(o *writeOptions) {
+		o.base = dir
+		o.baseOpts = opts
+	}
+}
+
+// WithReferenceName names the image of the layout, such as the tag skopeo copies it as.
+func WithReferenceName(name string) WriteOption {
+	return func(o *writeOptions) {
+		o.refName = name
+	}
+}
+
+// Write writes fbc to a new OCI image layout at dir, as a single layer holding one catalog.json per package
+// under the catalog directory, so that the same catalog always produces the same image. Without WithBaseImage,
+// the image only holds this layer, for a linux/amd64 platform, and its catalog is in /configs. With a
+// multi-platform base image, the layer is added on top of the image of each platform, unless the options of
+// WithBaseImage select one of them.
+func Write(dir string, fbc declcfg.DeclarativeConfig, opts ...WriteOption) error {
+	o := writeOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if _, err := os.Stat(filepath.Join(dir, indexFile)); err == nil {
+		return fmt.Errorf("%q already holds an OCI image layout", dir)
+	}
+	var platforms []string
+	if o.base != "" {
+		var err error
+		if platforms, err = imagePlatforms(o.base, newOptions(o.baseOpts)); err != nil {
+			return fmt.Errorf("base image: %v", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	var desc descriptor
+	if len(platforms) == 0 {
+		var err error
+		if desc, err = writeCatalogImage(dir, fbc, o.base, newOptions(o.baseOpts)); err != nil {
+			return err
+		}
+	} else {
+		platformIndex := index{SchemaVersion: 2, MediaType: mediaTypeImageIndex}
+		for _, p := range platforms {
+			baseOpts := newOptions(o.baseOpts)
+			baseOpts.platform = p
+			manifestDesc, err := writeCatalogImage(dir, fbc, o.base, baseOpts)
+			if err != nil {
+				return err
+			}
+			platformIndex.Manifests = append(platformIndex.Manifests, manifestDesc)
+		}
+		var err error
+		if desc, err = writeJSON(dir, mediaTypeImageIndex, platformIndex); err != nil {
+			return err
+		}
+	}
+	if o.refName != "" {
+		desc.Annotations = map[string]string{RefNameAnnotation: o.refName}
+	}
+	return writeLayout(dir, index{SchemaVersion: 2, MediaType: mediaTypeImageIndex, Manifests: []descriptor{desc}})
+}
+
+// writeCatalogImage writes the image holding fbc to the layout at dir, on top of the image selected by baseOpts
+// in the layout at base when it is set, and returns the descriptor of its manifest.
+func writeCatalogImage(dir string, fbc declcfg.DeclarativeConfig, base string, baseOpts options) (descriptor, error) {
+	img := &image{}
+	config := map[string]any{
+		"architecture": "amd64",
+		"os":           "linux",
+		"config":       map[string]any{},
+		"rootfs":       map[string]any{"type": "layers", "diff_ids": []any{}},
+	}
+	var cacheDir string
+	if base != "" {
+		var err error
+		if img, err = readImage(base, baseOpts); err != nil {
+			return descriptor{}, fmt.Errorf("base image: %v", err)
+		}
+		if err := json.Unmarshal(img.rawConfig, &config); err != nil {
+			return descriptor{}, fmt.Errorf("base image: %v", err)
+		}
+		cacheDir = dropServeCache(config)
+	}
+	configsDir := img.configsDir()
+
+	layer, diffID, err := catalogLayer(fbc, configsDir, base != "", cacheDir)
+	if err != nil {
+		return descriptor{}, err
+	}
+	out := manifest{SchemaVersion: 2, MediaType: mediaTypeImageManifest}
+	for _, baseLayer := range img.manifest.Layers {
+		if err := copyBlob(base, dir, baseLayer); err != nil {
+			return descriptor{}, err
+		}
+		if baseLayer.MediaType == mediaTypeDockerLayerGzip {
+			baseLayer.MediaType = mediaTypeLayerGzip
+		}
+		out.Layers = append(out.Layers, descriptor{MediaType: baseLayer.MediaType, Digest: baseLayer.Digest, Size: baseLayer.Size})
+	}
+	layerDesc, err := writeBlob(dir, mediaTypeLayerGzip, layer)
+	if err != nil {
+		return descriptor{}, err
+	}
+	out.Layers = append(out.Layers, layerDesc)
+
+	addLayerToConfig(config, configsDir, diffID)
+	if out.Config, err = writeJSON(dir, mediaTypeImageConfig, config); err != nil {
+		return descriptor{}, err
+	}
+	manifestDesc, err := writeJSON(dir, mediaTypeImageManifest, out)
+	if err != nil {
+		return descriptor{}, err
+	}
+	manifestDesc.Platform = &platform{}
+	manifestDesc.Platform.OS, _ = config["os"].(string)
+	manifestDesc.Platform.Architecture, _ = config["architecture"].(string)
+	manifestDesc.Platform.Variant, _ = config["variant"].(string)
+	return manifestDesc, nil
+}
+
+// dropServeCache removes the --cache-dir and --cache-enforce-integrity arguments of `opm serve` from the
+// entrypoint and command of config, and returns the cache directory, without its leading slash. The cache built
+// in the base image is out of date once its catalog is replaced, and opm refuses to serve a catalog that does
+// not match its cache: without these arguments, opm builds the cache of the catalog when it starts.
+func dropServeCache(config map[string]any) string {
+	containerConfig, _ := config["config"].(map[string]any)
+	var cacheDir string
+	for _, key := range []string{"Entrypoint", "Cmd"} {
+		args, ok := containerConfig[key].([]any)
+		if !ok {
+			continue
+		}
+		kept := []any{}
+		for i := 0; i < len(args); i++ {
+			arg, _ := args[i].(string)
+			name, value, hasValue := strings.Cut(arg, "=")
+			switch name {
+			case "--cache-dir":
+				if !hasValue && i+1 < len(args) {
+					i++
+					value, _ = args[i].(string)
+				}
+				cacheDir = value
+				continue
+			case "--cache-enforce-integrity":
+				continue
+			}
+			kept = append(kept, args[i])
+		}
+		if len(kept) != len(args) {
+			containerConfig[key] = kept
+		}
+	}
+	if cacheDir == "" {
+		return ""
+	}
+	return strings.TrimPrefix(path.Clean("/"+cacheDir), "/")
+}
+
+// addLayerToConfig records the catalog layer in the image configuration, and labels the image with its catalog
+// directory.
+func addLayerToConfig(config map[string]any, configsDir, diffID string) {
+	containerConfig, _ := config["config"].(map[string]any)
+	if containerConfig == nil {
+		containerConfig = map[string]any{}
+		config["config"] = containerConfig
+	}
+	labels, _ := containerConfig["Labels"].(map[string]any)
+	if labels == nil {
+		labels = map[string]any{}
+		containerConfig["Labels"] = labels
+	}
+	labels[ConfigsLabel] = "/" + configsDir
+
+	rootfs, _ := config["rootfs"].(map[string]any)
+	if rootfs == nil {
+		rootfs = map[string]any{"type": "layers"}
+		config["rootfs"] = rootfs
+	}
+	diffIDs, _ := rootfs["diff_ids"].([]any)
+	rootfs["diff_ids"] = append(diffIDs, diffID)
+
+	// the history has an entry per layer, when the base image has one
+	if history, ok := config["history"].([]any); ok {
+		config["history"] = append(history, map[string]any{"created_by": "catalog-filter", "comment": "filtered file based catalog"})
+	}
+}
+
+// catalogLayer returns the gzipped tar layer holding fbc under configsDir, and the digest of the uncompressed tar.
+// The layer hides the files of the lower layers under configsDir when replace is set, and cacheDir when it is
+// set outside of configsDir.
+func catalogLayer(fbc declcfg.DeclarativeConfig, configsDir string, replace bool, cacheDir string) ([]byte, string, error) {
+	files, err := catalogFiles(fbc)
+	if err != nil {
+		return nil, "", err
+	}
+	tarball := &bytes.Buffer{}
+	tw := tar.NewWriter(tarball)
+	// the entries have no modification time, for the layer to only depend on the catalog
+	modTime := time.Unix(0, 0)
+	var dirs []string
+	for dir := configsDir; dir != "." && dir != ""; dir = path.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+	hideCache := cacheDir != "" && cacheDir != configsDir && !strings.HasPrefix(cacheDir, configsDir+"/")
+	if hideCache {
+		var cacheDirs []string
+		for dir := path.Dir(cacheDir); dir != "." && dir != "" && !slices.Contains(dirs, dir); dir = path.Dir(dir) {
+			cacheDirs = append([]string{dir}, cacheDirs...)
+		}
+		dirs = append(dirs, cacheDirs...)
+	}
+	for _, dir := range dirs {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755, ModTime: modTime}); err != nil {
+			return nil, "", err
+		}
+	}
+	if hideCache {
+		whiteout := path.Join(path.Dir(cacheDir), whiteoutPrefix+path.Base(cacheDir))
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: whiteout, Mode: 0644, ModTime: modTime}); err != nil {
+			return nil, "", err
+		}
+	}
+	if replace {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: path.Join(configsDir, whiteoutOpaque), Mode: 0644, ModTime: modTime}); err != nil {
+			return nil, "", err
+		}
+	}
+	for _, file := range files {
+		name := path.Join(configsDir, file.name)
+		if dir := path.Dir(name); dir != configsDir {
+			if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755, ModTime: modTime}); err != nil {
+				return nil, "", err
+			}
+		}
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(file.data)), ModTime: modTime}); err != nil {
+			return nil, "", err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(tarball.Bytes())
+
+	layer := &bytes.Buffer{}
+	gz := gzip.NewWriter(layer)
+	if _, err := gz.Write(tarball.Bytes()); err != nil {
+		return nil, "", err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, "", err
+	}
+	return layer.Bytes(), "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+type catalogFile struct {
+	name string
+	data []byte
+}
+
+// catalogFiles splits fbc into one catalog.json per package, sorted by package. Meta objects that belong to no
+// package are written to the catalog.json at the root of the catalog.
+func catalogFiles(fbc declcfg.DeclarativeConfig) ([]catalogFile, error) {
+	byPackage := map[string]*declcfg.DeclarativeConfig{}
+	pkgConfig := func(name string) *declcfg.DeclarativeConfig {
+		cfg, ok := byPackage[name]
+		if !ok {
+			cfg = &declcfg.DeclarativeConfig{}
+			byPackage[name] = cfg
+		}
+		return cfg
+	}
+	for _, p := range fbc.Packages {
+		pkgConfig(p.Name).Packages = append(pkgConfig(p.Name).Packages, p)
+	}
+	for _, c := range fbc.Channels {
+		pkgConfig(c.Package).Channels = append(pkgConfig(c.Package).Channels, c)
+	}
+	for _, b := range fbc.Bundles {
+		pkgConfig(b.Package).Bundles = append(pkgConfig(b.Package).Bundles, b)
+	}
+	for _, d := range fbc.Deprecations {
+		pkgConfig(d.Package).Deprecations = append(pkgConfig(d.Package).Deprecations, d)
+	}
+	for _, o := range fbc.Others {
+		pkgConfig(o.Package).Others = append(pkgConfig(o.Package).Others, o)
+	}
+
+	var files []catalogFile
+	for pkg, cfg := range byPackage {
+		data := &bytes.Buffer{}
+		if err := declcfg.WriteJSON(*cfg, data); err != nil {
+			return nil, err
+		}
+		files = append(files, catalogFile{name: path.Join(pkg, "catalog.json"), data: data.Bytes()})
+	}
+	slices.SortFunc(files, func(a, b catalogFile) int { return strings.Compare(a.name, b.name) })
+	return files, nil
+}
+
+// copyBlob copies the blob of desc from the layout at src to the layout at dest, checking its digest.
+func copyBlob(src, dest string, desc descriptor) error {
+	r, err := openBlob(src, desc)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	path, err := blobPath(dest, desc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("write file %q: %v", path, err)
+	}
+	return f.Close()
+}
+
+// writeLayout writes the oci-layout and index.json files of the layout at dir.
+func writeLayout(dir string, idx index) error {
+	layout, err := marshal(map[string]string{"imageLayoutVersion": layoutVersion})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, layoutFile), layout, 0666); err != nil {
+		return err
+	}
+	data, err := marshal(idx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, indexFile), data, 0666)
+}
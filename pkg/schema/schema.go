@@ -0,0 +1,184 @@
+// Package schema generates JSON Schemas from Go configuration types, and validates YAML documents against them,
+// reporting the line and column of each error.
+package schema
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	// Draft is the JSON Schema dialect of the generated schemas.
+	Draft = "https://json-schema.org/draft/2020-12/schema"
+
+	// SemverPattern matches a semantic version, with an optional v prefix as accepted by the semver library.
+	SemverPattern = `^v?(0|[1-9][0-9]*)(\.(0|[1-9][0-9]*)){0,2}(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`
+
+	// VersionRangePattern matches a semver range: comparisons separated by spaces or commas, hyphen ranges and
+	// wildcard versions, or'ed with ||, such as ">=1.0.0 <2.0.0 || 3.x".
+	VersionRangePattern = `^\s*` + versionRangeGroup + `(\s*\|\|\s*` + versionRangeGroup + `)*\s*$`
+
+	rangeVersion      = `v?(0|[1-9][0-9]*|[xX*])(\.(0|[1-9][0-9]*|[xX*])){0,2}(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?`
+	rangeTerm         = `(` + rangeVersion + `\s+-\s+` + rangeVersion + `|(\^|~>?|[<>]=?|=[<>]|!?=)?\s*` + rangeVersion + `)`
+	versionRangeGroup = rangeTerm + `((\s*,\s*|\s+)` + rangeTerm + `)*`
+)
+
+// Schema is a JSON Schema, limited to the keywords needed to describe configuration files.
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type  string `json:"type,omitempty"`
+	Enum  []any  `json:"enum,omitempty"`
+	Const any    `json:"const,omitempty"`
+
+	MinLength *int   `json:"minLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// AdditionalProperties is either false, rejecting the properties that are not listed in Properties, or the
+	// *Schema of the values of a map.
+	AdditionalProperties any  `json:"additionalProperties,omitempty"`
+	MinProperties        *int `json:"minProperties,omitempty"`
+
+	Items    *Schema `json:"items,omitempty"`
+	MinItems *int    `json:"minItems,omitempty"`
+	Contains *Schema `json:"contains,omitempty"`
+
+	AllOf []*Schema `json:"allOf,omitempty"`
+	AnyOf []*Schema `json:"anyOf,omitempty"`
+	Not   *Schema   `json:"not,omitempty"`
+}
+
+// Write writes s as indented JSON, the format of the schema files.
+func (s *Schema) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// Extender is implemented by the types that add constraints, such as enums, patterns or rules between their
+// fields, to the schema Generate derives from them.
+type Extender interface {
+	ExtendJSONSchema(s *Schema)
+}
+
+// Generate returns the schema of the type of v, following its json tags: structs are objects rejecting unknown
+// properties, whose fields without omitempty are required unless they are slices or maps, and whose embedded
+// inline structs are flattened. The schema of each type implementing Extender is extended by it.
+func Generate(v any) *Schema {
+	return generate(reflect.TypeOf(v))
+}
+
+func generate(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	s := &Schema{}
+	switch t.Kind() {
+	case reflect.Struct:
+		s.Type = "object"
+		s.Properties = map[string]*Schema{}
+		s.AdditionalProperties = false
+		addFields(s, t)
+	case reflect.Slice, reflect.Array:
+		s.Type = "array"
+		s.Items = generate(t.Elem())
+	case reflect.Map:
+		s.Type = "object"
+		s.AdditionalProperties = generate(t.Elem())
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		s.Type = "number"
+	}
+	if e, ok := reflect.Zero(t).Interface().(Extender); ok {
+		e.ExtendJSONSchema(s)
+	}
+	return s
+}
+
+func addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addFields(s, field.Type)
+			continue
+		}
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = generate(field.Type)
+		kind := field.Type.Kind()
+		if !slices.Contains(strings.Split(options, ","), "omitempty") && kind != reflect.Slice && kind != reflect.Map {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// VersionRange restricts the string schema s to semver ranges.
+func VersionRange(s *Schema) {
+	s.Pattern = VersionRangePattern
+	s.Description = `a semver range, such as ">=1.0.0 <2.0.0"`
+}
+
+// Semver restricts the string schema s to semantic versions.
+func Semver(s *Schema) {
+	s.Pattern = SemverPattern
+	s.Description = `a semantic version, such as "1.2.3"`
+}
+
+// Enum returns values as the values of the Enum keyword.
+func Enum[T ~string](values ...T) []any {
+	enum := make([]any, 0, len(values))
+	for _, v := range values {
+		enum = append(enum, string(v))
+	}
+	return enum
+}
+
+// Ptr returns a pointer to v, to set the numeric keywords of a Schema.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Sets matches the objects that set the property name.
+func Sets(name string) *Schema {
+	return &Schema{Required: []string{name}}
+}
+
+// SetsItems matches the objects whose array property name has items.
+func SetsItems(name string) *Schema {
+	return &Schema{Required: []string{name}, Properties: map[string]*Schema{name: {MinItems: Ptr(1)}}}
+}
+
+// SetsTrue matches the objects whose boolean property name is true.
+func SetsTrue(name string) *Schema {
+	return &Schema{Required: []string{name}, Properties: map[string]*Schema{name: {Const: true}}}
+}
+
+// AnyOf matches the values matching any of schemas.
+func AnyOf(schemas ...*Schema) *Schema {
+	return &Schema{AnyOf: schemas}
+}
+
+// Exclusive rejects the values matching both a and b. The description explains the rule in validation errors.
+func Exclusive(description string, a, b *Schema) *Schema {
+	return &Schema{Description: description, Not: &Schema{AllOf: []*Schema{a, b}}}
+}
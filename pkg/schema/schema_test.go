@@ -0,0 +1,94 @@
+package schema
+
+import (
+	"bytes"
+	"testing"
+
+	mmsemver "github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testMeta struct {
+	Kind string `json:"kind,omitempty"`
+}
+
+type testLevel string
+
+func (testLevel) ExtendJSONSchema(s *Schema) {
+	s.Enum = []any{"low", "high"}
+}
+
+type testItem struct {
+	Name  string    `json:"name"`
+	Level testLevel `json:"level,omitempty"`
+}
+
+type testConfig struct {
+	testMeta `json:",inline"`
+
+	Items   []testItem        `json:"items"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Enabled bool              `json:"enabled,omitempty"`
+	Count   int               `json:"count,omitempty"`
+	Ignored string            `json:"-"`
+	ignored string
+}
+
+func TestGenerate(t *testing.T) {
+	expected := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"kind": {Type: "string"},
+			"items": {Type: "array", Items: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"name":  {Type: "string"},
+					"level": {Type: "string", Enum: []any{"low", "high"}},
+				},
+				Required:             []string{"name"},
+				AdditionalProperties: false,
+			}},
+			"labels":  {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			"enabled": {Type: "boolean"},
+			"count":   {Type: "integer"},
+		},
+		AdditionalProperties: false,
+	}
+	assert.Equal(t, expected, Generate(&testConfig{}))
+}
+
+func TestSchema_Write(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, (&Schema{Type: "string", Pattern: "^<[a-z]+>$"}).Write(buf))
+	assert.Equal(t, "{\n  \"type\": \"string\",\n  \"pattern\": \"^<[a-z]+>$\"\n}\n", buf.String())
+}
+
+func TestVersionRangePattern(t *testing.T) {
+	valid := []string{
+		">=1.0.0 <2.0.0", ">=0.2.0", "1.2.3", "v1.2", "1.x", "*", "~1.2.3", "^2", "~>1.2", "!=1.2.3", "=>1.0", "=<2",
+		">= 1.0.0, < 2.0.0", "1.0.0 - 2.0.0", ">=1.0.0-alpha.1 <1.0.0+build.5", "<1.0.0 || >=2.0.0 <3.0.0",
+	}
+	for _, versionRange := range valid {
+		_, err := mmsemver.NewConstraint(versionRange)
+		require.NoError(t, err, versionRange)
+		assert.Regexp(t, VersionRangePattern, versionRange)
+	}
+	invalid := []string{"", "latest", ">=1.0.0 <", "1.0.0.0", ">>1.0.0", "1.0.0 ||"}
+	for _, versionRange := range invalid {
+		_, err := mmsemver.NewConstraint(versionRange)
+		require.Error(t, err, versionRange)
+		assert.NotRegexp(t, VersionRangePattern, versionRange)
+	}
+}
+
+func TestSemverPattern(t *testing.T) {
+	for _, version := range []string{"1.0.0", "v1.2", "1", "1.0.0-rc.1+build"} {
+		_, err := mmsemver.NewVersion(version)
+		require.NoError(t, err, version)
+		assert.Regexp(t, SemverPattern, version)
+	}
+	for _, version := range []string{"", "latest", "1.0.0.0", "1.x"} {
+		assert.NotRegexp(t, SemverPattern, version)
+	}
+}
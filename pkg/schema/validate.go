@@ -0,0 +1,261 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a value of a YAML document that does not match the schema.
+type Error struct {
+	// Line and Column locate the value in the document, starting at 1.
+	Line   int
+	Column int
+	// Path is the path of the value in the document, such as packages[0].channels[1].name, empty for the
+	// document itself.
+	Path    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+}
+
+// ValidateYAML validates the YAML documents of data against s. It returns the documents that cannot be parsed
+// and the values that do not match s, each as an *Error, joined in the order they appear in data. A pattern of
+// s that is not a valid regular expression is returned as an error before data is read.
+func ValidateYAML(s *Schema, data []byte) error {
+	v := &validator{patterns: map[string]*regexp.Regexp{}}
+	if err := v.compilePatterns(s); err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var errs []error
+	for {
+		doc := &yaml.Node{}
+		err := decoder.Decode(doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Join(append(errs, parseError(err))...)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		for _, e := range v.validate(s, doc.Content[0], "") {
+			errs = append(errs, e)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var yamlLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// parseError returns err as an *Error when the YAML parser locates it.
+func parseError(err error) error {
+	m := yamlLine.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(m[1])
+	return &Error{Line: line, Column: 1, Message: m[2]}
+}
+
+// validator validates YAML nodes against a schema whose patterns are compiled.
+type validator struct {
+	patterns map[string]*regexp.Regexp
+}
+
+// compilePatterns compiles the patterns of s and of its subschemas.
+func (v *validator) compilePatterns(s *Schema) error {
+	if s == nil {
+		return nil
+	}
+	if _, ok := v.patterns[s.Pattern]; s.Pattern != "" && !ok {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", s.Pattern, err)
+		}
+		v.patterns[s.Pattern] = pattern
+	}
+	subschemas := []*Schema{s.Items, s.Contains, s.Not}
+	for _, property := range s.Properties {
+		subschemas = append(subschemas, property)
+	}
+	if additional, ok := s.AdditionalProperties.(*Schema); ok {
+		subschemas = append(subschemas, additional)
+	}
+	subschemas = append(subschemas, s.AllOf...)
+	subschemas = append(subschemas, s.AnyOf...)
+	for _, sub := range subschemas {
+		if err := v.compilePatterns(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *validator) validate(s *Schema, node *yaml.Node, path string) []*Error {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	fail := func(n *yaml.Node, path, format string, args ...any) []*Error {
+		return []*Error{{Line: n.Line, Column: n.Column, Path: path, Message: fmt.Sprintf(format, args...)}}
+	}
+	if s.Type != "" && !hasType(node, s.Type) {
+		return fail(node, path, "must be of type %s", s.Type)
+	}
+
+	var errs []*Error
+	value := decode(node)
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(v any) bool { return reflect.DeepEqual(v, value) }) {
+		errs = append(errs, fail(node, path, "must be one of %s", formatValues(s.Enum))...)
+	}
+	if s.Const != nil && !reflect.DeepEqual(s.Const, value) {
+		errs = append(errs, fail(node, path, "must be %s", formatValues([]any{s.Const}))...)
+	}
+	if node.Kind == yaml.ScalarNode {
+		if s.MinLength != nil && len([]rune(node.Value)) < *s.MinLength {
+			errs = append(errs, fail(node, path, "must have at least %d characters", *s.MinLength)...)
+		}
+		if s.Pattern != "" && !v.patterns[s.Pattern].MatchString(node.Value) {
+			if s.Description != "" {
+				errs = append(errs, fail(node, path, "%q must be %s", node.Value, s.Description)...)
+			} else {
+				errs = append(errs, fail(node, path, "%q does not match pattern %q", node.Value, s.Pattern)...)
+			}
+		}
+	}
+
+	if node.Kind == yaml.MappingNode {
+		keys := map[string]bool{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if keys[key.Value] {
+				errs = append(errs, fail(key, path, "duplicate field %q", key.Value)...)
+				continue
+			}
+			keys[key.Value] = true
+			if property, ok := s.Properties[key.Value]; ok {
+				errs = append(errs, v.validate(property, value, join(path, key.Value))...)
+				continue
+			}
+			switch additional := s.AdditionalProperties.(type) {
+			case bool:
+				if !additional {
+					errs = append(errs, fail(key, path, "unknown field %q", key.Value)...)
+				}
+			case *Schema:
+				errs = append(errs, v.validate(additional, value, join(path, key.Value))...)
+			}
+		}
+		for _, name := range s.Required {
+			if !keys[name] {
+				errs = append(errs, fail(node, path, "missing required field %q", name)...)
+			}
+		}
+		if s.MinProperties != nil && len(keys) < *s.MinProperties {
+			errs = append(errs, fail(node, path, "must have at least %d fields", *s.MinProperties)...)
+		}
+	}
+
+	if node.Kind == yaml.SequenceNode {
+		if s.MinItems != nil && len(node.Content) < *s.MinItems {
+			errs = append(errs, fail(node, path, "must have at least %d items", *s.MinItems)...)
+		}
+		if s.Items != nil {
+			for i, item := range node.Content {
+				errs = append(errs, v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+		if s.Contains != nil && !slices.ContainsFunc(node.Content, func(item *yaml.Node) bool { return len(v.validate(s.Contains, item, path)) == 0 }) {
+			errs = append(errs, fail(node, path, "must contain an item matching %s", describe(s.Contains))...)
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		errs = append(errs, v.validate(sub, node, path)...)
+	}
+	if len(s.AnyOf) > 0 && !slices.ContainsFunc(s.AnyOf, func(sub *Schema) bool { return len(v.validate(sub, node, path)) == 0 }) {
+		errs = append(errs, fail(node, path, "must match %s", describe(s))...)
+	}
+	if s.Not != nil && len(v.validate(s.Not, node, path)) == 0 {
+		message := s.Description
+		if message == "" {
+			message = "must not match " + describe(s.Not)
+		}
+		errs = append(errs, fail(node, path, "%s", message)...)
+	}
+	return errs
+}
+
+func hasType(node *yaml.Node, typ string) bool {
+	switch typ {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	case "string":
+		return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str"
+	case "boolean":
+		return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!bool"
+	case "integer":
+		return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!int"
+	case "number":
+		return node.Kind == yaml.ScalarNode && (node.ShortTag() == "!!int" || node.ShortTag() == "!!float")
+	}
+	return true
+}
+
+// decode returns the value of a scalar node as a string or a bool, the types of the enums and constants of the
+// schemas, or nil.
+func decode(node *yaml.Node) any {
+	if node.Kind != yaml.ScalarNode {
+		return nil
+	}
+	switch node.ShortTag() {
+	case "!!str":
+		return node.Value
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err == nil {
+			return b
+		}
+	}
+	return nil
+}
+
+func formatValues(values []any) string {
+	formatted := make([]string, 0, len(values))
+	for _, v := range values {
+		formatted = append(formatted, fmt.Sprintf("%q", fmt.Sprint(v)))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// describe returns the description of s, or a generic one.
+func describe(s *Schema) string {
+	if s.Description != "" {
+		return s.Description
+	}
+	return "the schema"
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
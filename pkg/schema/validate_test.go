@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validateTestSchema() *Schema {
+	s := Generate(testConfig{})
+	s.Properties["kind"].Const = "Test"
+	s.Properties["items"].Items.Properties["name"].MinLength = Ptr(1)
+	s.Properties["items"].Items.Properties["name"].Pattern = "^[a-z]+$"
+	s.Properties["items"].MinItems = Ptr(1)
+	s.AllOf = append(s.AllOf, Exclusive("labels and count are exclusive", Sets("labels"), Sets("count")))
+	s.Properties["items"].Contains = AnyOf(&Schema{Properties: map[string]*Schema{"level": {Const: "high"}}, Required: []string{"level"}})
+	s.Properties["items"].Contains.Description = "a high level item"
+	return s
+}
+
+func TestValidateYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{
+			name: "WHEN document matches THEN Returns no error",
+			data: "kind: Test\nitems:\n- name: foo\n  level: high\nlabels:\n  team: a\n",
+		},
+		{
+			name: "WHEN fields are unknown THEN Returns their position",
+			data: "kind: Test\nitems:\n- name: foo\n  level: high\n  levle: low\nextra: true\n",
+			expected: []string{
+				`line 5, column 3: items[0]: unknown field "levle"`,
+				`line 6, column 1: unknown field "extra"`,
+			},
+		},
+		{
+			name: "WHEN values are invalid THEN Returns their position",
+			data: "kind: Other\nenabled: \"yes\"\nitems:\n- name: Foo\n  level: medium\n- level: high\nlabels:\n  team: 1\ncount: 3\n",
+			expected: []string{
+				`line 1, column 7: kind: must be "Test"`,
+				`line 2, column 10: enabled: must be of type boolean`,
+				`line 4, column 9: items[0].name: "Foo" does not match pattern "^[a-z]+$"`,
+				`line 5, column 10: items[0].level: must be one of "low", "high"`,
+				`line 6, column 3: items[1]: missing required field "name"`,
+				`line 8, column 9: labels.team: must be of type string`,
+				`line 1, column 1: labels and count are exclusive`,
+			},
+		},
+		{
+			name: "WHEN arrays do not match THEN Returns their position",
+			data: "kind: Test\nitems: []\n---\nkind: Test\nitems:\n- name: foo\n",
+			expected: []string{
+				`line 2, column 8: items: must have at least 1 items`,
+				`line 2, column 8: items: must contain an item matching a high level item`,
+				`line 6, column 1: items: must contain an item matching a high level item`,
+			},
+		},
+		{
+			name:     "WHEN fields are duplicated THEN Returns their position",
+			data:     "kind: Test\nitems:\n- name: foo\n  name: bar\n- name: baz\n  level: high\n",
+			expected: []string{`line 4, column 3: items[0]: duplicate field "name"`},
+		},
+		{
+			name:     "WHEN document cannot be parsed THEN Returns the line of the error",
+			data:     "kind: Test\nitems: [{name: foo, level: high}]\n---\nkind: Test\nitems: x: y\n",
+			expected: []string{`line 5, column 1: mapping values are not allowed in this context`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateYAML(validateTestSchema(), []byte(tt.data))
+			if len(tt.expected) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			var actual []string
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var schemaErr *Error
+				require.True(t, errors.As(e, &schemaErr))
+				actual = append(actual, schemaErr.Error())
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestValidateYAML_InvalidPattern(t *testing.T) {
+	s := validateTestSchema()
+	s.Properties["labels"].AdditionalProperties = &Schema{Type: "string", Pattern: "^[a-z"}
+	err := ValidateYAML(s, []byte("kind: Test\nitems: [{name: foo, level: high}]\n"))
+	assert.EqualError(t, err, "invalid pattern \"^[a-z\": error parsing regexp: missing closing ]: `[a-z`")
+}